@@ -0,0 +1,92 @@
+package service
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/jinzhu/gorm"
+
+	"github.com/deterok/go_test_task/payments/pkg/service/migrations"
+)
+
+// Migrate flags
+var (
+	migrateFs    = flag.NewFlagSet("payments migrate up|down|status", flag.ExitOnError)
+	migrateSteps = migrateFs.Int("steps", 1, "Number of the latest migrations reverted by down")
+)
+
+// RunMigrate applies pending migrations (up), reverts the latest ones (down)
+// or prints the state of all migrations (status).
+func RunMigrate(args []string) {
+	shareFlags(migrateFs, "dialect", "db-dsn")
+
+	logger = log.NewLogfmtLogger(os.Stderr)
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+	logger = log.With(logger, "caller", log.DefaultCaller)
+
+	if len(args) == 0 {
+		migrateFs.Usage()
+		os.Exit(2)
+	}
+
+	command := args[0]
+	migrateFs.Parse(args[1:])
+
+	db, err := gorm.Open(*dbDialect, *dbDSN)
+	if err != nil {
+		logger.Log("command", "migrate", "err", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db)
+	if err != nil {
+		logger.Log("command", "migrate", "err", err)
+		os.Exit(1)
+	}
+
+	var done []migrations.Migration
+	switch command {
+	case "up":
+		done, err = m.Up()
+	case "down":
+		done, err = m.Down(*migrateSteps)
+	case "status":
+		err = printMigrations(m)
+	default:
+		logger.Log("command", "migrate", "err", fmt.Sprintf("unknown command %q", command))
+		os.Exit(2)
+	}
+
+	for _, mg := range done {
+		logger.Log("command", "migrate", command, fmt.Sprintf("%d_%s", mg.Version, mg.Name))
+	}
+
+	if err != nil {
+		logger.Log("command", "migrate", "err", err)
+		os.Exit(1)
+	}
+}
+
+func printMigrations(m *migrations.Migrator) error {
+	ss, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range ss {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	log "github.com/go-kit/kit/log"
+
+	service "github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+// Reconcile flags
+var (
+	reconcileFs     = flag.NewFlagSet("payments reconcile", flag.ExitOnError)
+	reconcileFormat = reconcileFs.String("format", "json", "Report format: json or csv")
+	reconcileOutput = reconcileFs.String("output", "", "Report file (default stdout)")
+	reconcileFix    = reconcileFs.Bool("fix", false, "Write adjustment operations for found drifts")
+)
+
+// RunReconcile recomputes balances of all accounts from their operations history
+// and reports accounts whose balances drifted. Exits with non-zero code if there
+// are unfixed drifts.
+func RunReconcile(args []string) {
+	shareFlags(reconcileFs, "dialect", "db-dsn", "redis-addr", "lock-ttl", "lock-tries", "lock-retry-delay", "system-currencies")
+	reconcileFs.Parse(args)
+
+	logger = log.NewLogfmtLogger(os.Stderr)
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+	logger = log.With(logger, "caller", log.DefaultCaller)
+
+	if *reconcileFormat != "json" && *reconcileFormat != "csv" {
+		logger.Log("command", "reconcile", "err", fmt.Sprintf("unknown format %q", *reconcileFormat))
+		os.Exit(2)
+	}
+
+	db := initDB()
+	defer db.Close()
+
+	r := service.NewReconciler(initLocks(), service.NewUOWPaymentsFactory(db))
+
+	ctx := context.Background()
+	drifts, err := r.Reconcile(ctx)
+	if err != nil {
+		logger.Log("command", "reconcile", "err", err)
+		os.Exit(1)
+	}
+
+	unfixed := len(drifts)
+	if *reconcileFix {
+		for _, d := range drifts {
+			if _, err := r.Fix(ctx, d); err != nil {
+				logger.Log("command", "reconcile", "account", d.AccountID, "err", err)
+				continue
+			}
+			unfixed--
+		}
+	}
+
+	out := io.Writer(os.Stdout)
+	if *reconcileOutput != "" {
+		f, err := os.Create(*reconcileOutput)
+		if err != nil {
+			logger.Log("command", "reconcile", "err", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := writeDrifts(out, *reconcileFormat, drifts); err != nil {
+		logger.Log("command", "reconcile", "err", err)
+		os.Exit(1)
+	}
+
+	logger.Log("command", "reconcile", "drifts", len(drifts), "unfixed", unfixed)
+	if unfixed > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeDrifts(w io.Writer, format string, drifts []*service.Drift) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(drifts)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"account_id", "currency", "amount", "computed", "difference", "adjustment_id"})
+	for _, d := range drifts {
+		adjustment := ""
+		if d.AdjustmentID != nil {
+			adjustment = strconv.FormatUint(uint64(*d.AdjustmentID), 10)
+		}
+
+		cw.Write([]string{
+			strconv.FormatInt(d.AccountID, 10),
+			d.Currency,
+			d.Amount.String(),
+			d.Computed.String(),
+			d.Difference.String(),
+			adjustment,
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// shareFlags registers the flags of the main flag set in the subcommand flag set
+func shareFlags(dst *flag.FlagSet, names ...string) {
+	for _, name := range names {
+		if f := fs.Lookup(name); f != nil {
+			dst.Var(f.Value, f.Name, f.Usage)
+		}
+	}
+}
@@ -1,22 +1,26 @@
 package service
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
 
 	"github.com/deterok/go_test_task/payments/pkg/endpoint"
 	payhttp "github.com/deterok/go_test_task/payments/pkg/http"
 	service "github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/deterok/go_test_task/payments/pkg/service/migrations"
 	kitendpoint "github.com/go-kit/kit/endpoint"
 	log "github.com/go-kit/kit/log"
 	"github.com/oklog/oklog/pkg/group"
@@ -30,13 +34,41 @@ var logger log.Logger
 var (
 	fs        = flag.NewFlagSet("payments", flag.ExitOnError)
 	httpAddr  = fs.String("http-addr", ":8081", "HTTP listen address")
-	redisAddr = fs.String("redis-addr", "redis:6379", "Redis address")
+	redisAddr = fs.String("redis-addr", "redis:6379", "Redis address, locks work within the process without it")
+	// Locks
+	lockTTL        = fs.Duration("lock-ttl", service.DefaultLockOptions.TTL, "Lease of locks, held locks extend it")
+	lockTries      = fs.Int("lock-tries", service.DefaultLockOptions.Tries, "Number of attempts to acquire a lock")
+	lockRetryDelay = fs.Duration("lock-retry-delay", service.DefaultLockOptions.RetryDelay, "Delay between attempts to acquire a lock")
 	// Database
-	dbDialect = fs.String("dialect", "postgres", "Database dialect")
-	dbDSN     = fs.String("db-dsn", "host=postgres sslmode=disable user=postgres", "Database DSN")
+	dbDialect   = fs.String("dialect", "postgres", "Database dialect: postgres or sqlite3")
+	dbDSN       = fs.String("db-dsn", "host=postgres sslmode=disable user=postgres", "Database DSN")
+	autoMigrate = fs.Bool("auto-migrate", false, "Apply pending schema migrations at startup instead of refusing to run")
+	// System accounts
+	systemCurrencies = fs.String("system-currencies", "", "Comma-separated currencies whose system accounts are created at startup")
+	// Currencies
+	currenciesFile = fs.String("currencies-file", "", "JSON file with currencies extending the ISO 4217 table")
+	fxRatesFile    = fs.String("fx-rates-file", "", "JSON file with exchange rates")
+	feesFile       = fs.String("fees-file", "", "JSON file with the fee schedule")
+	// Workers
+	holdsExpiryInterval = fs.Duration("holds-expiry-interval", time.Minute, "Interval of stale holds expiration")
+	schedulesInterval   = fs.Duration("schedules-interval", time.Minute, "Interval of due schedules execution")
+	outboxInterval      = fs.Duration("outbox-interval", time.Second, "Interval of events relaying from the outbox")
+	webhooksInterval    = fs.Duration("webhooks-interval", 5*time.Second, "Interval of webhook deliveries sending")
+	// Events
+	publisherKind = fs.String("publisher", "log", "Events publisher: log or webhook")
+	publisherURL  = fs.String("publisher-url", "", "URL of the webhook events are posted to")
 )
 
 func Run() {
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		RunReconcile(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		RunMigrate(os.Args[2:])
+		return
+	}
+
 	fs.Parse(os.Args[1:])
 
 	// Create a single logger, which we'll use and give to other components.
@@ -46,34 +78,123 @@ func Run() {
 
 	tracer = opentracinggo.GlobalTracer()
 
-	// Init database
+	db := initDB()
+
+	lockFactory := initLocks()
+	uowFacotry := service.NewUOWPaymentsFactory(db)
+	svc := service.New(lockFactory, uowFacotry, initCurrencies(), initFXRates(), initFees(), getServiceMiddleware(logger))
+	eps := endpoint.New(svc, getEndpointMiddleware(logger))
+	g := createService(eps)
+	initHoldsExpirer(svc, g)
+	initScheduler(svc, g)
+	pub := service.NewMultiPublisher(initPublisher(), service.NewWebhooksPublisher(uowFacotry))
+	initRelay(service.NewRelay(lockFactory, uowFacotry, pub), g)
+	initWebhookDispatcher(service.NewWebhookDispatcher(lockFactory, uowFacotry, &http.Client{Timeout: 10 * time.Second}), g)
+	initCancelInterrupt(g)
+	logger.Log("exit", g.Run())
+}
+
+func initDB() *gorm.DB {
 	db, err := gorm.Open(*dbDialect, *dbDSN)
 	if err != nil {
 		panic(err)
 	}
 
-	if err := service.InitModels(db); err != nil {
+	if *autoMigrate {
+		ms, err := migrations.Up(db)
+		if err != nil {
+			panic(err)
+		}
+		for _, m := range ms {
+			logger.Log("migration", fmt.Sprintf("%d_%s", m.Version, m.Name), "applied", true)
+		}
+	}
+
+	if err := service.InitModels(db, splitList(*systemCurrencies)...); err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+func initCurrencies() service.CurrencyRegistry {
+	if *currenciesFile == "" {
+		return service.NewCurrencyRegistry()
+	}
+
+	extra, err := service.LoadCurrencies(*currenciesFile)
+	if err != nil {
+		panic(err)
+	}
+
+	return service.NewCurrencyRegistry(extra...)
+}
+
+// initFXRates returns the exchange rates provider. Exchanges are unavailable without the rates file.
+func initFXRates() service.FXRateProvider {
+	if *fxRatesFile == "" {
+		return service.NewMemoryFXRateProvider()
+	}
+
+	fx, err := service.NewStaticFXRateProvider(*fxRatesFile)
+	if err != nil {
+		panic(err)
+	}
+
+	return fx
+}
+
+// initFees returns the fee schedule. Operations are free without the fees file.
+func initFees() *service.FeeSchedule {
+	if *feesFile == "" {
+		return nil
+	}
+
+	fees, err := service.LoadFeeSchedule(*feesFile)
+	if err != nil {
 		panic(err)
 	}
 
-	// Init redis
-	redis := &redis.Pool{
+	return fees
+}
+
+// splitList splits the comma-separated flag value
+func splitList(v string) []string {
+	res := []string{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// initLocks returns the factory of locks. Without the redis address locks work
+// within the process, which is enough for a single node.
+func initLocks() service.LockFactory {
+	opts := []service.LockOption{
+		service.WithLockTTL(*lockTTL),
+		service.WithLockTries(*lockTries),
+		service.WithLockRetryDelay(*lockRetryDelay),
+	}
+
+	if *redisAddr == "" {
+		return service.NewMemoryLockFactory(opts...)
+	}
+
+	return service.NewLockFactory(initRedis(), opts...)
+}
+
+func initRedis() *redis.Pool {
+	return &redis.Pool{
 		MaxIdle:     1,
 		IdleTimeout: 5 * time.Second,
-		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "redis:6379") },
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", *redisAddr) },
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
 			_, err := c.Do("PING")
 			return err
 		},
 	}
-
-	lockFactory := service.NewLockFactory(redis)
-	uowFacotry := service.NewUOWPaymentsFactory(db)
-	svc := service.New(lockFactory, uowFacotry, getServiceMiddleware(logger))
-	eps := endpoint.New(svc, getEndpointMiddleware(logger))
-	g := createService(eps)
-	initCancelInterrupt(g)
-	logger.Log("exit", g.Run())
 }
 
 func initHttpHandler(endpoints endpoint.Endpoints, g *group.Group) {
@@ -92,12 +213,129 @@ func initHttpHandler(endpoints endpoint.Endpoints, g *group.Group) {
 	})
 
 }
+
+// initHoldsExpirer periodically releases money of stale holds
+func initHoldsExpirer(svc service.PaymentsService, g *group.Group) {
+	ticker := time.NewTicker(*holdsExpiryInterval)
+	done := make(chan struct{})
+	g.Add(func() error {
+		for {
+			select {
+			case <-ticker.C:
+				hs, err := svc.ExpireHolds(context.Background())
+				if err != nil {
+					logger.Log("worker", "HoldsExpirer", "err", err)
+				}
+				if len(hs) > 0 {
+					logger.Log("worker", "HoldsExpirer", "expired", len(hs))
+				}
+			case <-done:
+				return nil
+			}
+		}
+	}, func(error) {
+		ticker.Stop()
+		close(done)
+	})
+}
+
+// initScheduler periodically executes due schedules
+func initScheduler(svc service.PaymentsService, g *group.Group) {
+	ticker := time.NewTicker(*schedulesInterval)
+	done := make(chan struct{})
+	g.Add(func() error {
+		for {
+			select {
+			case <-ticker.C:
+				as, err := svc.RunDueSchedules(context.Background())
+				if err != nil {
+					logger.Log("worker", "Scheduler", "err", err)
+				}
+				if len(as) > 0 {
+					logger.Log("worker", "Scheduler", "attempts", len(as))
+				}
+			case <-done:
+				return nil
+			}
+		}
+	}, func(error) {
+		ticker.Stop()
+		close(done)
+	})
+}
+
+// initPublisher returns the publisher of events chosen by the flags
+func initPublisher() service.Publisher {
+	switch *publisherKind {
+	case "log":
+		return service.NewLogPublisher(os.Stdout)
+	case "webhook":
+		if *publisherURL == "" {
+			panic("publisher-url is required by the webhook publisher")
+		}
+		return service.NewWebhookPublisher(*publisherURL, &http.Client{Timeout: 10 * time.Second})
+	}
+
+	panic(fmt.Sprintf("unknown publisher %q", *publisherKind))
+}
+
+// initRelay periodically publishes events from the outbox
+func initRelay(r service.Relay, g *group.Group) {
+	ticker := time.NewTicker(*outboxInterval)
+	done := make(chan struct{})
+	g.Add(func() error {
+		for {
+			select {
+			case <-ticker.C:
+				n, err := r.Relay(context.Background())
+				if err != nil {
+					logger.Log("worker", "Relay", "err", err)
+				}
+				if n > 0 {
+					logger.Log("worker", "Relay", "published", n)
+				}
+			case <-done:
+				return nil
+			}
+		}
+	}, func(error) {
+		ticker.Stop()
+		close(done)
+	})
+}
+
+// initWebhookDispatcher periodically sends due webhook deliveries
+func initWebhookDispatcher(d service.WebhookDispatcher, g *group.Group) {
+	ticker := time.NewTicker(*webhooksInterval)
+	done := make(chan struct{})
+	g.Add(func() error {
+		for {
+			select {
+			case <-ticker.C:
+				ds, err := d.Dispatch(context.Background())
+				if err != nil {
+					logger.Log("worker", "WebhookDispatcher", "err", err)
+				}
+				if len(ds) > 0 {
+					logger.Log("worker", "WebhookDispatcher", "sent", len(ds))
+				}
+			case <-done:
+				return nil
+			}
+		}
+	}, func(error) {
+		ticker.Stop()
+		close(done)
+	})
+}
+
 func getServiceMiddleware(logger log.Logger) (mw []service.Middleware) {
 	mw = []service.Middleware{}
 	return
 }
 func getEndpointMiddleware(logger log.Logger) (mw map[string][]kitendpoint.Middleware) {
 	mw = map[string][]kitendpoint.Middleware{}
+	addEndpointMiddlewareToAllMethods(mw, endpoint.ValidationMiddleware())
 	return
 }
 
@@ -41,12 +41,137 @@ func defaultHttpOptions(logger log.Logger, tracer opentracinggo.Tracer) map[stri
 			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
 			kithttp.ServerErrorLogger(logger),
 		},
+		"MakeBatchTransfer": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"MakeOperation": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"MakeWithdrawal": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"ReverseOperation": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"AuthorizeHold": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"CaptureHold": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"VoidHold": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetCurrencies": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"QuoteExchange": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"MakeExchangeTransfer": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"QuoteFee": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetSystemAccounts": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"FreezeAccount": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"UnfreezeAccount": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"CloseAccount": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetAccountStatusHistory": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"CreateSchedule": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetSchedule": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetAccountSchedules": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"UpdateSchedule": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"CancelSchedule": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetScheduleAttempts": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"CreateWebhook": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetWebhook": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetWebhooks": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"UpdateWebhook": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"DeleteWebhook": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"GetWebhookDeliveries": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
+		"RedeliverWebhook": {
+			kithttp.ServerErrorEncoder(payhttp.ErrorEncoder),
+			kithttp.ServerErrorLogger(logger),
+		},
 	}
 	return options
 }
 
 func addEndpointMiddlewareToAllMethods(mw map[string][]endpoint.Middleware, m endpoint.Middleware) {
-	methods := []string{"CreateAccount", "GetAccount", "GetAccounts", "GetAccountOperations", "MakeDeposit", "MakeTransfer"}
+	methods := []string{
+		"CreateAccount", "GetAccount", "GetAccounts", "GetAccountOperations",
+		"MakeDeposit", "MakeTransfer", "MakeBatchTransfer", "MakeOperation", "MakeWithdrawal", "ReverseOperation",
+		"AuthorizeHold", "CaptureHold", "VoidHold",
+		"GetCurrencies", "QuoteExchange", "MakeExchangeTransfer", "QuoteFee",
+		"GetSystemAccounts",
+		"FreezeAccount", "UnfreezeAccount", "CloseAccount", "GetAccountStatusHistory",
+		"CreateSchedule", "GetSchedule", "GetAccountSchedules", "UpdateSchedule", "CancelSchedule", "GetScheduleAttempts",
+		"CreateWebhook", "GetWebhook", "GetWebhooks", "UpdateWebhook", "DeleteWebhook", "GetWebhookDeliveries", "RedeliverWebhook",
+	}
 	for _, v := range methods {
 		mw[v] = append(mw[v], m)
 	}
@@ -2,6 +2,7 @@ package endpoint
 
 import (
 	"context"
+	"time"
 
 	"github.com/deterok/go_test_task/payments/pkg/service"
 	"github.com/go-kit/kit/endpoint"
@@ -68,21 +69,40 @@ func (r GetAccountResponse) Failed() error {
 }
 
 // GetAccountsRequest collects the request parameters for the GetAccounts method.
-type GetAccountsRequest struct{}
+type GetAccountsRequest struct {
+	Currency    string                `json:"currency"`
+	NamePrefix  string                `json:"name_prefix"`
+	MinAmount   *decimal.Decimal      `json:"min_amount"`
+	MaxAmount   *decimal.Decimal      `json:"max_amount"`
+	CreatedFrom *time.Time            `json:"created_from"`
+	CreatedTo   *time.Time            `json:"created_to"`
+	Deleted     service.DeletedStatus `json:"deleted"`
+	WithTotal   bool                  `json:"with_total"`
+	Order       service.SortOrder     `json:"order"`
+	Cursor      string                `json:"cursor"`
+	Limit       int                   `json:"limit"`
+}
 
 // GetAccountsResponse collects the response parameters for the GetAccounts method.
 type GetAccountsResponse struct {
-	Account []*service.Account `json:"account"`
-	Err     error              `json:"error,omitempty"`
+	Account    []*service.Account `json:"account"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	Total      *int64             `json:"total,omitempty"`
+	Err        error              `json:"error,omitempty"`
 }
 
 // MakeGetAccountsEndpoint returns an endpoint that invokes GetAccounts on the service.
 func MakeGetAccountsEndpoint(s service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		a, err := s.GetAccounts(ctx)
+		req := request.(GetAccountsRequest)
+		p, err := s.GetAccounts(ctx, req.filter())
+		if err != nil {
+			return GetAccountsResponse{Err: err}, nil
+		}
 		return GetAccountsResponse{
-			Account: a,
-			Err:     err,
+			Account:    p.Accounts,
+			NextCursor: p.NextCursor,
+			Total:      p.Total,
 		}, nil
 	}
 }
@@ -92,14 +112,42 @@ func (r GetAccountsResponse) Failed() error {
 	return r.Err
 }
 
+func (r GetAccountsRequest) filter() service.AccountsFilter {
+	return service.AccountsFilter{
+		Page: service.Page{
+			Cursor: r.Cursor,
+			Limit:  r.Limit,
+			Order:  r.Order,
+		},
+		Currency:    r.Currency,
+		NamePrefix:  r.NamePrefix,
+		MinAmount:   r.MinAmount,
+		MaxAmount:   r.MaxAmount,
+		CreatedFrom: r.CreatedFrom,
+		CreatedTo:   r.CreatedTo,
+		Deleted:     r.Deleted,
+		WithTotal:   r.WithTotal,
+	}
+}
+
 // GetAccountOperationsRequest collects the request parameters for the GetAccountOperations method.
 type GetAccountOperationsRequest struct {
-	AccountID int64 `json:"account_id"`
+	AccountID    int64                   `json:"account_id"`
+	Types        []service.OperationType `json:"types"`
+	CreatedFrom  *time.Time              `json:"created_from"`
+	CreatedTo    *time.Time              `json:"created_to"`
+	Counterparty int64                   `json:"counterparty"`
+	MinAmount    *decimal.Decimal        `json:"min_amount"`
+	MaxAmount    *decimal.Decimal        `json:"max_amount"`
+	Order        service.SortOrder       `json:"order"`
+	Cursor       string                  `json:"cursor"`
+	Limit        int                     `json:"limit"`
 }
 
 // GetAccountOperationsResponse collects the response parameters for the GetAccountOperations method.
 type GetAccountOperationsResponse struct {
 	Operations []*service.Operation `json:"operations"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 	Err        error                `json:"error,omitempty"`
 }
 
@@ -107,10 +155,13 @@ type GetAccountOperationsResponse struct {
 func MakeGetAccountOperationsEndpoint(s service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(GetAccountOperationsRequest)
-		o, err := s.GetAccountOperations(ctx, req.AccountID)
+		p, err := s.GetAccountOperations(ctx, req.AccountID, req.filter())
+		if err != nil {
+			return GetAccountOperationsResponse{Err: err}, nil
+		}
 		return GetAccountOperationsResponse{
-			Operations: o,
-			Err:        err,
+			Operations: p.Operations,
+			NextCursor: p.NextCursor,
 		}, nil
 	}
 }
@@ -120,6 +171,22 @@ func (r GetAccountOperationsResponse) Failed() error {
 	return r.Err
 }
 
+func (r GetAccountOperationsRequest) filter() service.OperationsFilter {
+	return service.OperationsFilter{
+		Page: service.Page{
+			Cursor: r.Cursor,
+			Limit:  r.Limit,
+			Order:  r.Order,
+		},
+		Types:        r.Types,
+		CreatedFrom:  r.CreatedFrom,
+		CreatedTo:    r.CreatedTo,
+		Counterparty: r.Counterparty,
+		MinAmount:    r.MinAmount,
+		MaxAmount:    r.MaxAmount,
+	}
+}
+
 // ─── ENDPOINTS IMPLIMENTATION ───────────────────────────────────────────────────
 
 // GetAccount implements Service.
@@ -133,23 +200,54 @@ func (e Endpoints) GetAccount(ctx context.Context, id int64) (*service.Account,
 }
 
 // GetAccounts implements Service.
-func (e Endpoints) GetAccounts(ctx context.Context) ([]*service.Account, error) {
-	request := GetAccountsRequest{}
+func (e Endpoints) GetAccounts(ctx context.Context, f service.AccountsFilter) (*service.AccountsPage, error) {
+	request := GetAccountsRequest{
+		Currency:    f.Currency,
+		NamePrefix:  f.NamePrefix,
+		MinAmount:   f.MinAmount,
+		MaxAmount:   f.MaxAmount,
+		CreatedFrom: f.CreatedFrom,
+		CreatedTo:   f.CreatedTo,
+		Deleted:     f.Deleted,
+		WithTotal:   f.WithTotal,
+		Order:       f.Order,
+		Cursor:      f.Cursor,
+		Limit:       f.Limit,
+	}
 	response, err := e.GetAccountsEndpoint(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return response.(GetAccountsResponse).Account, response.(GetAccountsResponse).Err
+	resp := response.(GetAccountsResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &service.AccountsPage{Accounts: resp.Account, NextCursor: resp.NextCursor, Total: resp.Total}, nil
 }
 
 // GetAccountOperations implements Service.
-func (e Endpoints) GetAccountOperations(ctx context.Context, accID int64) ([]*service.Operation, error) {
-	request := GetAccountOperationsRequest{AccountID: accID}
+func (e Endpoints) GetAccountOperations(ctx context.Context, accID int64, f service.OperationsFilter) (*service.OperationsPage, error) {
+	request := GetAccountOperationsRequest{
+		AccountID:    accID,
+		Types:        f.Types,
+		CreatedFrom:  f.CreatedFrom,
+		CreatedTo:    f.CreatedTo,
+		Counterparty: f.Counterparty,
+		MinAmount:    f.MinAmount,
+		MaxAmount:    f.MaxAmount,
+		Order:        f.Order,
+		Cursor:       f.Cursor,
+		Limit:        f.Limit,
+	}
 	response, err := e.GetAccountOperationsEndpoint(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return response.(GetAccountOperationsResponse).Operations, response.(GetAccountOperationsResponse).Err
+	resp := response.(GetAccountOperationsResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &service.OperationsPage{Operations: resp.Operations, NextCursor: resp.NextCursor}, nil
 }
 
 // CreateAccount implements Service.
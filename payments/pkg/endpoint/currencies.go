@@ -0,0 +1,47 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ─── ENDPOINTS ENVOKERS ─────────────────────────────────────────────────────────
+
+// GetCurrenciesRequest collects the request parameters for the GetCurrencies method.
+type GetCurrenciesRequest struct{}
+
+// GetCurrenciesResponse collects the response parameters for the GetCurrencies method.
+type GetCurrenciesResponse struct {
+	Currencies []*service.Currency `json:"currencies"`
+	Err        error               `json:"error,omitempty"`
+}
+
+// MakeGetCurrenciesEndpoint returns an endpoint that invokes GetCurrencies on the service.
+func MakeGetCurrenciesEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		c, err := s.GetCurrencies(ctx)
+		return GetCurrenciesResponse{
+			Currencies: c,
+			Err:        err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetCurrenciesResponse) Failed() error {
+	return r.Err
+}
+
+// ─── ENDPOINTS IMPLIMENTATION ───────────────────────────────────────────────────
+
+// GetCurrencies implements Service.
+func (e Endpoints) GetCurrencies(ctx context.Context) ([]*service.Currency, error) {
+	request := GetCurrenciesRequest{}
+	response, err := e.GetCurrenciesEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetCurrenciesResponse).Currencies, response.(GetCurrenciesResponse).Err
+}
@@ -10,24 +10,82 @@ import (
 // meant to be used as a helper struct, to collect all of the endpoints into a
 // single parameter.
 type Endpoints struct {
-	CreateAccountEndpoint        endpoint.Endpoint
-	GetAccountEndpoint           endpoint.Endpoint
-	GetAccountsEndpoint          endpoint.Endpoint
-	GetAccountOperationsEndpoint endpoint.Endpoint
-	MakeDepositEndpoint         endpoint.Endpoint
-	MakeTransferEndpoint         endpoint.Endpoint
+	CreateAccountEndpoint           endpoint.Endpoint
+	GetAccountEndpoint              endpoint.Endpoint
+	GetAccountsEndpoint             endpoint.Endpoint
+	GetAccountOperationsEndpoint    endpoint.Endpoint
+	MakeDepositEndpoint             endpoint.Endpoint
+	MakeTransferEndpoint            endpoint.Endpoint
+	MakeBatchTransferEndpoint       endpoint.Endpoint
+	MakeOperationEndpoint           endpoint.Endpoint
+	MakeWithdrawalEndpoint          endpoint.Endpoint
+	ReverseOperationEndpoint        endpoint.Endpoint
+	AuthorizeHoldEndpoint           endpoint.Endpoint
+	CaptureHoldEndpoint             endpoint.Endpoint
+	VoidHoldEndpoint                endpoint.Endpoint
+	GetCurrenciesEndpoint           endpoint.Endpoint
+	QuoteExchangeEndpoint           endpoint.Endpoint
+	MakeExchangeTransferEndpoint    endpoint.Endpoint
+	QuoteFeeEndpoint                endpoint.Endpoint
+	GetSystemAccountsEndpoint       endpoint.Endpoint
+	FreezeAccountEndpoint           endpoint.Endpoint
+	UnfreezeAccountEndpoint         endpoint.Endpoint
+	CloseAccountEndpoint            endpoint.Endpoint
+	GetAccountStatusHistoryEndpoint endpoint.Endpoint
+	CreateScheduleEndpoint          endpoint.Endpoint
+	GetScheduleEndpoint             endpoint.Endpoint
+	GetAccountSchedulesEndpoint     endpoint.Endpoint
+	UpdateScheduleEndpoint          endpoint.Endpoint
+	CancelScheduleEndpoint          endpoint.Endpoint
+	GetScheduleAttemptsEndpoint     endpoint.Endpoint
+	CreateWebhookEndpoint           endpoint.Endpoint
+	GetWebhookEndpoint              endpoint.Endpoint
+	GetWebhooksEndpoint             endpoint.Endpoint
+	UpdateWebhookEndpoint           endpoint.Endpoint
+	DeleteWebhookEndpoint           endpoint.Endpoint
+	GetWebhookDeliveriesEndpoint    endpoint.Endpoint
+	RedeliverWebhookEndpoint        endpoint.Endpoint
 }
 
 // New returns a Endpoints struct that wraps the provided service, and wires in all of the
 // expected endpoint middlewares
 func New(s service.PaymentsService, mdw map[string][]endpoint.Middleware) Endpoints {
 	eps := Endpoints{
-		CreateAccountEndpoint:        MakeCreateAccountEndpoint(s),
-		GetAccountEndpoint:           MakeGetAccountEndpoint(s),
-		GetAccountOperationsEndpoint: MakeGetAccountOperationsEndpoint(s),
-		GetAccountsEndpoint:          MakeGetAccountsEndpoint(s),
-		MakeDepositEndpoint:         MakeMakeDepositEndpoint(s),
-		MakeTransferEndpoint:         MakeMakeTransferEndpoint(s),
+		CreateAccountEndpoint:           MakeCreateAccountEndpoint(s),
+		GetAccountEndpoint:              MakeGetAccountEndpoint(s),
+		GetAccountOperationsEndpoint:    MakeGetAccountOperationsEndpoint(s),
+		GetAccountsEndpoint:             MakeGetAccountsEndpoint(s),
+		MakeDepositEndpoint:             MakeMakeDepositEndpoint(s),
+		MakeTransferEndpoint:            MakeMakeTransferEndpoint(s),
+		MakeBatchTransferEndpoint:       MakeMakeBatchTransferEndpoint(s),
+		MakeOperationEndpoint:           MakeMakeOperationEndpoint(s),
+		MakeWithdrawalEndpoint:          MakeMakeWithdrawalEndpoint(s),
+		ReverseOperationEndpoint:        MakeReverseOperationEndpoint(s),
+		AuthorizeHoldEndpoint:           MakeAuthorizeHoldEndpoint(s),
+		CaptureHoldEndpoint:             MakeCaptureHoldEndpoint(s),
+		VoidHoldEndpoint:                MakeVoidHoldEndpoint(s),
+		GetCurrenciesEndpoint:           MakeGetCurrenciesEndpoint(s),
+		QuoteExchangeEndpoint:           MakeQuoteExchangeEndpoint(s),
+		MakeExchangeTransferEndpoint:    MakeMakeExchangeTransferEndpoint(s),
+		QuoteFeeEndpoint:                MakeQuoteFeeEndpoint(s),
+		GetSystemAccountsEndpoint:       MakeGetSystemAccountsEndpoint(s),
+		FreezeAccountEndpoint:           MakeFreezeAccountEndpoint(s),
+		UnfreezeAccountEndpoint:         MakeUnfreezeAccountEndpoint(s),
+		CloseAccountEndpoint:            MakeCloseAccountEndpoint(s),
+		GetAccountStatusHistoryEndpoint: MakeGetAccountStatusHistoryEndpoint(s),
+		CreateScheduleEndpoint:          MakeCreateScheduleEndpoint(s),
+		GetScheduleEndpoint:             MakeGetScheduleEndpoint(s),
+		GetAccountSchedulesEndpoint:     MakeGetAccountSchedulesEndpoint(s),
+		UpdateScheduleEndpoint:          MakeUpdateScheduleEndpoint(s),
+		CancelScheduleEndpoint:          MakeCancelScheduleEndpoint(s),
+		GetScheduleAttemptsEndpoint:     MakeGetScheduleAttemptsEndpoint(s),
+		CreateWebhookEndpoint:           MakeCreateWebhookEndpoint(s),
+		GetWebhookEndpoint:              MakeGetWebhookEndpoint(s),
+		GetWebhooksEndpoint:             MakeGetWebhooksEndpoint(s),
+		UpdateWebhookEndpoint:           MakeUpdateWebhookEndpoint(s),
+		DeleteWebhookEndpoint:           MakeDeleteWebhookEndpoint(s),
+		GetWebhookDeliveriesEndpoint:    MakeGetWebhookDeliveriesEndpoint(s),
+		RedeliverWebhookEndpoint:        MakeRedeliverWebhookEndpoint(s),
 	}
 	for _, m := range mdw["CreateAccount"] {
 		eps.CreateAccountEndpoint = m(eps.CreateAccountEndpoint)
@@ -41,12 +99,99 @@ func New(s service.PaymentsService, mdw map[string][]endpoint.Middleware) Endpoi
 	for _, m := range mdw["GetAccountOperations"] {
 		eps.GetAccountOperationsEndpoint = m(eps.GetAccountOperationsEndpoint)
 	}
-	for _, m := range mdw["MakeDepositEndpoint"] {
+	for _, m := range mdw["MakeDeposit"] {
 		eps.MakeDepositEndpoint = m(eps.MakeDepositEndpoint)
 	}
 	for _, m := range mdw["MakeTransfer"] {
 		eps.MakeTransferEndpoint = m(eps.MakeTransferEndpoint)
 	}
+	for _, m := range mdw["MakeBatchTransfer"] {
+		eps.MakeBatchTransferEndpoint = m(eps.MakeBatchTransferEndpoint)
+	}
+	for _, m := range mdw["MakeOperation"] {
+		eps.MakeOperationEndpoint = m(eps.MakeOperationEndpoint)
+	}
+	for _, m := range mdw["MakeWithdrawal"] {
+		eps.MakeWithdrawalEndpoint = m(eps.MakeWithdrawalEndpoint)
+	}
+	for _, m := range mdw["ReverseOperation"] {
+		eps.ReverseOperationEndpoint = m(eps.ReverseOperationEndpoint)
+	}
+	for _, m := range mdw["AuthorizeHold"] {
+		eps.AuthorizeHoldEndpoint = m(eps.AuthorizeHoldEndpoint)
+	}
+	for _, m := range mdw["CaptureHold"] {
+		eps.CaptureHoldEndpoint = m(eps.CaptureHoldEndpoint)
+	}
+	for _, m := range mdw["VoidHold"] {
+		eps.VoidHoldEndpoint = m(eps.VoidHoldEndpoint)
+	}
+	for _, m := range mdw["GetCurrencies"] {
+		eps.GetCurrenciesEndpoint = m(eps.GetCurrenciesEndpoint)
+	}
+	for _, m := range mdw["QuoteExchange"] {
+		eps.QuoteExchangeEndpoint = m(eps.QuoteExchangeEndpoint)
+	}
+	for _, m := range mdw["MakeExchangeTransfer"] {
+		eps.MakeExchangeTransferEndpoint = m(eps.MakeExchangeTransferEndpoint)
+	}
+	for _, m := range mdw["QuoteFee"] {
+		eps.QuoteFeeEndpoint = m(eps.QuoteFeeEndpoint)
+	}
+	for _, m := range mdw["GetSystemAccounts"] {
+		eps.GetSystemAccountsEndpoint = m(eps.GetSystemAccountsEndpoint)
+	}
+	for _, m := range mdw["FreezeAccount"] {
+		eps.FreezeAccountEndpoint = m(eps.FreezeAccountEndpoint)
+	}
+	for _, m := range mdw["UnfreezeAccount"] {
+		eps.UnfreezeAccountEndpoint = m(eps.UnfreezeAccountEndpoint)
+	}
+	for _, m := range mdw["CloseAccount"] {
+		eps.CloseAccountEndpoint = m(eps.CloseAccountEndpoint)
+	}
+	for _, m := range mdw["GetAccountStatusHistory"] {
+		eps.GetAccountStatusHistoryEndpoint = m(eps.GetAccountStatusHistoryEndpoint)
+	}
+	for _, m := range mdw["CreateSchedule"] {
+		eps.CreateScheduleEndpoint = m(eps.CreateScheduleEndpoint)
+	}
+	for _, m := range mdw["GetSchedule"] {
+		eps.GetScheduleEndpoint = m(eps.GetScheduleEndpoint)
+	}
+	for _, m := range mdw["GetAccountSchedules"] {
+		eps.GetAccountSchedulesEndpoint = m(eps.GetAccountSchedulesEndpoint)
+	}
+	for _, m := range mdw["UpdateSchedule"] {
+		eps.UpdateScheduleEndpoint = m(eps.UpdateScheduleEndpoint)
+	}
+	for _, m := range mdw["CancelSchedule"] {
+		eps.CancelScheduleEndpoint = m(eps.CancelScheduleEndpoint)
+	}
+	for _, m := range mdw["GetScheduleAttempts"] {
+		eps.GetScheduleAttemptsEndpoint = m(eps.GetScheduleAttemptsEndpoint)
+	}
+	for _, m := range mdw["CreateWebhook"] {
+		eps.CreateWebhookEndpoint = m(eps.CreateWebhookEndpoint)
+	}
+	for _, m := range mdw["GetWebhook"] {
+		eps.GetWebhookEndpoint = m(eps.GetWebhookEndpoint)
+	}
+	for _, m := range mdw["GetWebhooks"] {
+		eps.GetWebhooksEndpoint = m(eps.GetWebhooksEndpoint)
+	}
+	for _, m := range mdw["UpdateWebhook"] {
+		eps.UpdateWebhookEndpoint = m(eps.UpdateWebhookEndpoint)
+	}
+	for _, m := range mdw["DeleteWebhook"] {
+		eps.DeleteWebhookEndpoint = m(eps.DeleteWebhookEndpoint)
+	}
+	for _, m := range mdw["GetWebhookDeliveries"] {
+		eps.GetWebhookDeliveriesEndpoint = m(eps.GetWebhookDeliveriesEndpoint)
+	}
+	for _, m := range mdw["RedeliverWebhook"] {
+		eps.RedeliverWebhookEndpoint = m(eps.RedeliverWebhookEndpoint)
+	}
 	return eps
 }
 
@@ -0,0 +1,54 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shopspring/decimal"
+)
+
+// QuoteFeeRequest collects the request parameters for the QuoteFee method.
+type QuoteFeeRequest struct {
+	Type     service.OperationType `json:"type"`
+	Currency string                `json:"currency"`
+	Amount   decimal.Decimal       `json:"amount"`
+}
+
+// QuoteFeeResponse collects the response parameters for the QuoteFee method.
+type QuoteFeeResponse struct {
+	Fee decimal.Decimal `json:"fee"`
+	Err error           `json:"error,omitempty"`
+}
+
+// MakeQuoteFeeEndpoint returns an endpoint that invokes QuoteFee on the service.
+func MakeQuoteFeeEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(QuoteFeeRequest)
+		fee, err := s.QuoteFee(ctx, req.Type, req.Currency, req.Amount)
+		return QuoteFeeResponse{
+			Fee: fee,
+			Err: err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r QuoteFeeResponse) Failed() error {
+	return r.Err
+}
+
+// QuoteFee implements Service.
+func (e Endpoints) QuoteFee(ctx context.Context, t service.OperationType, currency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	request := QuoteFeeRequest{
+		Type:     t,
+		Currency: currency,
+		Amount:   amount,
+	}
+	response, err := e.QuoteFeeEndpoint(ctx, request)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return response.(QuoteFeeResponse).Fee, response.(QuoteFeeResponse).Err
+}
@@ -0,0 +1,102 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shopspring/decimal"
+)
+
+// QuoteExchangeRequest collects the request parameters for the QuoteExchange method.
+type QuoteExchangeRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	TTL  int64  `json:"ttl"` // seconds
+}
+
+// QuoteExchangeResponse collects the response parameters for the QuoteExchange method.
+type QuoteExchangeResponse struct {
+	Quote *service.FXQuote `json:"quote"`
+	Err   error            `json:"error,omitempty"`
+}
+
+// MakeQuoteExchangeEndpoint returns an endpoint that invokes QuoteExchange on the service.
+func MakeQuoteExchangeEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(QuoteExchangeRequest)
+		q, err := s.QuoteExchange(ctx, req.From, req.To, time.Duration(req.TTL)*time.Second)
+		return QuoteExchangeResponse{
+			Quote: q,
+			Err:   err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r QuoteExchangeResponse) Failed() error {
+	return r.Err
+}
+
+// MakeExchangeTransferRequest collects the request parameters for the MakeExchangeTransfer method.
+type MakeExchangeTransferRequest struct {
+	From    int64           `json:"from"`
+	To      int64           `json:"to"`
+	Amount  decimal.Decimal `json:"amount"`
+	QuoteID int64           `json:"quote_id"`
+}
+
+// MakeExchangeTransferResponse collects the response parameters for the MakeExchangeTransfer method.
+type MakeExchangeTransferResponse struct {
+	Operation *service.Operation `json:"operation"`
+	Err       error              `json:"error,omitempty"`
+}
+
+// MakeMakeExchangeTransferEndpoint returns an endpoint that invokes MakeExchangeTransfer on the service.
+func MakeMakeExchangeTransferEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(MakeExchangeTransferRequest)
+		o, err := s.MakeExchangeTransfer(ctx, req.From, req.To, req.Amount, req.QuoteID)
+		return MakeExchangeTransferResponse{
+			Operation: o,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r MakeExchangeTransferResponse) Failed() error {
+	return r.Err
+}
+
+// QuoteExchange implements Service.
+func (e Endpoints) QuoteExchange(ctx context.Context, from, to string, ttl time.Duration) (*service.FXQuote, error) {
+	request := QuoteExchangeRequest{
+		From: from,
+		To:   to,
+		TTL:  int64(ttl / time.Second),
+	}
+	response, err := e.QuoteExchangeEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(QuoteExchangeResponse).Quote, response.(QuoteExchangeResponse).Err
+}
+
+// MakeExchangeTransfer implements Service.
+func (e Endpoints) MakeExchangeTransfer(ctx context.Context, from, to int64, amount decimal.Decimal, quoteID int64) (*service.Operation, error) {
+	request := MakeExchangeTransferRequest{
+		From:    from,
+		To:      to,
+		Amount:  amount,
+		QuoteID: quoteID,
+	}
+	response, err := e.MakeExchangeTransferEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(MakeExchangeTransferResponse).Operation, response.(MakeExchangeTransferResponse).Err
+}
@@ -0,0 +1,141 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shopspring/decimal"
+)
+
+// AuthorizeHoldRequest collects the request parameters for the AuthorizeHold method.
+type AuthorizeHoldRequest struct {
+	AccountID int64           `json:"account_id"`
+	Currency  string          `json:"currency"`
+	Amount    decimal.Decimal `json:"amount"`
+	TTL       int64           `json:"ttl"` // seconds
+}
+
+// AuthorizeHoldResponse collects the response parameters for the AuthorizeHold method.
+type AuthorizeHoldResponse struct {
+	Hold *service.Hold `json:"hold"`
+	Err  error         `json:"error,omitempty"`
+}
+
+// MakeAuthorizeHoldEndpoint returns an endpoint that invokes AuthorizeHold on the service.
+func MakeAuthorizeHoldEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(AuthorizeHoldRequest)
+		h, err := s.AuthorizeHold(ctx, req.AccountID, req.Currency, req.Amount, time.Duration(req.TTL)*time.Second)
+		return AuthorizeHoldResponse{
+			Hold: h,
+			Err:  err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r AuthorizeHoldResponse) Failed() error {
+	return r.Err
+}
+
+// CaptureHoldRequest collects the request parameters for the CaptureHold method.
+type CaptureHoldRequest struct {
+	HoldID int64           `json:"hold_id"`
+	To     int64           `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// CaptureHoldResponse collects the response parameters for the CaptureHold method.
+type CaptureHoldResponse struct {
+	Operation *service.Operation `json:"operation"`
+	Err       error              `json:"error,omitempty"`
+}
+
+// MakeCaptureHoldEndpoint returns an endpoint that invokes CaptureHold on the service.
+func MakeCaptureHoldEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CaptureHoldRequest)
+		o, err := s.CaptureHold(ctx, req.HoldID, req.To, req.Amount)
+		return CaptureHoldResponse{
+			Operation: o,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r CaptureHoldResponse) Failed() error {
+	return r.Err
+}
+
+// VoidHoldRequest collects the request parameters for the VoidHold method.
+type VoidHoldRequest struct {
+	HoldID int64 `json:"hold_id"`
+}
+
+// VoidHoldResponse collects the response parameters for the VoidHold method.
+type VoidHoldResponse struct {
+	Hold *service.Hold `json:"hold"`
+	Err  error         `json:"error,omitempty"`
+}
+
+// MakeVoidHoldEndpoint returns an endpoint that invokes VoidHold on the service.
+func MakeVoidHoldEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(VoidHoldRequest)
+		h, err := s.VoidHold(ctx, req.HoldID)
+		return VoidHoldResponse{
+			Hold: h,
+			Err:  err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r VoidHoldResponse) Failed() error {
+	return r.Err
+}
+
+// AuthorizeHold implements Service.
+func (e Endpoints) AuthorizeHold(ctx context.Context, accID int64, currency string, amount decimal.Decimal, ttl time.Duration) (*service.Hold, error) {
+	request := AuthorizeHoldRequest{
+		AccountID: accID,
+		Currency:  currency,
+		Amount:    amount,
+		TTL:       int64(ttl / time.Second),
+	}
+	response, err := e.AuthorizeHoldEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(AuthorizeHoldResponse).Hold, response.(AuthorizeHoldResponse).Err
+}
+
+// CaptureHold implements Service.
+func (e Endpoints) CaptureHold(ctx context.Context, holdID int64, to int64, amount decimal.Decimal) (*service.Operation, error) {
+	request := CaptureHoldRequest{
+		HoldID: holdID,
+		To:     to,
+		Amount: amount,
+	}
+	response, err := e.CaptureHoldEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(CaptureHoldResponse).Operation, response.(CaptureHoldResponse).Err
+}
+
+// VoidHold implements Service.
+func (e Endpoints) VoidHold(ctx context.Context, holdID int64) (*service.Hold, error) {
+	request := VoidHoldRequest{HoldID: holdID}
+	response, err := e.VoidHoldEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(VoidHoldResponse).Hold, response.(VoidHoldResponse).Err
+}
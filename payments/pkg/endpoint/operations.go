@@ -13,6 +13,8 @@ type MakeDepositRequest struct {
 	To       int64           `json:"to"`
 	Currency string          `json:"currency"`
 	Amount   decimal.Decimal `json:"amount"`
+
+	IdempotencyKey string `json:"-"`
 }
 
 // MakeDepositResponse collects the response parameters for the MakeDeposit method.
@@ -25,6 +27,9 @@ type MakeDepositResponse struct {
 func MakeMakeDepositEndpoint(s service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(MakeDepositRequest)
+		if req.IdempotencyKey != "" {
+			ctx = service.WithIdempotencyKey(ctx, req.IdempotencyKey)
+		}
 		o, err := s.MakeDeposit(ctx, req.To, req.Currency, req.Amount)
 		return MakeDepositResponse{
 			Operation: o,
@@ -44,6 +49,8 @@ type MakeTransferRequest struct {
 	To       int64           `json:"to"`
 	Currency string          `json:"currency"`
 	Amount   decimal.Decimal `json:"amount"`
+
+	IdempotencyKey string `json:"-"`
 }
 
 // MakeTransferResponse collects the response parameters for the MakeTransfer method.
@@ -56,6 +63,9 @@ type MakeTransferResponse struct {
 func MakeMakeTransferEndpoint(s service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(MakeTransferRequest)
+		if req.IdempotencyKey != "" {
+			ctx = service.WithIdempotencyKey(ctx, req.IdempotencyKey)
+		}
 		o, err := s.MakeTransfer(ctx, req.From, req.To, req.Currency, req.Amount)
 		return MakeTransferResponse{
 			Operation: o,
@@ -69,12 +79,138 @@ func (r MakeTransferResponse) Failed() error {
 	return r.Err
 }
 
+// MakeBatchTransferRequest collects the request parameters for the MakeBatchTransfer method.
+type MakeBatchTransferRequest struct {
+	Legs []service.TransferLeg `json:"legs"`
+
+	IdempotencyKey string `json:"-"`
+}
+
+// MakeBatchTransferResponse collects the response parameters for the MakeBatchTransfer method.
+type MakeBatchTransferResponse struct {
+	Operation *service.Operation `json:"operation"`
+	Err       error              `json:"error,omitempty"`
+}
+
+// MakeMakeBatchTransferEndpoint returns an endpoint that invokes MakeBatchTransfer on the service.
+func MakeMakeBatchTransferEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(MakeBatchTransferRequest)
+		if req.IdempotencyKey != "" {
+			ctx = service.WithIdempotencyKey(ctx, req.IdempotencyKey)
+		}
+		o, err := s.MakeBatchTransfer(ctx, req.Legs)
+		return MakeBatchTransferResponse{
+			Operation: o,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r MakeBatchTransferResponse) Failed() error {
+	return r.Err
+}
+
+// MakeOperationRequest collects the request parameters for the MakeOperation method.
+type MakeOperationRequest struct {
+	Legs []service.OperationLeg `json:"legs"`
+
+	IdempotencyKey string `json:"-"`
+}
+
+// MakeOperationResponse collects the response parameters for the MakeOperation method.
+type MakeOperationResponse struct {
+	Operation *service.Operation `json:"operation"`
+	Err       error              `json:"error,omitempty"`
+}
+
+// MakeMakeOperationEndpoint returns an endpoint that invokes MakeOperation on the service.
+func MakeMakeOperationEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(MakeOperationRequest)
+		if req.IdempotencyKey != "" {
+			ctx = service.WithIdempotencyKey(ctx, req.IdempotencyKey)
+		}
+		o, err := s.MakeOperation(ctx, req.Legs)
+		return MakeOperationResponse{
+			Operation: o,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r MakeOperationResponse) Failed() error {
+	return r.Err
+}
+
+// MakeWithdrawalRequest collects the request parameters for the MakeWithdrawal method.
+type MakeWithdrawalRequest struct {
+	From     int64           `json:"from"`
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+// MakeWithdrawalResponse collects the response parameters for the MakeWithdrawal method.
+type MakeWithdrawalResponse struct {
+	Operation *service.Operation `json:"operation"`
+	Err       error              `json:"error,omitempty"`
+}
+
+// MakeMakeWithdrawalEndpoint returns an endpoint that invokes MakeWithdrawal on the service.
+func MakeMakeWithdrawalEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(MakeWithdrawalRequest)
+		o, err := s.MakeWithdrawal(ctx, req.From, req.Currency, req.Amount)
+		return MakeWithdrawalResponse{
+			Operation: o,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r MakeWithdrawalResponse) Failed() error {
+	return r.Err
+}
+
+// ReverseOperationRequest collects the request parameters for the ReverseOperation method.
+type ReverseOperationRequest struct {
+	OperationID int64  `json:"operation_id"`
+	Reason      string `json:"reason"`
+}
+
+// ReverseOperationResponse collects the response parameters for the ReverseOperation method.
+type ReverseOperationResponse struct {
+	Operation *service.Operation `json:"operation"`
+	Err       error              `json:"error,omitempty"`
+}
+
+// MakeReverseOperationEndpoint returns an endpoint that invokes ReverseOperation on the service.
+func MakeReverseOperationEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ReverseOperationRequest)
+		o, err := s.ReverseOperation(ctx, req.OperationID, req.Reason)
+		return ReverseOperationResponse{
+			Operation: o,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r ReverseOperationResponse) Failed() error {
+	return r.Err
+}
+
 // MakeDeposit implements Service.
 func (e Endpoints) MakeDeposit(ctx context.Context, to int64, currency string, amount decimal.Decimal) (*service.Operation, error) {
 	request := MakeDepositRequest{
-		Amount:   amount,
-		Currency: currency,
-		To:       to,
+		Amount:         amount,
+		Currency:       currency,
+		To:             to,
+		IdempotencyKey: service.IdempotencyKey(ctx),
 	}
 	response, err := e.MakeDepositEndpoint(ctx, request)
 	if err != nil {
@@ -86,15 +222,73 @@ func (e Endpoints) MakeDeposit(ctx context.Context, to int64, currency string, a
 // MakeTransfer implements Service.
 func (e Endpoints) MakeTransfer(ctx context.Context, from int64, to int64, currency string, amount decimal.Decimal) (*service.Operation, error) {
 	request := MakeTransferRequest{
+		Amount:         amount,
+		Currency:       currency,
+		From:           from,
+		To:             to,
+		IdempotencyKey: service.IdempotencyKey(ctx),
+	}
+	response, err := e.MakeTransferEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(MakeTransferResponse).Operation, response.(MakeTransferResponse).Err
+}
+
+// MakeBatchTransfer implements Service.
+func (e Endpoints) MakeBatchTransfer(ctx context.Context, legs []service.TransferLeg) (*service.Operation, error) {
+	request := MakeBatchTransferRequest{
+		Legs:           legs,
+		IdempotencyKey: service.IdempotencyKey(ctx),
+	}
+	response, err := e.MakeBatchTransferEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(MakeBatchTransferResponse).Operation, response.(MakeBatchTransferResponse).Err
+}
+
+// MakeOperation implements Service.
+func (e Endpoints) MakeOperation(ctx context.Context, legs []service.OperationLeg) (*service.Operation, error) {
+	request := MakeOperationRequest{
+		Legs:           legs,
+		IdempotencyKey: service.IdempotencyKey(ctx),
+	}
+	response, err := e.MakeOperationEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(MakeOperationResponse).Operation, response.(MakeOperationResponse).Err
+}
+
+// MakeWithdrawal implements Service.
+func (e Endpoints) MakeWithdrawal(ctx context.Context, from int64, currency string, amount decimal.Decimal) (*service.Operation, error) {
+	request := MakeWithdrawalRequest{
 		Amount:   amount,
 		Currency: currency,
 		From:     from,
-		To:       to,
 	}
-	response, err := e.MakeTransferEndpoint(ctx, request)
+	response, err := e.MakeWithdrawalEndpoint(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	return response.(MakeTransferResponse).Operation, response.(MakeTransferResponse).Err
+	return response.(MakeWithdrawalResponse).Operation, response.(MakeWithdrawalResponse).Err
+}
+
+// ReverseOperation implements Service.
+func (e Endpoints) ReverseOperation(ctx context.Context, operationID int64, reason string) (*service.Operation, error) {
+	request := ReverseOperationRequest{
+		OperationID: operationID,
+		Reason:      reason,
+	}
+	response, err := e.ReverseOperationEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.(ReverseOperationResponse).Operation, response.(ReverseOperationResponse).Err
 }
@@ -0,0 +1,289 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/shopspring/decimal"
+)
+
+// ScheduleSpecRequest collects the parameters of a standing order.
+type ScheduleSpecRequest struct {
+	From       int64                  `json:"from"`
+	To         int64                  `json:"to"`
+	Currency   string                 `json:"currency"`
+	Amount     decimal.Decimal        `json:"amount"`
+	Period     service.SchedulePeriod `json:"period"`
+	Every      int                    `json:"every"`
+	StartAt    *time.Time             `json:"start_at"`
+	EndAt      *time.Time             `json:"end_at"`
+	MaxRetries int                    `json:"max_retries"`
+	RetryDelay int64                  `json:"retry_delay"` // seconds
+}
+
+func (r ScheduleSpecRequest) spec() service.ScheduleSpec {
+	spec := service.ScheduleSpec{
+		From:       r.From,
+		To:         r.To,
+		Currency:   r.Currency,
+		Amount:     r.Amount,
+		Period:     r.Period,
+		Every:      r.Every,
+		EndAt:      r.EndAt,
+		MaxRetries: r.MaxRetries,
+		RetryDelay: time.Duration(r.RetryDelay) * time.Second,
+	}
+	if r.StartAt != nil {
+		spec.StartAt = *r.StartAt
+	}
+	return spec
+}
+
+func specRequest(spec service.ScheduleSpec) ScheduleSpecRequest {
+	r := ScheduleSpecRequest{
+		From:       spec.From,
+		To:         spec.To,
+		Currency:   spec.Currency,
+		Amount:     spec.Amount,
+		Period:     spec.Period,
+		Every:      spec.Every,
+		EndAt:      spec.EndAt,
+		MaxRetries: spec.MaxRetries,
+		RetryDelay: int64(spec.RetryDelay / time.Second),
+	}
+	if !spec.StartAt.IsZero() {
+		r.StartAt = &spec.StartAt
+	}
+	return r
+}
+
+// CreateScheduleRequest collects the request parameters for the CreateSchedule method.
+type CreateScheduleRequest struct {
+	ScheduleSpecRequest
+}
+
+// CreateScheduleResponse collects the response parameters for the CreateSchedule method.
+type CreateScheduleResponse struct {
+	Schedule *service.Schedule `json:"schedule"`
+	Err      error             `json:"error,omitempty"`
+}
+
+// MakeCreateScheduleEndpoint returns an endpoint that invokes CreateSchedule on the service.
+func MakeCreateScheduleEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateScheduleRequest)
+		sch, err := s.CreateSchedule(ctx, req.spec())
+		return CreateScheduleResponse{
+			Schedule: sch,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r CreateScheduleResponse) Failed() error {
+	return r.Err
+}
+
+// GetScheduleRequest collects the request parameters for the GetSchedule method.
+type GetScheduleRequest struct {
+	ScheduleID int64 `json:"schedule_id"`
+}
+
+// GetScheduleResponse collects the response parameters for the GetSchedule method.
+type GetScheduleResponse struct {
+	Schedule *service.Schedule `json:"schedule"`
+	Err      error             `json:"error,omitempty"`
+}
+
+// MakeGetScheduleEndpoint returns an endpoint that invokes GetSchedule on the service.
+func MakeGetScheduleEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetScheduleRequest)
+		sch, err := s.GetSchedule(ctx, req.ScheduleID)
+		return GetScheduleResponse{
+			Schedule: sch,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetScheduleResponse) Failed() error {
+	return r.Err
+}
+
+// GetAccountSchedulesRequest collects the request parameters for the GetAccountSchedules method.
+type GetAccountSchedulesRequest struct {
+	AccountID int64 `json:"account_id"`
+}
+
+// GetAccountSchedulesResponse collects the response parameters for the GetAccountSchedules method.
+type GetAccountSchedulesResponse struct {
+	Schedules []*service.Schedule `json:"schedules"`
+	Err       error               `json:"error,omitempty"`
+}
+
+// MakeGetAccountSchedulesEndpoint returns an endpoint that invokes GetAccountSchedules on the service.
+func MakeGetAccountSchedulesEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetAccountSchedulesRequest)
+		ss, err := s.GetAccountSchedules(ctx, req.AccountID)
+		return GetAccountSchedulesResponse{
+			Schedules: ss,
+			Err:       err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetAccountSchedulesResponse) Failed() error {
+	return r.Err
+}
+
+// UpdateScheduleRequest collects the request parameters for the UpdateSchedule method.
+type UpdateScheduleRequest struct {
+	ScheduleID int64 `json:"schedule_id"`
+	ScheduleSpecRequest
+}
+
+// UpdateScheduleResponse collects the response parameters for the UpdateSchedule method.
+type UpdateScheduleResponse struct {
+	Schedule *service.Schedule `json:"schedule"`
+	Err      error             `json:"error,omitempty"`
+}
+
+// MakeUpdateScheduleEndpoint returns an endpoint that invokes UpdateSchedule on the service.
+func MakeUpdateScheduleEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateScheduleRequest)
+		sch, err := s.UpdateSchedule(ctx, req.ScheduleID, req.spec())
+		return UpdateScheduleResponse{
+			Schedule: sch,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r UpdateScheduleResponse) Failed() error {
+	return r.Err
+}
+
+// CancelScheduleRequest collects the request parameters for the CancelSchedule method.
+type CancelScheduleRequest struct {
+	ScheduleID int64 `json:"schedule_id"`
+}
+
+// CancelScheduleResponse collects the response parameters for the CancelSchedule method.
+type CancelScheduleResponse struct {
+	Schedule *service.Schedule `json:"schedule"`
+	Err      error             `json:"error,omitempty"`
+}
+
+// MakeCancelScheduleEndpoint returns an endpoint that invokes CancelSchedule on the service.
+func MakeCancelScheduleEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CancelScheduleRequest)
+		sch, err := s.CancelSchedule(ctx, req.ScheduleID)
+		return CancelScheduleResponse{
+			Schedule: sch,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r CancelScheduleResponse) Failed() error {
+	return r.Err
+}
+
+// GetScheduleAttemptsRequest collects the request parameters for the GetScheduleAttempts method.
+type GetScheduleAttemptsRequest struct {
+	ScheduleID int64 `json:"schedule_id"`
+}
+
+// GetScheduleAttemptsResponse collects the response parameters for the GetScheduleAttempts method.
+type GetScheduleAttemptsResponse struct {
+	Attempts []*service.ScheduleAttempt `json:"attempts"`
+	Err      error                      `json:"error,omitempty"`
+}
+
+// MakeGetScheduleAttemptsEndpoint returns an endpoint that invokes GetScheduleAttempts on the service.
+func MakeGetScheduleAttemptsEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetScheduleAttemptsRequest)
+		as, err := s.GetScheduleAttempts(ctx, req.ScheduleID)
+		return GetScheduleAttemptsResponse{
+			Attempts: as,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetScheduleAttemptsResponse) Failed() error {
+	return r.Err
+}
+
+// CreateSchedule implements Service.
+func (e Endpoints) CreateSchedule(ctx context.Context, spec service.ScheduleSpec) (*service.Schedule, error) {
+	request := CreateScheduleRequest{specRequest(spec)}
+	response, err := e.CreateScheduleEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(CreateScheduleResponse).Schedule, response.(CreateScheduleResponse).Err
+}
+
+// GetSchedule implements Service.
+func (e Endpoints) GetSchedule(ctx context.Context, id int64) (*service.Schedule, error) {
+	request := GetScheduleRequest{ScheduleID: id}
+	response, err := e.GetScheduleEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetScheduleResponse).Schedule, response.(GetScheduleResponse).Err
+}
+
+// GetAccountSchedules implements Service.
+func (e Endpoints) GetAccountSchedules(ctx context.Context, accID int64) ([]*service.Schedule, error) {
+	request := GetAccountSchedulesRequest{AccountID: accID}
+	response, err := e.GetAccountSchedulesEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetAccountSchedulesResponse).Schedules, response.(GetAccountSchedulesResponse).Err
+}
+
+// UpdateSchedule implements Service.
+func (e Endpoints) UpdateSchedule(ctx context.Context, id int64, spec service.ScheduleSpec) (*service.Schedule, error) {
+	request := UpdateScheduleRequest{ScheduleID: id, ScheduleSpecRequest: specRequest(spec)}
+	response, err := e.UpdateScheduleEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(UpdateScheduleResponse).Schedule, response.(UpdateScheduleResponse).Err
+}
+
+// CancelSchedule implements Service.
+func (e Endpoints) CancelSchedule(ctx context.Context, id int64) (*service.Schedule, error) {
+	request := CancelScheduleRequest{ScheduleID: id}
+	response, err := e.CancelScheduleEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(CancelScheduleResponse).Schedule, response.(CancelScheduleResponse).Err
+}
+
+// GetScheduleAttempts implements Service.
+func (e Endpoints) GetScheduleAttempts(ctx context.Context, id int64) ([]*service.ScheduleAttempt, error) {
+	request := GetScheduleAttemptsRequest{ScheduleID: id}
+	response, err := e.GetScheduleAttemptsEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetScheduleAttemptsResponse).Attempts, response.(GetScheduleAttemptsResponse).Err
+}
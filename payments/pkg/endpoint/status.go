@@ -0,0 +1,164 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// FreezeAccountRequest collects the request parameters for the FreezeAccount method.
+type FreezeAccountRequest struct {
+	AccountID int64  `json:"account_id"`
+	Reason    string `json:"reason"`
+}
+
+// FreezeAccountResponse collects the response parameters for the FreezeAccount method.
+type FreezeAccountResponse struct {
+	Account *service.Account `json:"account"`
+	Err     error            `json:"error,omitempty"`
+}
+
+// MakeFreezeAccountEndpoint returns an endpoint that invokes FreezeAccount on the service.
+func MakeFreezeAccountEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(FreezeAccountRequest)
+		a, err := s.FreezeAccount(ctx, req.AccountID, req.Reason)
+		return FreezeAccountResponse{
+			Account: a,
+			Err:     err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r FreezeAccountResponse) Failed() error {
+	return r.Err
+}
+
+// UnfreezeAccountRequest collects the request parameters for the UnfreezeAccount method.
+type UnfreezeAccountRequest struct {
+	AccountID int64  `json:"account_id"`
+	Reason    string `json:"reason"`
+}
+
+// UnfreezeAccountResponse collects the response parameters for the UnfreezeAccount method.
+type UnfreezeAccountResponse struct {
+	Account *service.Account `json:"account"`
+	Err     error            `json:"error,omitempty"`
+}
+
+// MakeUnfreezeAccountEndpoint returns an endpoint that invokes UnfreezeAccount on the service.
+func MakeUnfreezeAccountEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UnfreezeAccountRequest)
+		a, err := s.UnfreezeAccount(ctx, req.AccountID, req.Reason)
+		return UnfreezeAccountResponse{
+			Account: a,
+			Err:     err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r UnfreezeAccountResponse) Failed() error {
+	return r.Err
+}
+
+// CloseAccountRequest collects the request parameters for the CloseAccount method.
+type CloseAccountRequest struct {
+	AccountID int64  `json:"account_id"`
+	SweepTo   int64  `json:"sweep_to"`
+	Reason    string `json:"reason"`
+}
+
+// CloseAccountResponse collects the response parameters for the CloseAccount method.
+type CloseAccountResponse struct {
+	Account *service.Account `json:"account"`
+	Err     error            `json:"error,omitempty"`
+}
+
+// MakeCloseAccountEndpoint returns an endpoint that invokes CloseAccount on the service.
+func MakeCloseAccountEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CloseAccountRequest)
+		a, err := s.CloseAccount(ctx, req.AccountID, req.SweepTo, req.Reason)
+		return CloseAccountResponse{
+			Account: a,
+			Err:     err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r CloseAccountResponse) Failed() error {
+	return r.Err
+}
+
+// GetAccountStatusHistoryRequest collects the request parameters for the GetAccountStatusHistory method.
+type GetAccountStatusHistoryRequest struct {
+	AccountID int64 `json:"account_id"`
+}
+
+// GetAccountStatusHistoryResponse collects the response parameters for the GetAccountStatusHistory method.
+type GetAccountStatusHistoryResponse struct {
+	Changes []*service.AccountStatusChange `json:"changes"`
+	Err     error                          `json:"error,omitempty"`
+}
+
+// MakeGetAccountStatusHistoryEndpoint returns an endpoint that invokes GetAccountStatusHistory on the service.
+func MakeGetAccountStatusHistoryEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetAccountStatusHistoryRequest)
+		cs, err := s.GetAccountStatusHistory(ctx, req.AccountID)
+		return GetAccountStatusHistoryResponse{
+			Changes: cs,
+			Err:     err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetAccountStatusHistoryResponse) Failed() error {
+	return r.Err
+}
+
+// FreezeAccount implements Service.
+func (e Endpoints) FreezeAccount(ctx context.Context, id int64, reason string) (*service.Account, error) {
+	request := FreezeAccountRequest{AccountID: id, Reason: reason}
+	response, err := e.FreezeAccountEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(FreezeAccountResponse).Account, response.(FreezeAccountResponse).Err
+}
+
+// UnfreezeAccount implements Service.
+func (e Endpoints) UnfreezeAccount(ctx context.Context, id int64, reason string) (*service.Account, error) {
+	request := UnfreezeAccountRequest{AccountID: id, Reason: reason}
+	response, err := e.UnfreezeAccountEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(UnfreezeAccountResponse).Account, response.(UnfreezeAccountResponse).Err
+}
+
+// CloseAccount implements Service.
+func (e Endpoints) CloseAccount(ctx context.Context, id int64, sweepTo int64, reason string) (*service.Account, error) {
+	request := CloseAccountRequest{AccountID: id, SweepTo: sweepTo, Reason: reason}
+	response, err := e.CloseAccountEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(CloseAccountResponse).Account, response.(CloseAccountResponse).Err
+}
+
+// GetAccountStatusHistory implements Service.
+func (e Endpoints) GetAccountStatusHistory(ctx context.Context, id int64) ([]*service.AccountStatusChange, error) {
+	request := GetAccountStatusHistoryRequest{AccountID: id}
+	response, err := e.GetAccountStatusHistoryEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetAccountStatusHistoryResponse).Changes, response.(GetAccountStatusHistoryResponse).Err
+}
@@ -0,0 +1,43 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// GetSystemAccountsRequest collects the request parameters for the GetSystemAccounts method.
+type GetSystemAccountsRequest struct{}
+
+// GetSystemAccountsResponse collects the response parameters for the GetSystemAccounts method.
+type GetSystemAccountsResponse struct {
+	Accounts []*service.Account `json:"accounts"`
+	Err      error              `json:"error,omitempty"`
+}
+
+// MakeGetSystemAccountsEndpoint returns an endpoint that invokes GetSystemAccounts on the service.
+func MakeGetSystemAccountsEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		a, err := s.GetSystemAccounts(ctx)
+		return GetSystemAccountsResponse{
+			Accounts: a,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetSystemAccountsResponse) Failed() error {
+	return r.Err
+}
+
+// GetSystemAccounts implements Service.
+func (e Endpoints) GetSystemAccounts(ctx context.Context) ([]*service.Account, error) {
+	request := GetSystemAccountsRequest{}
+	response, err := e.GetSystemAccountsEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetSystemAccountsResponse).Accounts, response.(GetSystemAccountsResponse).Err
+}
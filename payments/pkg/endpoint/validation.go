@@ -0,0 +1,382 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/deterok/go_test_task/payments/pkg/validation"
+)
+
+// ValidationMiddleware returns an endpoint middleware that rejects requests
+// implementing validation.Validator which don't pass their checks.
+func ValidationMiddleware() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if v, ok := request.(validation.Validator); ok {
+				if err := v.Validate(); err != nil {
+					return nil, err
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// Validate implements validation.Validator.
+func (r CreateAccountRequest) Validate() error {
+	e := validation.Errors{}
+	e.Required("name", r.Name)
+	e.Currency("currency", r.Currency)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetAccountRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("id", r.ID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetAccountsRequest) Validate() error {
+	e := validation.Errors{}
+	if r.Currency != "" {
+		e.Currency("currency", r.Currency)
+	}
+	e.MaxLength("name_prefix", r.NamePrefix)
+	e.AmountRange("min_amount", "max_amount", r.MinAmount, r.MaxAmount)
+	e.TimeRange("created_from", "created_to", r.CreatedFrom, r.CreatedTo)
+	switch r.Deleted {
+	case "", service.DeletedStatusActive, service.DeletedStatusDeleted, service.DeletedStatusAll:
+	default:
+		e.Add("deleted", "must be %q, %q or %q", service.DeletedStatusActive, service.DeletedStatusDeleted, service.DeletedStatusAll)
+	}
+	e.Page(r.Cursor, r.Limit, r.Order)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetAccountOperationsRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	for _, t := range r.Types {
+		if t.String() == "" {
+			e.Add("types", "must contain operation types only")
+			break
+		}
+	}
+	e.TimeRange("created_from", "created_to", r.CreatedFrom, r.CreatedTo)
+	if r.Counterparty != 0 {
+		e.ID("counterparty", r.Counterparty)
+	}
+	e.AmountRange("min_amount", "max_amount", r.MinAmount, r.MaxAmount)
+	e.Page(r.Cursor, r.Limit, r.Order)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r MakeDepositRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("to", r.To)
+	e.Currency("currency", r.Currency)
+	e.Amount("amount", r.Amount)
+	e.MaxLength("idempotency_key", r.IdempotencyKey)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r MakeTransferRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("from", r.From)
+	e.ID("to", r.To)
+	if r.From == r.To {
+		e.Add("to", "must differ from the donor account")
+	}
+	e.Currency("currency", r.Currency)
+	e.Amount("amount", r.Amount)
+	e.MaxLength("idempotency_key", r.IdempotencyKey)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r MakeBatchTransferRequest) Validate() error {
+	e := validation.Errors{}
+	switch {
+	case len(r.Legs) == 0:
+		e.Add("legs", "must not be empty")
+	case len(r.Legs) > service.MaxBatchLegs:
+		e.Add("legs", "must contain at most %d legs", service.MaxBatchLegs)
+	}
+	for i, l := range r.Legs {
+		field := func(name string) string { return fmt.Sprintf("legs[%d].%s", i, name) }
+		e.ID(field("from"), l.From)
+		e.ID(field("to"), l.To)
+		if l.From == l.To {
+			e.Add(field("to"), "must differ from the donor account")
+		}
+		e.Currency(field("currency"), l.Currency)
+		e.Amount(field("amount"), l.Amount)
+	}
+	e.MaxLength("idempotency_key", r.IdempotencyKey)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r MakeOperationRequest) Validate() error {
+	e := validation.Errors{}
+	switch {
+	case len(r.Legs) < 2:
+		e.Add("legs", "must contain at least 2 legs")
+	case len(r.Legs) > service.MaxOperationLegs:
+		e.Add("legs", "must contain at most %d legs", service.MaxOperationLegs)
+	}
+	for i, l := range r.Legs {
+		field := func(name string) string { return fmt.Sprintf("legs[%d].%s", i, name) }
+		e.ID(field("account_id"), l.AccountID)
+		e.Currency(field("currency"), l.Currency)
+		e.SignedAmount(field("amount"), l.Amount)
+	}
+	e.MaxLength("idempotency_key", r.IdempotencyKey)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r MakeWithdrawalRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("from", r.From)
+	e.Currency("currency", r.Currency)
+	e.Amount("amount", r.Amount)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r ReverseOperationRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("operation_id", r.OperationID)
+	e.MaxLength("reason", r.Reason)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r FreezeAccountRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	e.MaxLength("reason", r.Reason)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r UnfreezeAccountRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	e.MaxLength("reason", r.Reason)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r CloseAccountRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	if r.SweepTo != 0 {
+		e.ID("sweep_to", r.SweepTo)
+		if r.SweepTo == r.AccountID {
+			e.Add("sweep_to", "must differ from the closed account")
+		}
+	}
+	e.MaxLength("reason", r.Reason)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetAccountStatusHistoryRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r AuthorizeHoldRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	e.Currency("currency", r.Currency)
+	e.Amount("amount", r.Amount)
+	if r.TTL < 0 {
+		e.Add("ttl", "must not be negative")
+	}
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r CaptureHoldRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("hold_id", r.HoldID)
+	e.ID("to", r.To)
+	e.OptionalAmount("amount", r.Amount)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r VoidHoldRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("hold_id", r.HoldID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r QuoteExchangeRequest) Validate() error {
+	e := validation.Errors{}
+	e.Currency("from", r.From)
+	e.Currency("to", r.To)
+	if r.TTL < 0 {
+		e.Add("ttl", "must not be negative")
+	}
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r MakeExchangeTransferRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("from", r.From)
+	e.ID("to", r.To)
+	if r.From == r.To {
+		e.Add("to", "must differ from the donor account")
+	}
+	e.Amount("amount", r.Amount)
+	e.ID("quote_id", r.QuoteID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r QuoteFeeRequest) Validate() error {
+	e := validation.Errors{}
+	if r.Type.String() == "" {
+		e.Add("type", "must be an operation type")
+	}
+	e.Currency("currency", r.Currency)
+	e.Amount("amount", r.Amount)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r CreateScheduleRequest) Validate() error {
+	e := validation.Errors{}
+	r.validate(&e)
+	return e.Err()
+}
+
+// validate checks the schedule spec, the rules are shared by creation and update
+func (r ScheduleSpecRequest) validate(e *validation.Errors) {
+	e.ID("from", r.From)
+	e.ID("to", r.To)
+	if r.From == r.To {
+		e.Add("to", "must differ from the donor account")
+	}
+	e.Currency("currency", r.Currency)
+	e.Amount("amount", r.Amount)
+	switch r.Period {
+	case service.SchedulePeriodOnce, service.SchedulePeriodDay, service.SchedulePeriodWeek, service.SchedulePeriodMonth:
+	default:
+		e.Add("period", "must be %q, %q, %q or %q",
+			service.SchedulePeriodOnce, service.SchedulePeriodDay, service.SchedulePeriodWeek, service.SchedulePeriodMonth)
+	}
+	if r.Every < 0 {
+		e.Add("every", "must not be negative")
+	}
+	e.TimeRange("start_at", "end_at", r.StartAt, r.EndAt)
+	if r.MaxRetries < 0 {
+		e.Add("max_retries", "must not be negative")
+	}
+	if r.RetryDelay < 0 {
+		e.Add("retry_delay", "must not be negative")
+	}
+}
+
+// Validate implements validation.Validator.
+func (r GetScheduleRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("schedule_id", r.ScheduleID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetAccountSchedulesRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("account_id", r.AccountID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r UpdateScheduleRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("schedule_id", r.ScheduleID)
+	r.validate(&e)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r CancelScheduleRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("schedule_id", r.ScheduleID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetScheduleAttemptsRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("schedule_id", r.ScheduleID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r CreateWebhookRequest) Validate() error {
+	e := validation.Errors{}
+	r.validate(&e)
+	return e.Err()
+}
+
+// validate checks the webhook spec, the rules are shared by creation and update
+func (r WebhookSpecRequest) validate(e *validation.Errors) {
+	e.Required("url", r.URL)
+	for i, id := range r.Accounts {
+		e.ID(fmt.Sprintf("accounts[%d]", i), id)
+	}
+}
+
+// Validate implements validation.Validator.
+func (r GetWebhookRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("webhook_id", r.WebhookID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r UpdateWebhookRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("webhook_id", r.WebhookID)
+	r.validate(&e)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r DeleteWebhookRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("webhook_id", r.WebhookID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r GetWebhookDeliveriesRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("webhook_id", r.WebhookID)
+	return e.Err()
+}
+
+// Validate implements validation.Validator.
+func (r RedeliverWebhookRequest) Validate() error {
+	e := validation.Errors{}
+	e.ID("delivery_id", r.DeliveryID)
+	return e.Err()
+}
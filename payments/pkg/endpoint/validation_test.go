@@ -0,0 +1,240 @@
+package endpoint_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+	payhttp "github.com/deterok/go_test_task/payments/pkg/http"
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/deterok/go_test_task/payments/pkg/validation"
+)
+
+// fields returns names of the invalid fields of the validation error
+func fields(err error) []string {
+	res := []string{}
+	for _, f := range service.FieldsOf(err) {
+		res = append(res, f.Field)
+	}
+	return res
+}
+
+func TestRequests_Validate(t *testing.T) {
+	type request func(id int64, amount decimal.Decimal) validation.Validator
+
+	// amounts are checked by every request with an amount
+	amounts := map[string]decimal.Decimal{
+		"missing":  {},
+		"zero":     decimal.Zero,
+		"negative": decimal.RequireFromString("-1"),
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		// idField and amountField are the fields filled by the id and the amount
+		idField     string
+		amountField string
+		// accepted are the amounts which are valid for the request
+		accepted []string
+	}{
+		{
+			name: "CreateAccount",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.CreateAccountRequest{Name: "test", Currency: "USD"}
+			},
+		},
+		{
+			name: "GetAccount",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.GetAccountRequest{ID: id}
+			},
+			idField: "id",
+		},
+		{
+			name: "MakeDeposit",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.MakeDepositRequest{To: id, Currency: "USD", Amount: amount}
+			},
+			idField:     "to",
+			amountField: "amount",
+		},
+		{
+			name: "MakeTransfer",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.MakeTransferRequest{From: id, To: 2, Currency: "USD", Amount: amount}
+			},
+			idField:     "from",
+			amountField: "amount",
+		},
+		{
+			name: "MakeBatchTransfer",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.MakeBatchTransferRequest{Legs: []service.TransferLeg{
+					{From: id, To: 2, Currency: "USD", Amount: amount},
+				}}
+			},
+			idField:     "legs[0].from",
+			amountField: "legs[0].amount",
+		},
+		{
+			name: "MakeOperation",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.MakeOperationRequest{Legs: []service.OperationLeg{
+					{AccountID: id, Currency: "USD", Amount: amount.Neg()},
+					{AccountID: 2, Currency: "USD", Amount: amount},
+				}}
+			},
+			idField:     "legs[0].account_id",
+			amountField: "legs[1].amount",
+			// Legs are signed
+			accepted: []string{"negative"},
+		},
+		{
+			name: "MakeWithdrawal",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.MakeWithdrawalRequest{From: id, Currency: "USD", Amount: amount}
+			},
+			idField:     "from",
+			amountField: "amount",
+		},
+		{
+			name: "ReverseOperation",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.ReverseOperationRequest{OperationID: id}
+			},
+			idField: "operation_id",
+		},
+		{
+			name: "CloseAccount",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.CloseAccountRequest{AccountID: id, SweepTo: 2}
+			},
+			idField: "account_id",
+		},
+		{
+			name: "AuthorizeHold",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.AuthorizeHoldRequest{AccountID: id, Currency: "USD", Amount: amount}
+			},
+			idField:     "account_id",
+			amountField: "amount",
+		},
+		{
+			name: "CaptureHold",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.CaptureHoldRequest{HoldID: id, To: 2, Amount: amount}
+			},
+			idField:     "hold_id",
+			amountField: "amount",
+			// Captures the whole hold
+			accepted: []string{"missing", "zero"},
+		},
+		{
+			name: "MakeExchangeTransfer",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.MakeExchangeTransferRequest{From: 2, To: 3, Amount: amount, QuoteID: id}
+			},
+			idField:     "quote_id",
+			amountField: "amount",
+		},
+		{
+			name: "QuoteFee",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.QuoteFeeRequest{Type: service.OperationTypeTransfer, Currency: "USD", Amount: amount}
+			},
+			amountField: "amount",
+		},
+		{
+			name: "CreateSchedule",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.CreateScheduleRequest{ScheduleSpecRequest: endpoint.ScheduleSpecRequest{
+					From: id, To: 2, Currency: "USD", Amount: amount, Period: service.SchedulePeriodOnce,
+				}}
+			},
+			idField:     "from",
+			amountField: "amount",
+		},
+		{
+			name: "UpdateSchedule",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.UpdateScheduleRequest{ScheduleID: id, ScheduleSpecRequest: endpoint.ScheduleSpecRequest{
+					From: 1, To: 2, Currency: "USD", Amount: amount, Period: service.SchedulePeriodOnce,
+				}}
+			},
+			idField:     "schedule_id",
+			amountField: "amount",
+		},
+		{
+			name: "GetWebhook",
+			request: func(id int64, amount decimal.Decimal) validation.Validator {
+				return endpoint.GetWebhookRequest{WebhookID: id}
+			},
+			idField: "webhook_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid := decimal.RequireFromString("10")
+			assert.NoError(t, tt.request(1, valid).Validate())
+
+			if tt.idField != "" {
+				for _, id := range []int64{0, -1} {
+					err := tt.request(id, valid).Validate()
+					assert.Equal(t, service.ErrorKindValidation, service.KindOf(err), "id %d", id)
+					assert.Equal(t, []string{tt.idField}, fields(err), "id %d", id)
+				}
+			}
+
+			if tt.amountField != "" {
+				accepted := map[string]bool{}
+				for _, name := range tt.accepted {
+					accepted[name] = true
+				}
+
+				for name, amount := range amounts {
+					err := tt.request(1, amount).Validate()
+					if accepted[name] {
+						assert.NoError(t, err, name)
+						continue
+					}
+					assert.Equal(t, service.ErrorKindValidation, service.KindOf(err), name)
+					assert.Contains(t, fields(err), tt.amountField, name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	calls := 0
+	e := endpoint.ValidationMiddleware()(func(ctx context.Context, request interface{}) (interface{}, error) {
+		calls++
+		return request, nil
+	})
+
+	// Invalid requests don't reach the service
+	_, err := e(context.Background(), endpoint.MakeTransferRequest{From: 1, To: 2, Currency: "USD"})
+	assert.Equal(t, service.ErrorKindValidation, service.KindOf(err))
+	assert.Equal(t, "invalid_request", service.CodeOf(err))
+	assert.Equal(t, []string{"amount"}, fields(err))
+	assert.Equal(t, 0, calls)
+
+	rec := httptest.NewRecorder()
+	payhttp.ErrorEncoder(context.Background(), err, rec)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"amount"`)
+
+	_, err = e(context.Background(), endpoint.MakeTransferRequest{From: 1, To: 2, Currency: "USD", Amount: decimal.New(1, 0)})
+	assert.NoError(t, err)
+
+	// Requests without rules pass as is
+	_, err = e(context.Background(), endpoint.GetCurrenciesRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,300 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// WebhookSpecRequest collects the parameters of a webhook subscription.
+type WebhookSpecRequest struct {
+	URL      string              `json:"url"`
+	Events   []service.EventType `json:"events"`
+	Accounts []int64             `json:"accounts"`
+}
+
+func (r WebhookSpecRequest) spec() service.WebhookSpec {
+	return service.WebhookSpec{
+		URL:      r.URL,
+		Events:   r.Events,
+		Accounts: r.Accounts,
+	}
+}
+
+func specWebhookRequest(spec service.WebhookSpec) WebhookSpecRequest {
+	return WebhookSpecRequest{
+		URL:      spec.URL,
+		Events:   spec.Events,
+		Accounts: spec.Accounts,
+	}
+}
+
+// CreateWebhookRequest collects the request parameters for the CreateWebhook method.
+type CreateWebhookRequest struct {
+	WebhookSpecRequest
+}
+
+// CreateWebhookResponse collects the response parameters for the CreateWebhook method.
+// The secret of the webhook isn't returned by any other method.
+type CreateWebhookResponse struct {
+	Webhook *service.Webhook `json:"webhook"`
+	Secret  string           `json:"secret,omitempty"`
+	Err     error            `json:"error,omitempty"`
+}
+
+// MakeCreateWebhookEndpoint returns an endpoint that invokes CreateWebhook on the service.
+func MakeCreateWebhookEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateWebhookRequest)
+		w, err := s.CreateWebhook(ctx, req.spec())
+
+		resp := CreateWebhookResponse{
+			Webhook: w,
+			Err:     err,
+		}
+		if w != nil {
+			resp.Secret = w.Secret
+		}
+		return resp, nil
+	}
+}
+
+// Failed implements Failer.
+func (r CreateWebhookResponse) Failed() error {
+	return r.Err
+}
+
+// GetWebhookRequest collects the request parameters for the GetWebhook method.
+type GetWebhookRequest struct {
+	WebhookID int64 `json:"webhook_id"`
+}
+
+// GetWebhookResponse collects the response parameters for the GetWebhook method.
+type GetWebhookResponse struct {
+	Webhook *service.Webhook `json:"webhook"`
+	Err     error            `json:"error,omitempty"`
+}
+
+// MakeGetWebhookEndpoint returns an endpoint that invokes GetWebhook on the service.
+func MakeGetWebhookEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetWebhookRequest)
+		w, err := s.GetWebhook(ctx, req.WebhookID)
+		return GetWebhookResponse{
+			Webhook: w,
+			Err:     err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetWebhookResponse) Failed() error {
+	return r.Err
+}
+
+// GetWebhooksRequest collects the request parameters for the GetWebhooks method.
+type GetWebhooksRequest struct{}
+
+// GetWebhooksResponse collects the response parameters for the GetWebhooks method.
+type GetWebhooksResponse struct {
+	Webhooks []*service.Webhook `json:"webhooks"`
+	Err      error              `json:"error,omitempty"`
+}
+
+// MakeGetWebhooksEndpoint returns an endpoint that invokes GetWebhooks on the service.
+func MakeGetWebhooksEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ws, err := s.GetWebhooks(ctx)
+		return GetWebhooksResponse{
+			Webhooks: ws,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetWebhooksResponse) Failed() error {
+	return r.Err
+}
+
+// UpdateWebhookRequest collects the request parameters for the UpdateWebhook method.
+type UpdateWebhookRequest struct {
+	WebhookID int64 `json:"webhook_id"`
+	WebhookSpecRequest
+}
+
+// UpdateWebhookResponse collects the response parameters for the UpdateWebhook method.
+type UpdateWebhookResponse struct {
+	Webhook *service.Webhook `json:"webhook"`
+	Err     error            `json:"error,omitempty"`
+}
+
+// MakeUpdateWebhookEndpoint returns an endpoint that invokes UpdateWebhook on the service.
+func MakeUpdateWebhookEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateWebhookRequest)
+		w, err := s.UpdateWebhook(ctx, req.WebhookID, req.spec())
+		return UpdateWebhookResponse{
+			Webhook: w,
+			Err:     err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r UpdateWebhookResponse) Failed() error {
+	return r.Err
+}
+
+// DeleteWebhookRequest collects the request parameters for the DeleteWebhook method.
+type DeleteWebhookRequest struct {
+	WebhookID int64 `json:"webhook_id"`
+}
+
+// DeleteWebhookResponse collects the response parameters for the DeleteWebhook method.
+type DeleteWebhookResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+// MakeDeleteWebhookEndpoint returns an endpoint that invokes DeleteWebhook on the service.
+func MakeDeleteWebhookEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteWebhookRequest)
+		err := s.DeleteWebhook(ctx, req.WebhookID)
+		return DeleteWebhookResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r DeleteWebhookResponse) Failed() error {
+	return r.Err
+}
+
+// GetWebhookDeliveriesRequest collects the request parameters for the GetWebhookDeliveries method.
+type GetWebhookDeliveriesRequest struct {
+	WebhookID int64 `json:"webhook_id"`
+}
+
+// GetWebhookDeliveriesResponse collects the response parameters for the GetWebhookDeliveries method.
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []*service.WebhookDelivery `json:"deliveries"`
+	Err        error                      `json:"error,omitempty"`
+}
+
+// MakeGetWebhookDeliveriesEndpoint returns an endpoint that invokes GetWebhookDeliveries on the service.
+func MakeGetWebhookDeliveriesEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetWebhookDeliveriesRequest)
+		ds, err := s.GetWebhookDeliveries(ctx, req.WebhookID)
+		return GetWebhookDeliveriesResponse{
+			Deliveries: ds,
+			Err:        err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r GetWebhookDeliveriesResponse) Failed() error {
+	return r.Err
+}
+
+// RedeliverWebhookRequest collects the request parameters for the RedeliverWebhook method.
+type RedeliverWebhookRequest struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// RedeliverWebhookResponse collects the response parameters for the RedeliverWebhook method.
+type RedeliverWebhookResponse struct {
+	Delivery *service.WebhookDelivery `json:"delivery"`
+	Err      error                    `json:"error,omitempty"`
+}
+
+// MakeRedeliverWebhookEndpoint returns an endpoint that invokes RedeliverWebhook on the service.
+func MakeRedeliverWebhookEndpoint(s service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RedeliverWebhookRequest)
+		d, err := s.RedeliverWebhook(ctx, req.DeliveryID)
+		return RedeliverWebhookResponse{
+			Delivery: d,
+			Err:      err,
+		}, nil
+	}
+}
+
+// Failed implements Failer.
+func (r RedeliverWebhookResponse) Failed() error {
+	return r.Err
+}
+
+// CreateWebhook implements Service.
+func (e Endpoints) CreateWebhook(ctx context.Context, spec service.WebhookSpec) (*service.Webhook, error) {
+	request := CreateWebhookRequest{specWebhookRequest(spec)}
+	response, err := e.CreateWebhookEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(CreateWebhookResponse).Webhook, response.(CreateWebhookResponse).Err
+}
+
+// GetWebhook implements Service.
+func (e Endpoints) GetWebhook(ctx context.Context, id int64) (*service.Webhook, error) {
+	request := GetWebhookRequest{WebhookID: id}
+	response, err := e.GetWebhookEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetWebhookResponse).Webhook, response.(GetWebhookResponse).Err
+}
+
+// GetWebhooks implements Service.
+func (e Endpoints) GetWebhooks(ctx context.Context) ([]*service.Webhook, error) {
+	request := GetWebhooksRequest{}
+	response, err := e.GetWebhooksEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetWebhooksResponse).Webhooks, response.(GetWebhooksResponse).Err
+}
+
+// UpdateWebhook implements Service.
+func (e Endpoints) UpdateWebhook(ctx context.Context, id int64, spec service.WebhookSpec) (*service.Webhook, error) {
+	request := UpdateWebhookRequest{WebhookID: id, WebhookSpecRequest: specWebhookRequest(spec)}
+	response, err := e.UpdateWebhookEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(UpdateWebhookResponse).Webhook, response.(UpdateWebhookResponse).Err
+}
+
+// DeleteWebhook implements Service.
+func (e Endpoints) DeleteWebhook(ctx context.Context, id int64) error {
+	request := DeleteWebhookRequest{WebhookID: id}
+	response, err := e.DeleteWebhookEndpoint(ctx, request)
+	if err != nil {
+		return err
+	}
+	return response.(DeleteWebhookResponse).Err
+}
+
+// GetWebhookDeliveries implements Service.
+func (e Endpoints) GetWebhookDeliveries(ctx context.Context, id int64) ([]*service.WebhookDelivery, error) {
+	request := GetWebhookDeliveriesRequest{WebhookID: id}
+	response, err := e.GetWebhookDeliveriesEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(GetWebhookDeliveriesResponse).Deliveries, response.(GetWebhookDeliveriesResponse).Err
+}
+
+// RedeliverWebhook implements Service.
+func (e Endpoints) RedeliverWebhook(ctx context.Context, deliveryID int64) (*service.WebhookDelivery, error) {
+	request := RedeliverWebhookRequest{DeliveryID: deliveryID}
+	response, err := e.RedeliverWebhookEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.(RedeliverWebhookResponse).Delivery, response.(RedeliverWebhookResponse).Err
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+	"github.com/deterok/go_test_task/payments/pkg/service"
 )
 
 // ─── GET ACCOUNT ─────────────────────────────────────────────────────────────────
@@ -32,10 +33,10 @@ func decodeGetAccountRequest(_ context.Context, r *http.Request) (interface{}, e
 	}
 
 	if err != nil {
-		return req, errors.Wrap(err, "order reciept failed")
+		return req, decodeError(errors.Wrap(err, "order reciept failed"))
 	}
 
-	return req, err
+	return req, decodeError(err)
 }
 
 func encodeGetAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
@@ -56,7 +57,22 @@ func makeGetAccountsHandler(m *mux.Router, endpoints endpoint.Endpoints, options
 }
 
 func decodeGetAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	return endpoint.GetAccountsRequest{}, nil
+	q := newQuery(r.URL.Query())
+	req := endpoint.GetAccountsRequest{
+		Currency:    q.String("currency"),
+		NamePrefix:  q.String("name_prefix"),
+		MinAmount:   q.Decimal("min_amount"),
+		MaxAmount:   q.Decimal("max_amount"),
+		CreatedFrom: q.Time("created_from"),
+		CreatedTo:   q.Time("created_to"),
+		Deleted:     service.DeletedStatus(q.String("deleted")),
+		WithTotal:   q.Bool("with_total"),
+		Order:       service.SortOrder(q.String("order")),
+		Cursor:      q.String("cursor"),
+		Limit:       q.Int("limit"),
+	}
+
+	return req, decodeError(q.err)
 }
 
 func encodeGetAccountsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
@@ -85,10 +101,27 @@ func decodeGetAccountOperationsRequest(_ context.Context, r *http.Request) (inte
 	}
 
 	if err != nil {
-		return req, errors.Wrap(err, "order getting failed")
+		return req, decodeError(errors.Wrap(err, "order getting failed"))
 	}
 
-	return req, err
+	q := newQuery(r.URL.Query())
+	for _, v := range q.List("type") {
+		t, err := strconv.Atoi(v)
+		if err != nil {
+			return req, decodeError(errors.Wrap(err, "operation type parsing failed"))
+		}
+		req.Types = append(req.Types, service.OperationType(t))
+	}
+	req.CreatedFrom = q.Time("created_from")
+	req.CreatedTo = q.Time("created_to")
+	req.Counterparty = q.Int64("counterparty")
+	req.MinAmount = q.Decimal("min_amount")
+	req.MaxAmount = q.Decimal("max_amount")
+	req.Order = service.SortOrder(q.String("order"))
+	req.Cursor = q.String("cursor")
+	req.Limit = q.Int("limit")
+
+	return req, decodeError(q.err)
 }
 
 func encodeGetAccountOperationsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
@@ -111,7 +144,7 @@ func makeCreateAccountHandler(m *mux.Router, endpoints endpoint.Endpoints, optio
 func decodeCreateAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	req := endpoint.CreateAccountRequest{}
 	err := json.NewDecoder(r.Body).Decode(&req)
-	return req, err
+	return req, decodeError(err)
 }
 
 func encodeCreateAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
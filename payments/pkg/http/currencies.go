@@ -0,0 +1,33 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+)
+
+// ─── GET CURRENCIES ─────────────────────────────────────────────────────────────
+
+func makeGetCurrenciesHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetCurrenciesEndpoint, decodeGetCurrenciesRequest, encodeGetCurrenciesResponse, options...)
+	m.Methods("GET").Path("/currencies").Handler(handler)
+}
+
+func decodeGetCurrenciesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoint.GetCurrenciesRequest{}, nil
+}
+
+func encodeGetCurrenciesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -0,0 +1,44 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+func TestGetCurrencies(t *testing.T) {
+	s := &stubService{
+		getCurrencies: func(ctx context.Context) ([]*service.Currency, error) {
+			return []*service.Currency{
+				{Code: "JPY", Name: "Japanese yen", Precision: 0},
+				{Code: "USD", Name: "US dollar", Precision: 2},
+			}, nil
+		},
+	}
+
+	rec := serve(s, "GetCurrencies", "GET", "/currencies")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"currencies": [
+		{"code": "JPY", "name": "Japanese yen", "precision": 0},
+		{"code": "USD", "name": "US dollar", "precision": 2}
+	]}`, rec.Body.String())
+}
+
+func TestGetCurrencies_error(t *testing.T) {
+	s := &stubService{
+		getCurrencies: func(ctx context.Context) ([]*service.Currency, error) {
+			return nil, errors.New("registry is broken")
+		},
+	}
+
+	rec := serve(s, "GetCurrencies", "GET", "/currencies")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"internal_error"`)
+	assert.NotContains(t, rec.Body.String(), "registry is broken")
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+// ─── QUOTE FEE ──────────────────────────────────────────────────────────────────
+
+func makeQuoteFeeHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.QuoteFeeEndpoint, decodeQuoteFeeRequest, encodeQuoteFeeResponse, options...)
+	m.Methods("GET").Path("/fees/quote").Handler(handler)
+}
+
+func decodeQuoteFeeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := endpoint.QuoteFeeRequest{
+		Currency: q.Get("currency"),
+	}
+
+	t, err := strconv.Atoi(q.Get("type"))
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "operation type parsing failed"))
+	}
+	req.Type = service.OperationType(t)
+
+	amount, err := decimal.NewFromString(q.Get("amount"))
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "amount parsing failed"))
+	}
+	req.Amount = amount
+
+	return req, nil
+}
+
+func encodeQuoteFeeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -0,0 +1,93 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+func TestQuoteFee(t *testing.T) {
+	type call struct {
+		t        service.OperationType
+		currency string
+		amount   string
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		err        error
+		wantCall   *call
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "quote",
+			query:      fmt.Sprintf("type=%d&currency=usd&amount=150.5", service.OperationTypeTransfer),
+			wantCall:   &call{service.OperationTypeTransfer, "usd", "150.5"},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"fee": "1.5"}`,
+		},
+		{
+			name:       "service error",
+			query:      fmt.Sprintf("type=%d&currency=xxx&amount=10", service.OperationTypeDeposit),
+			err:        service.ErrUnknownCurrency,
+			wantCall:   &call{service.OperationTypeDeposit, "xxx", "10"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"code":"unknown_currency"`,
+		},
+		{
+			name:       "missing type",
+			query:      "currency=usd&amount=10",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"code":"malformed_request"`,
+		},
+		{
+			name:       "malformed type",
+			query:      "type=transfer&currency=usd&amount=10",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"code":"malformed_request"`,
+		},
+		{
+			name:       "missing amount",
+			query:      fmt.Sprintf("type=%d&currency=usd", service.OperationTypeTransfer),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"code":"malformed_request"`,
+		},
+		{
+			name:       "malformed amount",
+			query:      fmt.Sprintf("type=%d&currency=usd&amount=ten", service.OperationTypeTransfer),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"code":"malformed_request"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *call
+			s := &stubService{
+				quoteFee: func(ctx context.Context, typ service.OperationType, currency string, amount decimal.Decimal) (decimal.Decimal, error) {
+					got = &call{typ, currency, amount.String()}
+					if tt.err != nil {
+						return decimal.Zero, tt.err
+					}
+					return decimal.RequireFromString("1.5"), nil
+				},
+			}
+
+			rec := serve(s, "QuoteFee", "GET", "/fees/quote?"+tt.query)
+			assert.Equal(t, tt.wantCall, got)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			} else {
+				assert.Contains(t, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
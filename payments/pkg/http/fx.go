@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+)
+
+// ─── QUOTE EXCHANGE ─────────────────────────────────────────────────────────────
+
+func makeQuoteExchangeHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.QuoteExchangeEndpoint, decodeQuoteExchangeRequest, encodeQuoteExchangeResponse, options...)
+	m.Methods("POST").Path("/fx/quotes").Handler(handler)
+}
+
+func decodeQuoteExchangeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.QuoteExchangeRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, decodeError(err)
+}
+
+func encodeQuoteExchangeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── MAKE EXCHANGE TRANSFER ─────────────────────────────────────────────────────
+
+func makeMakeExchangeTransferHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.MakeExchangeTransferEndpoint, decodeMakeExchangeTransferRequest, encodeMakeExchangeTransferResponse, options...)
+	m.Methods("POST").Path("/operations/exchange").Handler(handler)
+}
+
+func decodeMakeExchangeTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.MakeExchangeTransferRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, decodeError(err)
+}
+
+func encodeMakeExchangeTransferResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -3,13 +3,13 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 
 	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+	"github.com/deterok/go_test_task/payments/pkg/service"
 )
 
 // NewHTTPHandler returns a handler that makes a set of endpoints available on
@@ -22,25 +22,116 @@ func NewHTTPHandler(endpoints endpoint.Endpoints, options map[string][]kithttp.S
 	makeGetAccountOperationsHandler(m, endpoints, options["GetAccountOperations"])
 	makeMakeDepositHandler(m, endpoints, options["MakeDeposit"])
 	makeMakeTransferHandler(m, endpoints, options["MakeTransfer"])
+	makeMakeBatchTransferHandler(m, endpoints, options["MakeBatchTransfer"])
+	makeMakeOperationHandler(m, endpoints, options["MakeOperation"])
+	makeMakeWithdrawalHandler(m, endpoints, options["MakeWithdrawal"])
+	makeReverseOperationHandler(m, endpoints, options["ReverseOperation"])
+	makeAuthorizeHoldHandler(m, endpoints, options["AuthorizeHold"])
+	makeCaptureHoldHandler(m, endpoints, options["CaptureHold"])
+	makeVoidHoldHandler(m, endpoints, options["VoidHold"])
+	makeGetCurrenciesHandler(m, endpoints, options["GetCurrencies"])
+	makeQuoteExchangeHandler(m, endpoints, options["QuoteExchange"])
+	makeMakeExchangeTransferHandler(m, endpoints, options["MakeExchangeTransfer"])
+	makeQuoteFeeHandler(m, endpoints, options["QuoteFee"])
+	makeGetSystemAccountsHandler(m, endpoints, options["GetSystemAccounts"])
+	makeFreezeAccountHandler(m, endpoints, options["FreezeAccount"])
+	makeUnfreezeAccountHandler(m, endpoints, options["UnfreezeAccount"])
+	makeCloseAccountHandler(m, endpoints, options["CloseAccount"])
+	makeGetAccountStatusHistoryHandler(m, endpoints, options["GetAccountStatusHistory"])
+	makeCreateScheduleHandler(m, endpoints, options["CreateSchedule"])
+	makeGetScheduleHandler(m, endpoints, options["GetSchedule"])
+	makeGetAccountSchedulesHandler(m, endpoints, options["GetAccountSchedules"])
+	makeUpdateScheduleHandler(m, endpoints, options["UpdateSchedule"])
+	makeCancelScheduleHandler(m, endpoints, options["CancelSchedule"])
+	makeGetScheduleAttemptsHandler(m, endpoints, options["GetScheduleAttempts"])
+	makeCreateWebhookHandler(m, endpoints, options["CreateWebhook"])
+	makeGetWebhookHandler(m, endpoints, options["GetWebhook"])
+	makeGetWebhooksHandler(m, endpoints, options["GetWebhooks"])
+	makeUpdateWebhookHandler(m, endpoints, options["UpdateWebhook"])
+	makeDeleteWebhookHandler(m, endpoints, options["DeleteWebhook"])
+	makeGetWebhookDeliveriesHandler(m, endpoints, options["GetWebhookDeliveries"])
+	makeRedeliverWebhookHandler(m, endpoints, options["RedeliverWebhook"])
 	return m
 }
 
+// ErrorEncoder writes the error as an RFC 7807 problem document.
+// Messages of internal errors aren't written, they are left to the error logger of the server.
 func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
-	w.WriteHeader(err2code(err))
-	json.NewEncoder(w).Encode(errorWrapper{Error: err.Error()})
+	code := err2code(err)
+	detail := err.Error()
+	if service.KindOf(err) == service.ErrorKindInternal {
+		detail = internalErrorDetail
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(problem{
+		Type:   "urn:payments:error:" + service.CodeOf(err),
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: detail,
+		Code:   service.CodeOf(err),
+		Fields: service.FieldsOf(err),
+	})
 }
+
+// ErrorDecoder restores a domain error from a problem document
 func ErrorDecoder(r *http.Response) error {
-	var w errorWrapper
-	if err := json.NewDecoder(r.Body).Decode(&w); err != nil {
+	var p problem
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 		return err
 	}
-	return errors.New(w.Error)
+	err := service.NewError(code2kind(p.Status), p.Code, p.Detail)
+	err.Fields = p.Fields
+	return err
 }
 
+// internalErrorDetail is the detail of all internal errors
+const internalErrorDetail = "internal error"
+
 func err2code(err error) int {
+	switch service.KindOf(err) {
+	case service.ErrorKindNotFound:
+		return http.StatusNotFound
+	case service.ErrorKindValidation:
+		return http.StatusBadRequest
+	case service.ErrorKindConflict:
+		return http.StatusConflict
+	case service.ErrorKindInsufficientFunds:
+		return http.StatusUnprocessableEntity
+	}
 	return http.StatusInternalServerError
 }
 
-type errorWrapper struct {
-	Error string `json:"error"`
+func code2kind(code int) service.ErrorKind {
+	switch code {
+	case http.StatusNotFound:
+		return service.ErrorKindNotFound
+	case http.StatusBadRequest:
+		return service.ErrorKindValidation
+	case http.StatusConflict:
+		return service.ErrorKindConflict
+	case http.StatusUnprocessableEntity:
+		return service.ErrorKindInsufficientFunds
+	}
+	return service.ErrorKindInternal
+}
+
+// decodeError marks errors of request decoding as validation errors
+func decodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return service.NewError(service.ErrorKindValidation, "malformed_request", err.Error())
+}
+
+// problem is an RFC 7807 problem details object
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+	// Fields is an extension member with invalid fields of the request
+	Fields []service.FieldError `json:"fields,omitempty"`
 }
@@ -0,0 +1,147 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+	payhttp "github.com/deterok/go_test_task/payments/pkg/http"
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+// stubService implements the methods of the service which are set, others panic
+type stubService struct {
+	service.PaymentsService
+
+	getCurrencies     func(ctx context.Context) ([]*service.Currency, error)
+	quoteFee          func(ctx context.Context, t service.OperationType, currency string, amount decimal.Decimal) (decimal.Decimal, error)
+	getSystemAccounts func(ctx context.Context) ([]*service.Account, error)
+}
+
+func (s *stubService) GetCurrencies(ctx context.Context) ([]*service.Currency, error) {
+	return s.getCurrencies(ctx)
+}
+
+func (s *stubService) QuoteFee(ctx context.Context, t service.OperationType, currency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	return s.quoteFee(ctx, t, currency, amount)
+}
+
+func (s *stubService) GetSystemAccounts(ctx context.Context) ([]*service.Account, error) {
+	return s.getSystemAccounts(ctx)
+}
+
+// serve makes the request to the handler of the service endpoints. Errors of the route
+// are encoded by ErrorEncoder, like the service does.
+func serve(s service.PaymentsService, route, method, target string) *httptest.ResponseRecorder {
+	options := map[string][]kithttp.ServerOption{
+		route: {kithttp.ServerErrorEncoder(payhttp.ErrorEncoder)},
+	}
+	h := payhttp.NewHTTPHandler(endpoint.New(s, nil), options)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestErrorEncoder(t *testing.T) {
+	invalid := service.NewError(service.ErrorKindValidation, "invalid_request", "request is invalid")
+	invalid.Fields = []service.FieldError{{Field: "amount", Message: "must be positive"}}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+		wantFields []service.FieldError
+	}{
+		{
+			name:       "not found",
+			err:        service.ErrAccountNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "account_not_found",
+			wantDetail: "account not found",
+		},
+		{
+			name:       "validation",
+			err:        invalid,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+			wantDetail: "request is invalid",
+			wantFields: invalid.Fields,
+		},
+		{
+			name:       "conflict",
+			err:        service.ErrAccountFrozen,
+			wantStatus: http.StatusConflict,
+			wantCode:   "account_frozen",
+			wantDetail: "account is frozen",
+		},
+		{
+			name:       "insufficient funds",
+			err:        service.ErrBalanceTooLow,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "balance_too_low",
+			wantDetail: "balance too low",
+		},
+		{
+			name:       "wrapped",
+			err:        errors.Wrap(service.ErrAccountNotFound, "account (1) getting failed"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "account_not_found",
+			wantDetail: "account (1) getting failed: account not found",
+		},
+		{
+			name:       "internal",
+			err:        errors.Wrap(errors.New(`pq: duplicate key value violates unique constraint "uix_operations_idempotency_key"`), "operation createing failed"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: "internal error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			payhttp.ErrorEncoder(context.Background(), tt.err, rec)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/problem+json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+			var body struct {
+				Type   string               `json:"type"`
+				Title  string               `json:"title"`
+				Status int                  `json:"status"`
+				Detail string               `json:"detail"`
+				Code   string               `json:"code"`
+				Fields []service.FieldError `json:"fields"`
+			}
+			if !assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body)) {
+				t.FailNow()
+			}
+
+			assert.Equal(t, "urn:payments:error:"+tt.wantCode, body.Type)
+			assert.Equal(t, http.StatusText(tt.wantStatus), body.Title)
+			assert.Equal(t, tt.wantStatus, body.Status)
+			assert.Equal(t, tt.wantDetail, body.Detail)
+			assert.Equal(t, tt.wantCode, body.Code)
+			assert.Equal(t, tt.wantFields, body.Fields)
+		})
+	}
+}
+
+// Clients restore the domain error from the problem document
+func TestErrorDecoder(t *testing.T) {
+	rec := httptest.NewRecorder()
+	payhttp.ErrorEncoder(context.Background(), errors.Wrap(service.ErrBalanceTooLow, "transfer failed"), rec)
+
+	err := payhttp.ErrorDecoder(rec.Result())
+	assert.Equal(t, service.ErrorKindInsufficientFunds, service.KindOf(err))
+	assert.Equal(t, "balance_too_low", service.CodeOf(err))
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+)
+
+// ─── AUTHORIZE HOLD ─────────────────────────────────────────────────────────────
+
+func makeAuthorizeHoldHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.AuthorizeHoldEndpoint, decodeAuthorizeHoldRequest, encodeAuthorizeHoldResponse, options...)
+	m.Methods("POST").Path("/holds").Handler(handler)
+}
+
+func decodeAuthorizeHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.AuthorizeHoldRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, decodeError(err)
+}
+
+func encodeAuthorizeHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── CAPTURE HOLD ───────────────────────────────────────────────────────────────
+
+func makeCaptureHoldHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.CaptureHoldEndpoint, decodeCaptureHoldRequest, encodeCaptureHoldResponse, options...)
+	m.Methods("POST").Path("/holds/{id}/capture").Handler(handler)
+}
+
+func decodeCaptureHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.CaptureHoldRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "hold id parsing failed"))
+	}
+	req.HoldID = id
+
+	return req, nil
+}
+
+func encodeCaptureHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── VOID HOLD ──────────────────────────────────────────────────────────────────
+
+func makeVoidHoldHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.VoidHoldEndpoint, decodeVoidHoldRequest, encodeVoidHoldResponse, options...)
+	m.Methods("POST").Path("/holds/{id}/void").Handler(handler)
+}
+
+func decodeVoidHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.VoidHoldRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "hold id parsing failed"))
+	}
+	req.HoldID = id
+
+	return req, nil
+}
+
+func encodeVoidHoldResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -3,14 +3,20 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 
 	"github.com/deterok/go_test_task/payments/pkg/endpoint"
 )
 
+// IdempotencyKeyHeader is a header with a client key which allows to retry requests safely
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 // ─── MAKE Deposit ───────────────────────────────────────────────────────────────
 
 // makeMakeDepositHandler creates the handler logic
@@ -24,7 +30,8 @@ func makeMakeDepositHandler(m *mux.Router, endpoints endpoint.Endpoints, options
 func decodeMakeDepositRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	req := endpoint.MakeDepositRequest{}
 	err := json.NewDecoder(r.Body).Decode(&req)
-	return req, err
+	req.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+	return req, decodeError(err)
 }
 
 // encodeMakeDepositResponse is a transport/http.EncodeResponseFunc that encodes
@@ -49,7 +56,8 @@ func makeMakeTransferHandler(m *mux.Router, endpoints endpoint.Endpoints, option
 func decodeMakeTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	req := endpoint.MakeTransferRequest{}
 	err := json.NewDecoder(r.Body).Decode(&req)
-	return req, err
+	req.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+	return req, decodeError(err)
 }
 
 func encodeMakeTransferResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
@@ -61,3 +69,107 @@ func encodeMakeTransferResponse(ctx context.Context, w http.ResponseWriter, resp
 	err = json.NewEncoder(w).Encode(response)
 	return
 }
+
+// ─── MAKE BATCH TRANSFER ────────────────────────────────────────────────────────
+
+func makeMakeBatchTransferHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.MakeBatchTransferEndpoint, decodeMakeBatchTransferRequest, encodeMakeBatchTransferResponse, options...)
+	m.Methods("POST").Path("/operations/batch").Handler(handler)
+}
+
+func decodeMakeBatchTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.MakeBatchTransferRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	req.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+	return req, decodeError(err)
+}
+
+func encodeMakeBatchTransferResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── MAKE OPERATION ─────────────────────────────────────────────────────────────
+
+func makeMakeOperationHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.MakeOperationEndpoint, decodeMakeOperationRequest, encodeMakeOperationResponse, options...)
+	m.Methods("POST").Path("/operations").Handler(handler)
+}
+
+func decodeMakeOperationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.MakeOperationRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	req.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+	return req, decodeError(err)
+}
+
+func encodeMakeOperationResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── MAKE WITHDRAWAL ────────────────────────────────────────────────────────────
+
+func makeMakeWithdrawalHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.MakeWithdrawalEndpoint, decodeMakeWithdrawalRequest, encodeMakeWithdrawalResponse, options...)
+	m.Methods("POST").Path("/operations/withdrawal").Handler(handler)
+}
+
+func decodeMakeWithdrawalRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.MakeWithdrawalRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, decodeError(err)
+}
+
+func encodeMakeWithdrawalResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── REVERSE OPERATION ──────────────────────────────────────────────────────────
+
+func makeReverseOperationHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.ReverseOperationEndpoint, decodeReverseOperationRequest, encodeReverseOperationResponse, options...)
+	m.Methods("POST").Path("/operations/{id}/reverse").Handler(handler)
+}
+
+func decodeReverseOperationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.ReverseOperationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, decodeError(err)
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "operation id parsing failed"))
+	}
+	req.OperationID = id
+
+	return req, nil
+}
+
+func encodeReverseOperationResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// query reads optional typed values from the URL query. The first parsing error is kept in err.
+type query struct {
+	values url.Values
+	err    error
+}
+
+func newQuery(r url.Values) *query {
+	return &query{values: r}
+}
+
+func (q *query) fail(name string, err error) {
+	if q.err == nil {
+		q.err = errors.Wrapf(err, "query parameter %q parsing failed", name)
+	}
+}
+
+func (q *query) String(name string) string {
+	return q.values.Get(name)
+}
+
+// List returns values of the repeated or comma-separated parameter
+func (q *query) List(name string) []string {
+	res := []string{}
+	for _, v := range q.values[name] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
+
+func (q *query) Int(name string) int {
+	v := q.values.Get(name)
+	if v == "" {
+		return 0
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		q.fail(name, err)
+	}
+	return i
+}
+
+func (q *query) Int64(name string) int64 {
+	v := q.values.Get(name)
+	if v == "" {
+		return 0
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		q.fail(name, err)
+	}
+	return i
+}
+
+func (q *query) Bool(name string) bool {
+	v := q.values.Get(name)
+	if v == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		q.fail(name, err)
+	}
+	return b
+}
+
+// Time parses the RFC 3339 time
+func (q *query) Time(name string) *time.Time {
+	v := q.values.Get(name)
+	if v == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		q.fail(name, err)
+		return nil
+	}
+	return &t
+}
+
+func (q *query) Decimal(name string) *decimal.Decimal {
+	v := q.values.Get(name)
+	if v == "" {
+		return nil
+	}
+
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		q.fail(name, err)
+		return nil
+	}
+	return &d
+}
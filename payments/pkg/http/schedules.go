@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+)
+
+// ─── CREATE SCHEDULE ────────────────────────────────────────────────────────────
+
+func makeCreateScheduleHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.CreateScheduleEndpoint, decodeCreateScheduleRequest, encodeCreateScheduleResponse, options...)
+	m.Methods("POST").Path("/schedules").Handler(handler)
+}
+
+func decodeCreateScheduleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.CreateScheduleRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, decodeError(err)
+}
+
+func encodeCreateScheduleResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET SCHEDULE ───────────────────────────────────────────────────────────────
+
+func makeGetScheduleHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetScheduleEndpoint, decodeGetScheduleRequest, encodeGetScheduleResponse, options...)
+	m.Methods("GET").Path("/schedules/{id}").Handler(handler)
+}
+
+func decodeGetScheduleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetScheduleRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "schedule id parsing failed"))
+	}
+	req.ScheduleID = id
+
+	return req, nil
+}
+
+func encodeGetScheduleResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET ACCOUNT SCHEDULES ──────────────────────────────────────────────────────
+
+func makeGetAccountSchedulesHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetAccountSchedulesEndpoint, decodeGetAccountSchedulesRequest, encodeGetAccountSchedulesResponse, options...)
+	m.Methods("GET").Path("/accounts/{id}/schedules").Handler(handler)
+}
+
+func decodeGetAccountSchedulesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetAccountSchedulesRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "account id parsing failed"))
+	}
+	req.AccountID = id
+
+	return req, nil
+}
+
+func encodeGetAccountSchedulesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── UPDATE SCHEDULE ────────────────────────────────────────────────────────────
+
+func makeUpdateScheduleHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.UpdateScheduleEndpoint, decodeUpdateScheduleRequest, encodeUpdateScheduleResponse, options...)
+	m.Methods("PUT").Path("/schedules/{id}").Handler(handler)
+}
+
+func decodeUpdateScheduleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.UpdateScheduleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "schedule id parsing failed"))
+	}
+	req.ScheduleID = id
+
+	return req, nil
+}
+
+func encodeUpdateScheduleResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── CANCEL SCHEDULE ────────────────────────────────────────────────────────────
+
+func makeCancelScheduleHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.CancelScheduleEndpoint, decodeCancelScheduleRequest, encodeCancelScheduleResponse, options...)
+	m.Methods("DELETE").Path("/schedules/{id}").Handler(handler)
+}
+
+func decodeCancelScheduleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.CancelScheduleRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "schedule id parsing failed"))
+	}
+	req.ScheduleID = id
+
+	return req, nil
+}
+
+func encodeCancelScheduleResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET SCHEDULE ATTEMPTS ──────────────────────────────────────────────────────
+
+func makeGetScheduleAttemptsHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetScheduleAttemptsEndpoint, decodeGetScheduleAttemptsRequest, encodeGetScheduleAttemptsResponse, options...)
+	m.Methods("GET").Path("/schedules/{id}/attempts").Handler(handler)
+}
+
+func decodeGetScheduleAttemptsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetScheduleAttemptsRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "schedule id parsing failed"))
+	}
+	req.ScheduleID = id
+
+	return req, nil
+}
+
+func encodeGetScheduleAttemptsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+)
+
+// ─── FREEZE ACCOUNT ─────────────────────────────────────────────────────────────
+
+func makeFreezeAccountHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.FreezeAccountEndpoint, decodeFreezeAccountRequest, encodeFreezeAccountResponse, options...)
+	m.Methods("POST").Path("/accounts/{id}/freeze").Handler(handler)
+}
+
+func decodeFreezeAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.FreezeAccountRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "account id parsing failed"))
+	}
+	req.AccountID = id
+
+	return req, nil
+}
+
+func encodeFreezeAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── UNFREEZE ACCOUNT ───────────────────────────────────────────────────────────
+
+func makeUnfreezeAccountHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.UnfreezeAccountEndpoint, decodeUnfreezeAccountRequest, encodeUnfreezeAccountResponse, options...)
+	m.Methods("POST").Path("/accounts/{id}/unfreeze").Handler(handler)
+}
+
+func decodeUnfreezeAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.UnfreezeAccountRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "account id parsing failed"))
+	}
+	req.AccountID = id
+
+	return req, nil
+}
+
+func encodeUnfreezeAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── CLOSE ACCOUNT ──────────────────────────────────────────────────────────────
+
+func makeCloseAccountHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.CloseAccountEndpoint, decodeCloseAccountRequest, encodeCloseAccountResponse, options...)
+	m.Methods("POST").Path("/accounts/{id}/close").Handler(handler)
+}
+
+func decodeCloseAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.CloseAccountRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "account id parsing failed"))
+	}
+	req.AccountID = id
+
+	return req, nil
+}
+
+func encodeCloseAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET ACCOUNT STATUS HISTORY ─────────────────────────────────────────────────
+
+func makeGetAccountStatusHistoryHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetAccountStatusHistoryEndpoint, decodeGetAccountStatusHistoryRequest, encodeGetAccountStatusHistoryResponse, options...)
+	m.Methods("GET").Path("/accounts/{id}/status-history").Handler(handler)
+}
+
+func decodeGetAccountStatusHistoryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetAccountStatusHistoryRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "account id parsing failed"))
+	}
+	req.AccountID = id
+
+	return req, nil
+}
+
+func encodeGetAccountStatusHistoryResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
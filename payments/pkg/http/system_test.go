@@ -0,0 +1,64 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+func TestGetSystemAccounts(t *testing.T) {
+	s := &stubService{
+		getSystemAccounts: func(ctx context.Context) ([]*service.Account, error) {
+			return []*service.Account{
+				{ID: 1, Name: "world", Currency: "USD", Role: service.AccountRoleWorld, Amount: decimal.RequireFromString("-10.5")},
+				{ID: 2, Name: "fees", Currency: "USD", Role: service.AccountRoleFees, Amount: decimal.RequireFromString("0.5")},
+			}, nil
+		},
+	}
+
+	rec := serve(s, "GetSystemAccounts", "GET", "/system-accounts")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var body struct {
+		Accounts []*service.Account `json:"accounts"`
+	}
+	if !assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body)) || !assert.Len(t, body.Accounts, 2) {
+		t.FailNow()
+	}
+
+	for i, want := range []struct {
+		id     int64
+		role   service.AccountRole
+		amount string
+	}{
+		{1, service.AccountRoleWorld, "-10.5"},
+		{2, service.AccountRoleFees, "0.5"},
+	} {
+		a := body.Accounts[i]
+		assert.Equal(t, want.id, a.ID)
+		assert.Equal(t, want.role, a.Role)
+		assert.Equal(t, "USD", a.Currency)
+		assert.True(t, a.Amount.Equal(decimal.RequireFromString(want.amount)), "Got: %s; Want: %s", a.Amount, want.amount)
+	}
+}
+
+func TestGetSystemAccounts_error(t *testing.T) {
+	s := &stubService{
+		getSystemAccounts: func(ctx context.Context) ([]*service.Account, error) {
+			return nil, errors.Wrap(errors.New(`pq: relation "accounts" does not exist`), "system accounts getting failed")
+		},
+	}
+
+	rec := serve(s, "GetSystemAccounts", "GET", "/system-accounts")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"internal_error"`)
+	assert.NotContains(t, rec.Body.String(), "relation")
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/deterok/go_test_task/payments/pkg/endpoint"
+)
+
+// ─── CREATE WEBHOOK ─────────────────────────────────────────────────────────────
+
+func makeCreateWebhookHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.CreateWebhookEndpoint, decodeCreateWebhookRequest, encodeCreateWebhookResponse, options...)
+	m.Methods("POST").Path("/webhooks").Handler(handler)
+}
+
+func decodeCreateWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.CreateWebhookRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, decodeError(err)
+}
+
+func encodeCreateWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET WEBHOOK ────────────────────────────────────────────────────────────────
+
+func makeGetWebhookHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetWebhookEndpoint, decodeGetWebhookRequest, encodeGetWebhookResponse, options...)
+	m.Methods("GET").Path("/webhooks/{id}").Handler(handler)
+}
+
+func decodeGetWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetWebhookRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "webhook id parsing failed"))
+	}
+	req.WebhookID = id
+
+	return req, nil
+}
+
+func encodeGetWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET WEBHOOKS ───────────────────────────────────────────────────────────────
+
+func makeGetWebhooksHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetWebhooksEndpoint, decodeGetWebhooksRequest, encodeGetWebhooksResponse, options...)
+	m.Methods("GET").Path("/webhooks").Handler(handler)
+}
+
+func decodeGetWebhooksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoint.GetWebhooksRequest{}, nil
+}
+
+func encodeGetWebhooksResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── UPDATE WEBHOOK ─────────────────────────────────────────────────────────────
+
+func makeUpdateWebhookHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.UpdateWebhookEndpoint, decodeUpdateWebhookRequest, encodeUpdateWebhookResponse, options...)
+	m.Methods("PUT").Path("/webhooks/{id}").Handler(handler)
+}
+
+func decodeUpdateWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.UpdateWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, decodeError(err)
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "webhook id parsing failed"))
+	}
+	req.WebhookID = id
+
+	return req, nil
+}
+
+func encodeUpdateWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── DELETE WEBHOOK ─────────────────────────────────────────────────────────────
+
+func makeDeleteWebhookHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.DeleteWebhookEndpoint, decodeDeleteWebhookRequest, encodeDeleteWebhookResponse, options...)
+	m.Methods("DELETE").Path("/webhooks/{id}").Handler(handler)
+}
+
+func decodeDeleteWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.DeleteWebhookRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "webhook id parsing failed"))
+	}
+	req.WebhookID = id
+
+	return req, nil
+}
+
+func encodeDeleteWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── GET WEBHOOK DELIVERIES ─────────────────────────────────────────────────────
+
+func makeGetWebhookDeliveriesHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.GetWebhookDeliveriesEndpoint, decodeGetWebhookDeliveriesRequest, encodeGetWebhookDeliveriesResponse, options...)
+	m.Methods("GET").Path("/webhooks/{id}/deliveries").Handler(handler)
+}
+
+func decodeGetWebhookDeliveriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetWebhookDeliveriesRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "webhook id parsing failed"))
+	}
+	req.WebhookID = id
+
+	return req, nil
+}
+
+func encodeGetWebhookDeliveriesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
+
+// ─── REDELIVER WEBHOOK ──────────────────────────────────────────────────────────
+
+func makeRedeliverWebhookHandler(m *mux.Router, endpoints endpoint.Endpoints, options []kithttp.ServerOption) {
+	handler := kithttp.NewServer(endpoints.RedeliverWebhookEndpoint, decodeRedeliverWebhookRequest, encodeRedeliverWebhookResponse, options...)
+	m.Methods("POST").Path("/webhooks/deliveries/{id}/redeliver").Handler(handler)
+}
+
+func decodeRedeliverWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.RedeliverWebhookRequest{}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return req, decodeError(errors.Wrap(err, "delivery id parsing failed"))
+	}
+	req.DeliveryID = id
+
+	return req, nil
+}
+
+func encodeRedeliverWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if f, ok := response.(endpoint.Failure); ok && f.Failed() != nil {
+		ErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(response)
+	return
+}
@@ -2,29 +2,133 @@ package service
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
-	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 )
 
-var ErrAccountNotFound = errors.Errorf("account not found")
+var (
+	ErrAccountNotFound  = NewError(ErrorKindNotFound, "account_not_found", "account not found")
+	ErrAccountFrozen    = NewError(ErrorKindConflict, "account_frozen", "account is frozen")
+	ErrAccountNotFrozen = NewError(ErrorKindConflict, "account_not_frozen", "account isn't frozen")
+	ErrAccountClosed    = NewError(ErrorKindConflict, "account_closed", "account is closed")
+	ErrAccountNotEmpty  = NewError(ErrorKindConflict, "account_not_empty", "account balance must be zero or swept")
+	ErrAccountHasHolds  = NewError(ErrorKindConflict, "account_has_holds", "account has active holds")
+	ErrSystemAccount    = NewError(ErrorKindValidation, "system_account", "status of system accounts can't be changed")
+)
+
+// AccountStatus is the lifecycle state of an account.
+// Frozen accounts can't send or receive money until they are unfrozen, closed accounts are final.
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+// AccountRole marks system accounts. User accounts have an empty role.
+// System accounts are referenced by the role and the currency, every currency has one account of each role.
+type AccountRole string
 
-const WorldAccountID = -1
+const (
+	AccountRoleUser     AccountRole = ""
+	AccountRoleWorld    AccountRole = "world"    // source and destination of money outside the system
+	AccountRoleFees     AccountRole = "fees"     // collected fees
+	AccountRoleTaxes    AccountRole = "taxes"    // collected taxes
+	AccountRoleSuspense AccountRole = "suspense" // money which can't be assigned to an account yet
+	AccountRoleTreasury AccountRole = "treasury" // liquidity of currency exchanges
+)
+
+// SystemAccountRoles lists roles of system accounts created for every currency
+var SystemAccountRoles = []AccountRole{
+	AccountRoleWorld,
+	AccountRoleFees,
+	AccountRoleTaxes,
+	AccountRoleSuspense,
+	AccountRoleTreasury,
+}
 
 // Account is a virtual user wallet that can store only one currency
 type Account struct {
 	ID       int64           `gorm:"primary_key" json:"id"`
-	Name     string          `json:"name"`
-	Currency string          `json:"currency"`
+	Name     string          `gorm:"index" json:"name"`
+	Currency string          `gorm:"index" json:"currency"`
 	Amount   decimal.Decimal `sql:"type:decimal(20,8);" json:"amount"`
+	Held     decimal.Decimal `sql:"type:decimal(20,8);" json:"held"`
+	Role     AccountRole     `gorm:"index" json:"role,omitempty"`
+	Status   AccountStatus   `gorm:"index;default:'active'" json:"status"`
+
+	// Fence is the fencing token of the lock the row was last written under
+	Fence int64 `gorm:"not null;default:0" json:"-"`
 
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `sql:"index" json:"deleted_at,omitempty"`
 }
 
+// Available returns the part of the amount which isn't reserved by holds
+func (a *Account) Available() decimal.Decimal {
+	return a.Amount.Sub(a.Held)
+}
+
+// IsSystem reports whether the account is a system account.
+// Balances of system accounts may be negative.
+func (a *Account) IsSystem() bool {
+	return a.Role != AccountRoleUser
+}
+
+// checkStatus returns the error of the account which can't take part in operations.
+// Frozen accounts may only be swept by the closure.
+func (a *Account) checkStatus(closure bool) error {
+	switch a.Status {
+	case AccountStatusClosed:
+		return ErrAccountClosed
+	case AccountStatusFrozen:
+		if !closure {
+			return ErrAccountFrozen
+		}
+	}
+
+	return nil
+}
+
+// DeletedStatus selects accounts by the soft deletion
+type DeletedStatus string
+
+const (
+	DeletedStatusActive  DeletedStatus = "active"
+	DeletedStatusDeleted DeletedStatus = "deleted"
+	DeletedStatusAll     DeletedStatus = "all"
+)
+
+// AccountsFilter describes the requested part of the accounts list.
+// Empty fields don't restrict the list, deleted accounts are excluded by default.
+type AccountsFilter struct {
+	Page
+
+	Currency    string
+	NamePrefix  string
+	MinAmount   *decimal.Decimal
+	MaxAmount   *decimal.Decimal
+	CreatedFrom *time.Time // inclusive
+	CreatedTo   *time.Time // exclusive
+	Deleted     DeletedStatus
+
+	// WithTotal requests the number of all accounts matching the filter
+	WithTotal bool
+}
+
+// AccountsPage is a part of the accounts list.
+// NextCursor is empty on the last page, Total is set only on request.
+type AccountsPage struct {
+	Accounts   []*Account `json:"accounts"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Total      *int64     `json:"total,omitempty"`
+}
+
 // AccountsRepository describes interaction with a repository that can saves and stores Accounts.
 type AccountsRepository interface {
 	Create(ctx context.Context, a *Account) (*Account, error)
@@ -33,6 +137,12 @@ type AccountsRepository interface {
 
 	Get(ctx context.Context, id int64) (*Account, error)
 	GetAll(ctx context.Context) ([]*Account, error)
+	// Find returns accounts matching the filter. Zero limit returns all accounts.
+	Find(ctx context.Context, f AccountsFilter) ([]*Account, error)
+	// Count returns the number of accounts matching the filter regardless of the page
+	Count(ctx context.Context, f AccountsFilter) (int64, error)
+	GetByRole(ctx context.Context, role AccountRole, currency string) (*Account, error)
+	GetSystem(ctx context.Context) ([]*Account, error)
 }
 
 // ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
@@ -53,9 +163,10 @@ func (r *accountsRepository) Create(ctx context.Context, a *Account) (*Account,
 	return a, nil
 }
 
+// Get returns the account even if it's closed, so operations with closed accounts fail with ErrAccountClosed
 func (r *accountsRepository) Get(ctx context.Context, id int64) (*Account, error) {
 	acc := Account{}
-	if err := r.db.Find(&acc, id).Error; err != nil {
+	if err := r.db.Unscoped().Find(&acc, id).Error; err != nil {
 		if gorm.IsRecordNotFoundError(err) {
 			return nil, ErrAccountNotFound
 		}
@@ -80,8 +191,115 @@ func (r *accountsRepository) GetAll(ctx context.Context) ([]*Account, error) {
 	return a, nil
 }
 
+func (r *accountsRepository) Find(ctx context.Context, f AccountsFilter) ([]*Account, error) {
+	a := []*Account{}
+
+	after, err := DecodeCursor(f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	req := r.filter(f)
+
+	cond, order := f.cursorCond()
+	if after != 0 {
+		req = req.Where(cond, after)
+	}
+	req = req.Order(order)
+
+	if f.Limit > 0 {
+		req = req.Limit(f.Limit)
+	}
+
+	if err := req.Find(&a).Error; err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *accountsRepository) Count(ctx context.Context, f AccountsFilter) (int64, error) {
+	var count int64
+	if err := r.filter(f).Model(&Account{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filter applies conditions of the filter except the page
+func (r *accountsRepository) filter(f AccountsFilter) *gorm.DB {
+	req := r.db
+
+	switch f.Deleted {
+	case DeletedStatusDeleted:
+		req = req.Unscoped().Where("deleted_at IS NOT NULL")
+	case DeletedStatusAll:
+		req = req.Unscoped()
+	}
+
+	if f.Currency != "" {
+		req = req.Where("currency = ?", NormalizeCurrency(f.Currency))
+	}
+	if f.NamePrefix != "" {
+		req = req.Where(`name LIKE ? ESCAPE '\'`, likeEscaper.Replace(f.NamePrefix)+"%")
+	}
+	if f.MinAmount != nil {
+		req = req.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		req = req.Where("amount <= ?", *f.MaxAmount)
+	}
+	if f.CreatedFrom != nil {
+		req = req.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		req = req.Where("created_at < ?", *f.CreatedTo)
+	}
+
+	return req
+}
+
+func (r *accountsRepository) GetByRole(ctx context.Context, role AccountRole, currency string) (*Account, error) {
+	acc := Account{}
+	if err := r.db.Where("role = ? AND currency = ?", role, currency).First(&acc).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrAccountNotFound
+		}
+
+		return nil, err
+	}
+
+	return &acc, nil
+}
+
+func (r *accountsRepository) GetSystem(ctx context.Context) ([]*Account, error) {
+	a := []*Account{}
+
+	if err := r.db.Where("role <> ?", AccountRoleUser).Order("currency, role").Find(&a).Error; err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Update writes the account. If the account is locked in the context, the row isn't written
+// when the holder of a newer lock has already written it.
 func (r *accountsRepository) Update(ctx context.Context, a *Account) (*Account, error) {
-	if err := r.db.Save(a).Error; err != nil {
+	if token, ok := fenceToken(ctx, a.ID); ok {
+		req := r.db.Unscoped().Model(&Account{}).Where("id = ? AND fence <= ?", a.ID, token).UpdateColumn("fence", token)
+		if err := req.Error; err != nil {
+			return nil, err
+		}
+		if req.RowsAffected == 0 {
+			return nil, ErrLockLost
+		}
+		a.Fence = token
+	}
+
+	if err := r.db.Unscoped().Save(a).Error; err != nil {
 		return nil, err
 	}
 
@@ -89,7 +307,7 @@ func (r *accountsRepository) Update(ctx context.Context, a *Account) (*Account,
 }
 
 func (r *accountsRepository) Delete(ctx context.Context, id int64) error {
-	if err := r.db.Delete(id).Error; err != nil {
+	if err := r.db.Delete(&Account{ID: id}).Error; err != nil {
 		return err
 	}
 
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// MaxBatchLegs is the biggest number of legs of a batch transfer
+const MaxBatchLegs = 1000
+
+var (
+	ErrEmptyBatch    = NewError(ErrorKindValidation, "empty_batch", "batch must contain legs")
+	ErrBatchTooLarge = NewError(ErrorKindValidation, "batch_too_large", "batch contains too many legs")
+)
+
+// TransferLeg is one transfer of a batch
+type TransferLeg struct {
+	From     int64           `json:"from"`
+	To       int64           `json:"to"`
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+}
@@ -0,0 +1,469 @@
+// Package conformance describes how storage backends of the payments service must behave.
+// A backend runs the suite from its tests:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(t *testing.T) service.UOWPaymentsFactory {
+//			return service.NewMemoryUOWPaymentsFactory()
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory returns a factory of units of work over a clean storage
+type Factory func(t *testing.T) service.UOWPaymentsFactory
+
+// Case is a check of the suite
+type Case struct {
+	Name string
+	Run  func(t *testing.T, uowf service.UOWPaymentsFactory)
+}
+
+// Cases lists all checks of the suite
+var Cases = []Case{
+	{Name: "accounts not found", Run: AccountsNotFound},
+	{Name: "accounts create and update", Run: AccountsCreateUpdate},
+	{Name: "accounts soft delete", Run: AccountsSoftDelete},
+	{Name: "operations not found", Run: OperationsNotFound},
+	{Name: "operations preload transactions", Run: OperationsPreload},
+	{Name: "operations by participants", Run: OperationsParticipants},
+	{Name: "operations idempotency key", Run: OperationsIdempotencyKey},
+	{Name: "rollback visibility", Run: Rollback},
+	{Name: "isolation", Run: Isolation},
+	{Name: "concurrent access", Run: Concurrency},
+}
+
+// Run checks the backend against the cases, all cases of the suite are checked by default.
+// Every case gets a clean storage.
+func Run(t *testing.T, newFactory Factory, cases ...Case) {
+	if len(cases) == 0 {
+		cases = Cases
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			c.Run(t, newFactory(t))
+		})
+	}
+}
+
+// Except returns the cases of the suite except the given ones
+func Except(names ...string) []Case {
+	skip := map[string]bool{}
+	for _, n := range names {
+		skip[n] = true
+	}
+
+	cases := []Case{}
+	for _, c := range Cases {
+		if !skip[c.Name] {
+			cases = append(cases, c)
+		}
+	}
+	return cases
+}
+
+// AccountsNotFound checks that missing accounts are reported with ErrAccountNotFound
+func AccountsNotFound(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		_, err := uow.Accounts().Get(ctx, 404)
+		assert.Equal(t, service.ErrAccountNotFound, errors.Cause(err), "Get")
+
+		_, err = uow.Accounts().GetByRole(ctx, service.AccountRoleWorld, "USD")
+		assert.Equal(t, service.ErrAccountNotFound, errors.Cause(err), "GetByRole")
+
+		as, err := uow.Accounts().GetAll(ctx)
+		assert.NoError(t, err, "GetAll")
+		assert.Empty(t, as, "GetAll")
+	})
+}
+
+// AccountsCreateUpdate checks that accounts get ids and defaults on creation and keep updates
+func AccountsCreateUpdate(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	var id int64
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a, err := uow.Accounts().Create(ctx, &service.Account{Name: "test1", Currency: "USD"})
+		mustNoError(t, err)
+		assert.NotZero(t, a.ID)
+		assert.Equal(t, service.AccountStatusActive, a.Status, "status must default to active")
+		assert.False(t, a.CreatedAt.IsZero(), "CreatedAt must be set")
+		id = a.ID
+	})
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a, err := uow.Accounts().Get(ctx, id)
+		mustNoError(t, err)
+		assert.Equal(t, "test1", a.Name)
+		assert.Equal(t, "USD", a.Currency)
+
+		a.Amount = decimal.RequireFromString("10.5")
+		_, err = uow.Accounts().Update(ctx, a)
+		mustNoError(t, err)
+	})
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a, err := uow.Accounts().Get(ctx, id)
+		mustNoError(t, err)
+		assert.True(t, a.Amount.Equal(decimal.RequireFromString("10.5")), "Got: %s", a.Amount)
+	})
+}
+
+// AccountsSoftDelete checks that deleted accounts are kept for Get and are hidden from lists
+func AccountsSoftDelete(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	var kept, deleted int64
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		kept = createAccount(t, uow, "kept")
+		deleted = createAccount(t, uow, "deleted")
+		mustNoError(t, uow.Accounts().Delete(ctx, deleted))
+	})
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a, err := uow.Accounts().Get(ctx, deleted)
+		if assert.NoError(t, err, "deleted accounts must be returned by Get") {
+			assert.NotNil(t, a.DeletedAt)
+		}
+
+		as, err := uow.Accounts().GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{kept}, accountIDs(as), "GetAll")
+
+		for status, want := range map[service.DeletedStatus][]int64{
+			"":                           {kept},
+			service.DeletedStatusActive:  {kept},
+			service.DeletedStatusDeleted: {deleted},
+			service.DeletedStatusAll:     {kept, deleted},
+		} {
+			as, err := uow.Accounts().Find(ctx, service.AccountsFilter{Deleted: status})
+			assert.NoError(t, err)
+			assert.Equal(t, want, accountIDs(as), "Find %q", status)
+
+			n, err := uow.Accounts().Count(ctx, service.AccountsFilter{Deleted: status})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(want)), n, "Count %q", status)
+		}
+	})
+}
+
+// OperationsNotFound checks that missing operations are reported with ErrOperationNotFound
+func OperationsNotFound(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		_, err := uow.Operations().Get(ctx, 404)
+		assert.Equal(t, service.ErrOperationNotFound, errors.Cause(err), "Get")
+
+		_, err = uow.Operations().GetReversal(ctx, 404)
+		assert.Equal(t, service.ErrOperationNotFound, errors.Cause(err), "GetReversal")
+
+		_, err = uow.Operations().GetByIdempotencyKey(ctx, "missing")
+		assert.Equal(t, service.ErrOperationNotFound, errors.Cause(err), "GetByIdempotencyKey")
+
+		os, err := uow.Operations().GetByAccID(ctx, 404, service.OperationsFilter{})
+		assert.NoError(t, err, "GetByAccID")
+		assert.Empty(t, os, "GetByAccID")
+	})
+}
+
+// OperationsIdempotencyKey checks that operations with a taken idempotency key are rejected with ErrDuplicateIdempotencyKey
+func OperationsIdempotencyKey(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+	key := "key"
+
+	var a int64
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a = createAccount(t, uow, "test1")
+		o := &service.Operation{Participants: []int64{a}, Type: service.OperationTypeDeposit, IdempotencyKey: &key}
+		_, err := uow.Operations().Create(ctx, o)
+		mustNoError(t, err)
+	})
+
+	uow, err := uowf.Make()
+	mustNoError(t, err)
+	defer uow.Revert()
+
+	o := &service.Operation{Participants: []int64{a}, Type: service.OperationTypeDeposit, IdempotencyKey: &key}
+	_, err = uow.Operations().Create(ctx, o)
+	assert.Equal(t, service.ErrDuplicateIdempotencyKey, errors.Cause(err))
+}
+
+// OperationsPreload checks that operations are returned with their transactions
+func OperationsPreload(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	var (
+		o      *service.Operation
+		a1, a2 int64
+	)
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a1 = createAccount(t, uow, "test1")
+		a2 = createAccount(t, uow, "test2")
+		o = createOperation(t, uow, []int64{a1, a2}, transfer(a1, a2, "10"), transfer(a2, a1, "3"))
+
+		assert.NotZero(t, o.ID)
+		for _, tr := range o.Transactions {
+			assert.NotZero(t, tr.ID, "transactions must be created with the operation")
+		}
+	})
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		got, err := uow.Operations().Get(ctx, int64(o.ID))
+		mustNoError(t, err)
+		assertTransactions(t, o, got, "Get")
+
+		os, err := uow.Operations().GetByAccID(ctx, a2, service.OperationsFilter{})
+		mustNoError(t, err)
+		if assert.Len(t, os, 1, "GetByAccID") {
+			assertTransactions(t, o, os[0], "GetByAccID")
+		}
+	})
+}
+
+// OperationsParticipants checks that GetByAccID matches operations by participants in the order of creation
+func OperationsParticipants(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	var a, b, c int64
+	var o1, o2, o3 *service.Operation
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a = createAccount(t, uow, "a")
+		b = createAccount(t, uow, "b")
+		c = createAccount(t, uow, "c")
+
+		o1 = createOperation(t, uow, []int64{a, b}, transfer(a, b, "1"))
+		o2 = createOperation(t, uow, []int64{b, c}, transfer(b, c, "2"))
+		// Participants define the history even if transactions don't mention the account
+		o3 = createOperation(t, uow, []int64{c, a}, transfer(c, b, "3"))
+	})
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		for id, want := range map[int64][]uint{
+			a: {o1.ID, o3.ID},
+			b: {o1.ID, o2.ID},
+			c: {o2.ID, o3.ID},
+		} {
+			os, err := uow.Operations().GetByAccID(ctx, id, service.OperationsFilter{})
+			assert.NoError(t, err)
+			assert.Equal(t, want, operationIDs(os), "account %d", id)
+		}
+	})
+}
+
+// Rollback checks that saved changes are visible to other units of work and that reverted changes are discarded
+func Rollback(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	var id int64
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		id = createAccount(t, uow, "saved")
+	})
+
+	uow2, err := uowf.Make()
+	mustNoError(t, err)
+	a, err := uow2.Accounts().Get(ctx, id)
+	mustNoError(t, err, "saved changes must be visible")
+
+	a.Amount = decimal.RequireFromString("10")
+	_, err = uow2.Accounts().Update(ctx, a)
+	mustNoError(t, err)
+	reverted := createAccount(t, uow2, "reverted")
+	o := createOperation(t, uow2, []int64{id}, transfer(reverted, id, "10"))
+	mustNoError(t, uow2.Revert())
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		a, err := uow.Accounts().Get(ctx, id)
+		if assert.NoError(t, err) {
+			assert.True(t, a.Amount.IsZero(), "reverted update must be discarded, got: %s", a.Amount)
+		}
+
+		_, err = uow.Accounts().Get(ctx, reverted)
+		assert.Equal(t, service.ErrAccountNotFound, errors.Cause(err), "reverted account must be discarded")
+
+		_, err = uow.Operations().Get(ctx, int64(o.ID))
+		assert.Equal(t, service.ErrOperationNotFound, errors.Cause(err), "reverted operation must be discarded")
+
+		os, err := uow.Operations().GetByAccID(ctx, id, service.OperationsFilter{})
+		assert.NoError(t, err)
+		assert.Empty(t, os)
+	})
+}
+
+// Isolation checks that changes are invisible to other units of work until they are saved.
+// Backends which serialize units of work (sqlite) can't run the case, an open unit of work blocks others.
+func Isolation(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+
+	uow1, err := uowf.Make()
+	mustNoError(t, err)
+	id := createAccount(t, uow1, "unsaved")
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		_, err := uow.Accounts().Get(ctx, id)
+		assert.Equal(t, service.ErrAccountNotFound, errors.Cause(err), "unsaved changes must be invisible")
+
+		as, err := uow.Accounts().GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, as, "unsaved changes must be invisible")
+	})
+
+	mustNoError(t, uow1.Save())
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		_, err := uow.Accounts().Get(ctx, id)
+		assert.NoError(t, err, "saved changes must be visible")
+	})
+}
+
+// Concurrency checks that concurrent units of work don't lose or mix up their changes
+func Concurrency(t *testing.T, uowf service.UOWPaymentsFactory) {
+	ctx := context.Background()
+	const workers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- createHistory(ctx, uowf, fmt.Sprintf("worker%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	inUOW(t, uowf, func(uow service.UOWPayments) {
+		as, err := uow.Accounts().GetAll(ctx)
+		mustNoError(t, err)
+		assert.Len(t, as, workers)
+
+		ids := map[int64]bool{}
+		for _, a := range as {
+			ids[a.ID] = true
+
+			os, err := uow.Operations().GetByAccID(ctx, a.ID, service.OperationsFilter{})
+			assert.NoError(t, err)
+			if assert.Len(t, os, 1, "account %d", a.ID) {
+				assert.Equal(t, a.Name, *os[0].IdempotencyKey)
+			}
+		}
+		assert.Len(t, ids, workers, "ids must be unique")
+	})
+}
+
+// ─── HELPERS ────────────────────────────────────────────────────────────────────
+
+// mustNoError stops the case on the error, later checks make no sense without the data
+func mustNoError(t *testing.T, err error, msgAndArgs ...interface{}) {
+	if !assert.NoError(t, err, msgAndArgs...) {
+		t.FailNow()
+	}
+}
+
+// inUOW runs f in a unit of work and saves it
+func inUOW(t *testing.T, uowf service.UOWPaymentsFactory, f func(uow service.UOWPayments)) {
+	uow, err := uowf.Make()
+	mustNoError(t, err)
+
+	f(uow)
+	mustNoError(t, uow.Save())
+}
+
+// createHistory creates an account with a deposit in its own unit of work
+func createHistory(ctx context.Context, uowf service.UOWPaymentsFactory, name string) error {
+	uow, err := uowf.Make()
+	if err != nil {
+		return err
+	}
+
+	a, err := uow.Accounts().Create(ctx, &service.Account{Name: name, Currency: "USD"})
+	if err != nil {
+		uow.Revert()
+		return err
+	}
+
+	o := &service.Operation{
+		Participants:   []int64{a.ID},
+		Type:           service.OperationTypeDeposit,
+		IdempotencyKey: &name,
+		Transactions:   []service.Transaction{{To: a.ID, Currency: "USD", Amount: decimal.RequireFromString("1")}},
+	}
+	if _, err := uow.Operations().Create(ctx, o); err != nil {
+		uow.Revert()
+		return err
+	}
+
+	return uow.Save()
+}
+
+func createAccount(t *testing.T, uow service.UOWPayments, name string) int64 {
+	a, err := uow.Accounts().Create(context.Background(), &service.Account{Name: name, Currency: "USD"})
+	mustNoError(t, err)
+	return a.ID
+}
+
+func createOperation(t *testing.T, uow service.UOWPayments, participants []int64, ts ...service.Transaction) *service.Operation {
+	o, err := uow.Operations().Create(context.Background(), &service.Operation{
+		Participants: participants,
+		Type:         service.OperationTypeTransfer,
+		Transactions: ts,
+	})
+	mustNoError(t, err)
+	return o
+}
+
+func transfer(from, to int64, amount string) service.Transaction {
+	return service.Transaction{From: from, To: to, Currency: "USD", Amount: decimal.RequireFromString(amount)}
+}
+
+// assertTransactions compares transactions of operations regardless of their order
+func assertTransactions(t *testing.T, want, got *service.Operation, msg string) {
+	key := func(tr service.Transaction) string {
+		return fmt.Sprintf("%d:%d>%d:%s:%s", tr.OperationID, tr.From, tr.To, tr.Currency, tr.Amount.String())
+	}
+
+	wantKeys, gotKeys := []string{}, []string{}
+	for _, tr := range want.Transactions {
+		wantKeys = append(wantKeys, key(tr))
+	}
+	for _, tr := range got.Transactions {
+		gotKeys = append(gotKeys, key(tr))
+	}
+
+	assert.ElementsMatch(t, wantKeys, gotKeys, msg)
+}
+
+func accountIDs(as []*service.Account) []int64 {
+	ids := []int64{}
+	for _, a := range as {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func operationIDs(os []*service.Operation) []uint {
+	ids := []uint{}
+	for _, o := range os {
+		ids = append(ids, o.ID)
+	}
+	return ids
+}
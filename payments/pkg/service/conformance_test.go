@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/deterok/go_test_task/payments/pkg/service/conformance"
+	"github.com/deterok/go_test_task/payments/pkg/service/migrations"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func TestConformance_memory(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) service.UOWPaymentsFactory {
+		return service.NewMemoryUOWPaymentsFactory()
+	})
+}
+
+func TestConformance_sqlite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Every case gets its own database file. Units of work of sqlite are serialized,
+	// so it can't run the isolation case.
+	dbs := []*gorm.DB{}
+	defer func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}()
+
+	conformance.Run(t, func(t *testing.T) service.UOWPaymentsFactory {
+		path := filepath.Join(dir, fmt.Sprintf("%d.db", len(dbs)))
+
+		db, err := gorm.Open("sqlite3", "file:"+path+"?_txlock=immediate&_busy_timeout=10000&_journal_mode=WAL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dbs = append(dbs, db)
+
+		if _, err := migrations.Up(db); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.InitModels(db); err != nil {
+			t.Fatal(err)
+		}
+
+		return service.NewUOWPaymentsFactory(db)
+	}, conformance.Except("isolation")...)
+}
+
+func TestConformance_gorm(t *testing.T) {
+	if os.Getenv("PAYMENTS_TEST_STORAGE") != "postgres" {
+		t.Skip("set PAYMENTS_TEST_STORAGE=postgres to run the suite against postgresql")
+	}
+
+	db, err := gorm.Open("postgres", "host=postgres dbname=testdb sslmode=disable user=postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.InitModels(db); err != nil {
+		t.Fatal(err)
+	}
+
+	conformance.Run(t, func(t *testing.T) service.UOWPaymentsFactory {
+		// The suite expects a storage without system accounts
+		for _, table := range []string{"accounts", "operations", "transactions"} {
+			if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return service.NewUOWPaymentsFactory(db)
+	})
+}
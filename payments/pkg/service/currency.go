@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// MaxCurrencyPrecision is the biggest number of minor units supported by the amount columns
+const MaxCurrencyPrecision = 8
+
+var (
+	ErrUnknownCurrency   = NewError(ErrorKindValidation, "unknown_currency", "currency is unknown")
+	ErrAmountPrecision   = NewError(ErrorKindValidation, "amount_precision", "amount has more decimal places than the currency allows")
+	ErrAmountNotPositive = NewError(ErrorKindValidation, "amount_not_positive", "amount must be positive")
+)
+
+// Currency describes the currency supported by the system
+type Currency struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Precision int32  `json:"precision"`
+}
+
+// CheckAmount verifies that the amount fits the minor units of the currency
+func (c *Currency) CheckAmount(amount decimal.Decimal) error {
+	if !amount.Equal(amount.Truncate(c.Precision)) {
+		return errors.Wrapf(ErrAmountPrecision, "%s allows %d decimal places", c.Code, c.Precision)
+	}
+
+	return nil
+}
+
+// CurrencyRegistry describes the read-only set of currencies known to the system
+type CurrencyRegistry interface {
+	// Get returns the currency by the code. The code is normalized before lookup.
+	Get(code string) (*Currency, error)
+	// All returns all currencies ordered by code
+	All() []*Currency
+}
+
+// NormalizeCurrency returns the canonical form of the currency code
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+type currencyRegistry struct {
+	currencies map[string]*Currency
+	sorted     []*Currency
+}
+
+// NewCurrencyRegistry returns registry with the bundled ISO 4217 table extended by the given currencies.
+// Extra currencies override the bundled ones with the same code.
+func NewCurrencyRegistry(extra ...*Currency) CurrencyRegistry {
+	r := &currencyRegistry{
+		currencies: make(map[string]*Currency, len(iso4217)+len(extra)),
+	}
+
+	for _, c := range append(iso4217[:len(iso4217):len(iso4217)], extra...) {
+		cc := *c
+		cc.Code = NormalizeCurrency(cc.Code)
+		r.currencies[cc.Code] = &cc
+	}
+
+	r.sorted = make([]*Currency, 0, len(r.currencies))
+	for _, c := range r.currencies {
+		r.sorted = append(r.sorted, c)
+	}
+	sort.Slice(r.sorted, func(i, j int) bool {
+		return r.sorted[i].Code < r.sorted[j].Code
+	})
+
+	return r
+}
+
+func (r *currencyRegistry) Get(code string) (*Currency, error) {
+	c, ok := r.currencies[NormalizeCurrency(code)]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownCurrency, "currency (%s)", code)
+	}
+
+	return c, nil
+}
+
+func (r *currencyRegistry) All() []*Currency {
+	res := make([]*Currency, len(r.sorted))
+	copy(res, r.sorted)
+	return res
+}
+
+// LoadCurrencies reads the JSON list of additional currencies from the file
+func LoadCurrencies(path string) ([]*Currency, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "currencies file (%s) reading failed", path)
+	}
+
+	var res []*Currency
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, errors.Wrapf(err, "currencies file (%s) parsing failed", path)
+	}
+
+	for _, c := range res {
+		if NormalizeCurrency(c.Code) == "" {
+			return nil, errors.Errorf("currencies file (%s): empty currency code", path)
+		}
+		if c.Precision < 0 || c.Precision > MaxCurrencyPrecision {
+			return nil, errors.Errorf("currencies file (%s): %s precision must be in [0, %d]", path, c.Code, MaxCurrencyPrecision)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrorKind is a class of domain errors. Transports use it to choose a status code.
+type ErrorKind int
+
+const (
+	ErrorKindInternal ErrorKind = iota
+	ErrorKindNotFound
+	ErrorKindValidation
+	ErrorKindConflict
+	ErrorKindInsufficientFunds
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindInternal:
+		return "Internal"
+	case ErrorKindNotFound:
+		return "NotFound"
+	case ErrorKindValidation:
+		return "Validation"
+	case ErrorKindConflict:
+		return "Conflict"
+	case ErrorKindInsufficientFunds:
+		return "InsufficientFunds"
+	}
+	return ""
+}
+
+// Error is a domain error with a kind and a stable machine-readable code.
+// The kind and the code survive errors.Wrap (see KindOf and CodeOf).
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Fields describes invalid fields of a request (only for validation errors)
+	Fields []FieldError
+}
+
+// FieldError describes an invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewError creates a domain error
+func NewError(kind ErrorKind, code, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// FieldsOf returns field errors of the validation error wrapped by err
+func FieldsOf(err error) []FieldError {
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return e.Fields
+	}
+
+	return nil
+}
+
+// KindOf returns the kind of the domain error wrapped by err.
+// Errors which aren't domain errors are internal.
+func KindOf(err error) ErrorKind {
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return e.Kind
+	}
+
+	return ErrorKindInternal
+}
+
+// CodeOf returns the code of the domain error wrapped by err
+func CodeOf(err error) string {
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return e.Code
+	}
+
+	return "internal_error"
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.New(100, 0)
+
+// FeeTier overrides the fee of the rule for amounts starting from the threshold
+type FeeTier struct {
+	From    decimal.Decimal `json:"from"`
+	Flat    decimal.Decimal `json:"flat"`
+	Percent decimal.Decimal `json:"percent"`
+}
+
+// FeeRule describes the fee of operations of one type.
+// The fee is a flat part plus a percentage of the amount, limited by min and max.
+type FeeRule struct {
+	Type     OperationType   `json:"type"`
+	Currency string          `json:"currency"` // empty currency matches all currencies
+	Flat     decimal.Decimal `json:"flat"`
+	Percent  decimal.Decimal `json:"percent"`
+	Tiers    []FeeTier       `json:"tiers"`
+	Min      decimal.Decimal `json:"min"`
+	Max      decimal.Decimal `json:"max"` // zero max means no cap
+}
+
+// Fee calculates the fee of the amount
+func (r *FeeRule) Fee(amount decimal.Decimal) decimal.Decimal {
+	flat, percent := r.Flat, r.Percent
+	for _, t := range r.Tiers {
+		if amount.LessThan(t.From) {
+			break
+		}
+		flat, percent = t.Flat, t.Percent
+	}
+
+	fee := flat.Add(amount.Mul(percent).Div(hundred))
+	if fee.LessThan(r.Min) {
+		fee = r.Min
+	}
+	if r.Max.IsPositive() && fee.GreaterThan(r.Max) {
+		fee = r.Max
+	}
+
+	return fee
+}
+
+// FeeSchedule is a set of fee rules. Rules for the exact currency take precedence over rules for all currencies.
+type FeeSchedule struct {
+	rules []*FeeRule
+}
+
+// NewFeeSchedule returns schedule with the rules
+func NewFeeSchedule(rules ...*FeeRule) *FeeSchedule {
+	for _, r := range rules {
+		r.Currency = NormalizeCurrency(r.Currency)
+		sort.Slice(r.Tiers, func(i, j int) bool {
+			return r.Tiers[i].From.LessThan(r.Tiers[j].From)
+		})
+	}
+
+	return &FeeSchedule{rules: rules}
+}
+
+// Fee returns the fee of the operation rounded to the precision of the currency.
+// Operations without a matching rule are free.
+func (s *FeeSchedule) Fee(t OperationType, c *Currency, amount decimal.Decimal) decimal.Decimal {
+	if s == nil {
+		return decimal.Zero
+	}
+
+	var rule *FeeRule
+	for _, r := range s.rules {
+		if r.Type != t {
+			continue
+		}
+		if r.Currency == c.Code {
+			rule = r
+			break
+		}
+		if r.Currency == "" && rule == nil {
+			rule = r
+		}
+	}
+
+	if rule == nil {
+		return decimal.Zero
+	}
+
+	return rule.Fee(amount).Round(c.Precision)
+}
+
+// LoadFeeSchedule reads the JSON list of fee rules from the file
+func LoadFeeSchedule(path string) (*FeeSchedule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fees file (%s) reading failed", path)
+	}
+
+	var rules []*FeeRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, errors.Wrapf(err, "fees file (%s) parsing failed", path)
+	}
+
+	for _, r := range rules {
+		if r.Flat.IsNegative() || r.Percent.IsNegative() || r.Min.IsNegative() || r.Max.IsNegative() {
+			return nil, errors.Errorf("fees file (%s): %s fee of %s must not be negative", path, r.Type, r.Currency)
+		}
+		if r.Max.IsPositive() && r.Max.LessThan(r.Min) {
+			return nil, errors.Errorf("fees file (%s): %s fee of %s has max less than min", path, r.Type, r.Currency)
+		}
+	}
+
+	return NewFeeSchedule(rules...), nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultFXQuoteTTL is used when a quote is requested without explicit ttl
+const DefaultFXQuoteTTL = 30 * time.Second
+
+var (
+	ErrFXRateNotFound     = NewError(ErrorKindValidation, "fx_rate_not_found", "exchange rate isn't available")
+	ErrSameCurrencies     = NewError(ErrorKindValidation, "same_currencies", "currencies of the exchange must differ")
+	ErrExchangeTooSmall   = NewError(ErrorKindValidation, "exchange_amount_too_small", "exchanged amount rounds down to zero")
+	ErrFXQuoteNotFound    = NewError(ErrorKindNotFound, "fx_quote_not_found", "quote not found")
+	ErrFXQuoteExpired     = NewError(ErrorKindConflict, "fx_quote_expired", "quote expired")
+	ErrFXQuoteAlreadyUsed = NewError(ErrorKindConflict, "fx_quote_already_used", "quote already used")
+)
+
+// FXRateProvider describes the source of exchange rates.
+// The rate is the amount of the target currency for one unit of the source currency.
+type FXRateProvider interface {
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// ─── IN-MEMORY PROVIDER ─────────────────────────────────────────────────────────
+
+// MemoryFXRateProvider keeps exchange rates in memory. Rates can be changed at runtime.
+type MemoryFXRateProvider struct {
+	mu    sync.RWMutex
+	rates map[string]decimal.Decimal
+}
+
+// NewMemoryFXRateProvider returns provider without rates
+func NewMemoryFXRateProvider() *MemoryFXRateProvider {
+	return &MemoryFXRateProvider{
+		rates: map[string]decimal.Decimal{},
+	}
+}
+
+// Set sets the rate of the currency pair
+func (p *MemoryFXRateProvider) Set(from, to string, rate decimal.Decimal) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rates[fxPair(from, to)] = rate
+}
+
+func (p *MemoryFXRateProvider) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rate, ok := p.rates[fxPair(from, to)]
+	if !ok {
+		return decimal.Zero, errors.Wrapf(ErrFXRateNotFound, "pair (%s)", fxPair(from, to))
+	}
+
+	return rate, nil
+}
+
+func fxPair(from, to string) string {
+	return NormalizeCurrency(from) + "/" + NormalizeCurrency(to)
+}
+
+// ─── STATIC FILE PROVIDER ───────────────────────────────────────────────────────
+
+type fxRateRecord struct {
+	From string          `json:"from"`
+	To   string          `json:"to"`
+	Rate decimal.Decimal `json:"rate"`
+}
+
+// NewStaticFXRateProvider returns provider with rates read from the JSON file:
+//
+//	[{"from": "USD", "to": "EUR", "rate": "0.92"}]
+func NewStaticFXRateProvider(path string) (FXRateProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "rates file (%s) reading failed", path)
+	}
+
+	var records []fxRateRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrapf(err, "rates file (%s) parsing failed", path)
+	}
+
+	p := NewMemoryFXRateProvider()
+	for _, r := range records {
+		if !r.Rate.IsPositive() {
+			return nil, errors.Errorf("rates file (%s): %s rate must be positive", path, fxPair(r.From, r.To))
+		}
+		p.Set(r.From, r.To, r.Rate)
+	}
+
+	return p, nil
+}
+
+// ─── QUOTES ─────────────────────────────────────────────────────────────────────
+
+// FXQuote is an exchange rate locked for a limited time. A quote can be used by one exchange only.
+type FXQuote struct {
+	gorm.Model
+	From      string
+	To        string
+	Rate      decimal.Decimal `sql:"type:decimal(20,10);"`
+	ExpiresAt time.Time
+
+	// OperationID is the id of the exchange operation which used the quote
+	OperationID *uint
+}
+
+// FXQuotesRepository describes interaction with a repository that can saves and stores FXQuotes.
+type FXQuotesRepository interface {
+	Create(ctx context.Context, q *FXQuote) (*FXQuote, error)
+	Update(ctx context.Context, q *FXQuote) (*FXQuote, error)
+
+	Get(ctx context.Context, id int64) (*FXQuote, error)
+}
+
+type fxQuotesRepository struct {
+	db *gorm.DB
+}
+
+func NewFXQuotesRepository(db *gorm.DB) FXQuotesRepository {
+	return &fxQuotesRepository{db}
+}
+
+func (r *fxQuotesRepository) Create(ctx context.Context, q *FXQuote) (*FXQuote, error) {
+	if err := r.db.Create(q).Error; err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (r *fxQuotesRepository) Update(ctx context.Context, q *FXQuote) (*FXQuote, error) {
+	if err := r.db.Save(q).Error; err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (r *fxQuotesRepository) Get(ctx context.Context, id int64) (*FXQuote, error) {
+	q := FXQuote{}
+	if err := r.db.Find(&q, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrFXQuoteNotFound
+		}
+
+		return nil, err
+	}
+
+	return &q, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultHoldTTL is used when a hold is authorized without explicit ttl
+const DefaultHoldTTL = 24 * time.Hour
+
+type HoldStatus int
+
+const (
+	HoldStatusActive HoldStatus = iota
+	HoldStatusCaptured
+	HoldStatusVoided
+	HoldStatusExpired
+)
+
+func (s HoldStatus) String() string {
+	switch s {
+	case HoldStatusActive:
+		return "Active"
+	case HoldStatusCaptured:
+		return "Captured"
+	case HoldStatusVoided:
+		return "Voided"
+	case HoldStatusExpired:
+		return "Expired"
+	}
+	return ""
+}
+
+var (
+	ErrHoldNotFound       = NewError(ErrorKindNotFound, "hold_not_found", "hold not found")
+	ErrHoldNotActive      = NewError(ErrorKindConflict, "hold_not_active", "hold isn't active")
+	ErrHoldExpired        = NewError(ErrorKindConflict, "hold_expired", "hold expired")
+	ErrHoldAmountExceeded = NewError(ErrorKindValidation, "hold_amount_exceeded", "capture amount exceeds hold amount")
+)
+
+// Hold is a reservation of money on an account. Held money can't be spent
+// until the hold is captured, voided or expired.
+type Hold struct {
+	gorm.Model
+	AccountID int64 `gorm:"index"`
+	Currency  string
+	Amount    decimal.Decimal `sql:"type:decimal(20,8);"`
+	Captured  decimal.Decimal `sql:"type:decimal(20,8);"`
+	Status    HoldStatus      `gorm:"index"`
+	ExpiresAt time.Time       `gorm:"index"`
+
+	// OperationID is the id of the operation created by the capture
+	OperationID *uint
+}
+
+// HoldsRepository describes interaction with a repository that can saves and stores Holds.
+type HoldsRepository interface {
+	Create(ctx context.Context, h *Hold) (*Hold, error)
+	Update(ctx context.Context, h *Hold) (*Hold, error)
+
+	Get(ctx context.Context, id int64) (*Hold, error)
+	GetExpired(ctx context.Context, now time.Time) ([]*Hold, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type holdsRepository struct {
+	db *gorm.DB
+}
+
+func NewHoldsRepository(db *gorm.DB) HoldsRepository {
+	return &holdsRepository{db}
+}
+
+func (r *holdsRepository) Create(ctx context.Context, h *Hold) (*Hold, error) {
+	if err := r.db.Create(h).Error; err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (r *holdsRepository) Update(ctx context.Context, h *Hold) (*Hold, error) {
+	if err := r.db.Save(h).Error; err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (r *holdsRepository) Get(ctx context.Context, id int64) (*Hold, error) {
+	h := Hold{}
+	if err := r.db.Find(&h, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrHoldNotFound
+		}
+
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+func (r *holdsRepository) GetExpired(ctx context.Context, now time.Time) ([]*Hold, error) {
+	hs := []*Hold{}
+
+	req := r.db.Where("status = ? AND expires_at <= ?", HoldStatusActive, now)
+	if err := req.Find(&hs).Error; err != nil {
+		return nil, err
+	}
+
+	return hs, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrIdempotencyKeyConflict = NewError(ErrorKindConflict, "idempotency_key_conflict", "idempotency key is already used by another request")
+
+type idempotencyKeyCtxKey struct{}
+
+// WithIdempotencyKey returns a copy of the context that carries the idempotency key of a request.
+// Operations created with the same key are created only once.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key stored in the context
+func IdempotencyKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	key, _ := ctx.Value(idempotencyKeyCtxKey{}).(string)
+	return key
+}
+
+// requestHash returns a fingerprint of the operation request which is used
+// to recognize different requests sent with the same idempotency key
+func requestHash(t OperationType, from, to int64, currency string, amount decimal.Decimal) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%d:%d:%d:%s:%s", t, from, to, currency, amount.String())))
+	return hex.EncodeToString(h[:])
+}
+
+// batchRequestHash returns a fingerprint of the batch transfer request, the order of legs matters
+func batchRequestHash(legs []TransferLeg) string {
+	h := sha256.New()
+	for _, l := range legs {
+		fmt.Fprintf(h, "%d:%d:%d:%s:%s;", OperationTypeBatch, l.From, l.To, l.Currency, l.Amount.String())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// legsRequestHash returns a fingerprint of the multi-leg operation request, the order of legs matters
+func legsRequestHash(legs []OperationLeg) string {
+	h := sha256.New()
+	for _, l := range legs {
+		fmt.Fprintf(h, "%d:%d:%s:%s;", OperationTypeMultiLeg, l.AccountID, l.Currency, l.Amount.String())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
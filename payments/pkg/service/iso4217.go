@@ -0,0 +1,161 @@
+package service
+
+// iso4217 is the bundled table of ISO 4217 currencies with their minor units
+var iso4217 = []*Currency{
+	{Code: "AED", Precision: 2, Name: "UAE Dirham"},
+	{Code: "AFN", Precision: 2, Name: "Afghani"},
+	{Code: "ALL", Precision: 2, Name: "Lek"},
+	{Code: "AMD", Precision: 2, Name: "Armenian Dram"},
+	{Code: "ANG", Precision: 2, Name: "Netherlands Antillean Guilder"},
+	{Code: "AOA", Precision: 2, Name: "Kwanza"},
+	{Code: "ARS", Precision: 2, Name: "Argentine Peso"},
+	{Code: "AUD", Precision: 2, Name: "Australian Dollar"},
+	{Code: "AWG", Precision: 2, Name: "Aruban Florin"},
+	{Code: "AZN", Precision: 2, Name: "Azerbaijan Manat"},
+	{Code: "BAM", Precision: 2, Name: "Convertible Mark"},
+	{Code: "BBD", Precision: 2, Name: "Barbados Dollar"},
+	{Code: "BDT", Precision: 2, Name: "Taka"},
+	{Code: "BGN", Precision: 2, Name: "Bulgarian Lev"},
+	{Code: "BHD", Precision: 3, Name: "Bahraini Dinar"},
+	{Code: "BIF", Precision: 0, Name: "Burundi Franc"},
+	{Code: "BMD", Precision: 2, Name: "Bermudian Dollar"},
+	{Code: "BND", Precision: 2, Name: "Brunei Dollar"},
+	{Code: "BOB", Precision: 2, Name: "Boliviano"},
+	{Code: "BRL", Precision: 2, Name: "Brazilian Real"},
+	{Code: "BSD", Precision: 2, Name: "Bahamian Dollar"},
+	{Code: "BTN", Precision: 2, Name: "Ngultrum"},
+	{Code: "BWP", Precision: 2, Name: "Pula"},
+	{Code: "BYN", Precision: 2, Name: "Belarusian Ruble"},
+	{Code: "BZD", Precision: 2, Name: "Belize Dollar"},
+	{Code: "CAD", Precision: 2, Name: "Canadian Dollar"},
+	{Code: "CDF", Precision: 2, Name: "Congolese Franc"},
+	{Code: "CHF", Precision: 2, Name: "Swiss Franc"},
+	{Code: "CLP", Precision: 0, Name: "Chilean Peso"},
+	{Code: "CNY", Precision: 2, Name: "Yuan Renminbi"},
+	{Code: "COP", Precision: 2, Name: "Colombian Peso"},
+	{Code: "CRC", Precision: 2, Name: "Costa Rican Colon"},
+	{Code: "CUP", Precision: 2, Name: "Cuban Peso"},
+	{Code: "CVE", Precision: 2, Name: "Cabo Verde Escudo"},
+	{Code: "CZK", Precision: 2, Name: "Czech Koruna"},
+	{Code: "DJF", Precision: 0, Name: "Djibouti Franc"},
+	{Code: "DKK", Precision: 2, Name: "Danish Krone"},
+	{Code: "DOP", Precision: 2, Name: "Dominican Peso"},
+	{Code: "DZD", Precision: 2, Name: "Algerian Dinar"},
+	{Code: "EGP", Precision: 2, Name: "Egyptian Pound"},
+	{Code: "ERN", Precision: 2, Name: "Nakfa"},
+	{Code: "ETB", Precision: 2, Name: "Ethiopian Birr"},
+	{Code: "EUR", Precision: 2, Name: "Euro"},
+	{Code: "FJD", Precision: 2, Name: "Fiji Dollar"},
+	{Code: "FKP", Precision: 2, Name: "Falkland Islands Pound"},
+	{Code: "GBP", Precision: 2, Name: "Pound Sterling"},
+	{Code: "GEL", Precision: 2, Name: "Lari"},
+	{Code: "GHS", Precision: 2, Name: "Ghana Cedi"},
+	{Code: "GIP", Precision: 2, Name: "Gibraltar Pound"},
+	{Code: "GMD", Precision: 2, Name: "Dalasi"},
+	{Code: "GNF", Precision: 0, Name: "Guinean Franc"},
+	{Code: "GTQ", Precision: 2, Name: "Quetzal"},
+	{Code: "GYD", Precision: 2, Name: "Guyana Dollar"},
+	{Code: "HKD", Precision: 2, Name: "Hong Kong Dollar"},
+	{Code: "HNL", Precision: 2, Name: "Lempira"},
+	{Code: "HRK", Precision: 2, Name: "Kuna"},
+	{Code: "HTG", Precision: 2, Name: "Gourde"},
+	{Code: "HUF", Precision: 2, Name: "Forint"},
+	{Code: "IDR", Precision: 2, Name: "Rupiah"},
+	{Code: "ILS", Precision: 2, Name: "New Israeli Sheqel"},
+	{Code: "INR", Precision: 2, Name: "Indian Rupee"},
+	{Code: "IQD", Precision: 3, Name: "Iraqi Dinar"},
+	{Code: "IRR", Precision: 2, Name: "Iranian Rial"},
+	{Code: "ISK", Precision: 0, Name: "Iceland Krona"},
+	{Code: "JMD", Precision: 2, Name: "Jamaican Dollar"},
+	{Code: "JOD", Precision: 3, Name: "Jordanian Dinar"},
+	{Code: "JPY", Precision: 0, Name: "Yen"},
+	{Code: "KES", Precision: 2, Name: "Kenyan Shilling"},
+	{Code: "KGS", Precision: 2, Name: "Som"},
+	{Code: "KHR", Precision: 2, Name: "Riel"},
+	{Code: "KMF", Precision: 0, Name: "Comorian Franc"},
+	{Code: "KPW", Precision: 2, Name: "North Korean Won"},
+	{Code: "KRW", Precision: 0, Name: "Won"},
+	{Code: "KWD", Precision: 3, Name: "Kuwaiti Dinar"},
+	{Code: "KYD", Precision: 2, Name: "Cayman Islands Dollar"},
+	{Code: "KZT", Precision: 2, Name: "Tenge"},
+	{Code: "LAK", Precision: 2, Name: "Lao Kip"},
+	{Code: "LBP", Precision: 2, Name: "Lebanese Pound"},
+	{Code: "LKR", Precision: 2, Name: "Sri Lanka Rupee"},
+	{Code: "LRD", Precision: 2, Name: "Liberian Dollar"},
+	{Code: "LSL", Precision: 2, Name: "Loti"},
+	{Code: "LYD", Precision: 3, Name: "Libyan Dinar"},
+	{Code: "MAD", Precision: 2, Name: "Moroccan Dirham"},
+	{Code: "MDL", Precision: 2, Name: "Moldovan Leu"},
+	{Code: "MGA", Precision: 2, Name: "Malagasy Ariary"},
+	{Code: "MKD", Precision: 2, Name: "Denar"},
+	{Code: "MMK", Precision: 2, Name: "Kyat"},
+	{Code: "MNT", Precision: 2, Name: "Tugrik"},
+	{Code: "MOP", Precision: 2, Name: "Pataca"},
+	{Code: "MRU", Precision: 2, Name: "Ouguiya"},
+	{Code: "MUR", Precision: 2, Name: "Mauritius Rupee"},
+	{Code: "MVR", Precision: 2, Name: "Rufiyaa"},
+	{Code: "MWK", Precision: 2, Name: "Malawi Kwacha"},
+	{Code: "MXN", Precision: 2, Name: "Mexican Peso"},
+	{Code: "MYR", Precision: 2, Name: "Malaysian Ringgit"},
+	{Code: "MZN", Precision: 2, Name: "Mozambique Metical"},
+	{Code: "NAD", Precision: 2, Name: "Namibia Dollar"},
+	{Code: "NGN", Precision: 2, Name: "Naira"},
+	{Code: "NIO", Precision: 2, Name: "Cordoba Oro"},
+	{Code: "NOK", Precision: 2, Name: "Norwegian Krone"},
+	{Code: "NPR", Precision: 2, Name: "Nepalese Rupee"},
+	{Code: "NZD", Precision: 2, Name: "New Zealand Dollar"},
+	{Code: "OMR", Precision: 3, Name: "Rial Omani"},
+	{Code: "PAB", Precision: 2, Name: "Balboa"},
+	{Code: "PEN", Precision: 2, Name: "Sol"},
+	{Code: "PGK", Precision: 2, Name: "Kina"},
+	{Code: "PHP", Precision: 2, Name: "Philippine Peso"},
+	{Code: "PKR", Precision: 2, Name: "Pakistan Rupee"},
+	{Code: "PLN", Precision: 2, Name: "Zloty"},
+	{Code: "PYG", Precision: 0, Name: "Guarani"},
+	{Code: "QAR", Precision: 2, Name: "Qatari Rial"},
+	{Code: "RON", Precision: 2, Name: "Romanian Leu"},
+	{Code: "RSD", Precision: 2, Name: "Serbian Dinar"},
+	{Code: "RUB", Precision: 2, Name: "Russian Ruble"},
+	{Code: "RWF", Precision: 0, Name: "Rwanda Franc"},
+	{Code: "SAR", Precision: 2, Name: "Saudi Riyal"},
+	{Code: "SBD", Precision: 2, Name: "Solomon Islands Dollar"},
+	{Code: "SCR", Precision: 2, Name: "Seychelles Rupee"},
+	{Code: "SDG", Precision: 2, Name: "Sudanese Pound"},
+	{Code: "SEK", Precision: 2, Name: "Swedish Krona"},
+	{Code: "SGD", Precision: 2, Name: "Singapore Dollar"},
+	{Code: "SHP", Precision: 2, Name: "Saint Helena Pound"},
+	{Code: "SLL", Precision: 2, Name: "Leone"},
+	{Code: "SOS", Precision: 2, Name: "Somali Shilling"},
+	{Code: "SRD", Precision: 2, Name: "Surinam Dollar"},
+	{Code: "SSP", Precision: 2, Name: "South Sudanese Pound"},
+	{Code: "STN", Precision: 2, Name: "Dobra"},
+	{Code: "SVC", Precision: 2, Name: "El Salvador Colon"},
+	{Code: "SYP", Precision: 2, Name: "Syrian Pound"},
+	{Code: "SZL", Precision: 2, Name: "Lilangeni"},
+	{Code: "THB", Precision: 2, Name: "Baht"},
+	{Code: "TJS", Precision: 2, Name: "Somoni"},
+	{Code: "TMT", Precision: 2, Name: "Turkmenistan New Manat"},
+	{Code: "TND", Precision: 3, Name: "Tunisian Dinar"},
+	{Code: "TOP", Precision: 2, Name: "Pa'anga"},
+	{Code: "TRY", Precision: 2, Name: "Turkish Lira"},
+	{Code: "TTD", Precision: 2, Name: "Trinidad and Tobago Dollar"},
+	{Code: "TWD", Precision: 2, Name: "New Taiwan Dollar"},
+	{Code: "TZS", Precision: 2, Name: "Tanzanian Shilling"},
+	{Code: "UAH", Precision: 2, Name: "Hryvnia"},
+	{Code: "UGX", Precision: 0, Name: "Uganda Shilling"},
+	{Code: "USD", Precision: 2, Name: "US Dollar"},
+	{Code: "UYU", Precision: 2, Name: "Peso Uruguayo"},
+	{Code: "UZS", Precision: 2, Name: "Uzbekistan Sum"},
+	{Code: "VES", Precision: 2, Name: "Bolivar Soberano"},
+	{Code: "VND", Precision: 0, Name: "Dong"},
+	{Code: "VUV", Precision: 0, Name: "Vatu"},
+	{Code: "WST", Precision: 2, Name: "Tala"},
+	{Code: "XAF", Precision: 0, Name: "CFA Franc BEAC"},
+	{Code: "XCD", Precision: 2, Name: "East Caribbean Dollar"},
+	{Code: "XOF", Precision: 0, Name: "CFA Franc BCEAO"},
+	{Code: "XPF", Precision: 0, Name: "CFP Franc"},
+	{Code: "YER", Precision: 2, Name: "Yemeni Rial"},
+	{Code: "ZAR", Precision: 2, Name: "Rand"},
+	{Code: "ZMW", Precision: 2, Name: "Zambian Kwacha"},
+	{Code: "ZWL", Precision: 2, Name: "Zimbabwe Dollar"},
+}
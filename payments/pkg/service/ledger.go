@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+var ErrLedgerUnbalanced = errors.Errorf("ledger is unbalanced")
+
+// Posting is an immutable ledger entry. Every transaction produces two postings:
+// a debit of the donor (negative amount) and a credit of the recipient (positive amount),
+// so the sum of all postings in a currency is always zero.
+type Posting struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+
+	OperationID   uint   `gorm:"index"`
+	TransactionID uint   `gorm:"index"`
+	AccountID     int64  `gorm:"index"`
+	Currency      string `gorm:"index"`
+
+	Amount decimal.Decimal `sql:"type:decimal(20,8);"`
+	// Balance is the running balance of the account after the posting
+	Balance decimal.Decimal `sql:"type:decimal(20,8);"`
+}
+
+// IsDebit reports whether the posting takes money from the account
+func (p *Posting) IsDebit() bool {
+	return p.Amount.IsNegative()
+}
+
+// IsOpening reports whether the posting is the opening balance of the account written by the ledger backfill.
+// Opening postings belong to no operation.
+func (p *Posting) IsOpening() bool {
+	return p.OperationID == 0 && p.TransactionID == 0
+}
+
+// PostingsRepository describes interaction with an append-only repository of Postings.
+type PostingsRepository interface {
+	Create(ctx context.Context, p *Posting) (*Posting, error)
+
+	GetByAccID(ctx context.Context, id int64) ([]*Posting, error)
+	// Balance returns the sum of postings of the account
+	Balance(ctx context.Context, id int64) (decimal.Decimal, error)
+	// Balances returns sums of postings grouped by accounts
+	Balances(ctx context.Context) (map[int64]decimal.Decimal, error)
+	// Totals returns sums of postings grouped by currencies
+	Totals(ctx context.Context) (map[string]decimal.Decimal, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type postingsRepository struct {
+	db *gorm.DB
+}
+
+func NewPostingsRepository(db *gorm.DB) PostingsRepository {
+	return &postingsRepository{db}
+}
+
+func (r *postingsRepository) Create(ctx context.Context, p *Posting) (*Posting, error) {
+	if err := r.db.Create(p).Error; err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *postingsRepository) GetByAccID(ctx context.Context, id int64) ([]*Posting, error) {
+	ps := []*Posting{}
+	if err := r.db.Where("account_id = ?", id).Order("id").Find(&ps).Error; err != nil {
+		return nil, err
+	}
+
+	return ps, nil
+}
+
+// amountScale is the scale of amount columns. Sums are rounded to it, since sqlite
+// has no decimal type and sums amounts as floating point numbers.
+const amountScale = 8
+
+func (r *postingsRepository) Balance(ctx context.Context, id int64) (decimal.Decimal, error) {
+	b := decimal.Zero
+
+	row := r.db.Model(&Posting{}).Where("account_id = ?", id).Select("COALESCE(SUM(amount), 0)").Row()
+	if err := row.Scan(&b); err != nil {
+		return b, err
+	}
+
+	return b.Round(amountScale), nil
+}
+
+func (r *postingsRepository) Balances(ctx context.Context) (map[int64]decimal.Decimal, error) {
+	rows, err := r.db.Model(&Posting{}).Select("account_id, SUM(amount)").Group("account_id").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := map[int64]decimal.Decimal{}
+	for rows.Next() {
+		var id int64
+		var sum decimal.Decimal
+		if err := rows.Scan(&id, &sum); err != nil {
+			return nil, err
+		}
+		balances[id] = sum.Round(amountScale)
+	}
+
+	return balances, rows.Err()
+}
+
+func (r *postingsRepository) Totals(ctx context.Context) (map[string]decimal.Decimal, error) {
+	rows, err := r.db.Model(&Posting{}).Select("currency, SUM(amount)").Group("currency").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := map[string]decimal.Decimal{}
+	for rows.Next() {
+		var currency string
+		var sum decimal.Decimal
+		if err := rows.Scan(&currency, &sum); err != nil {
+			return nil, err
+		}
+		totals[currency] = sum.Round(amountScale)
+	}
+
+	return totals, rows.Err()
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// MaxOperationLegs is the biggest number of legs of an operation
+const MaxOperationLegs = 1000
+
+var (
+	ErrEmptyOperation      = NewError(ErrorKindValidation, "empty_operation", "operation must move money")
+	ErrTooManyLegs         = NewError(ErrorKindValidation, "too_many_legs", "operation contains too many legs")
+	ErrUnbalancedOperation = NewError(ErrorKindValidation, "unbalanced_operation", "legs of every currency must sum to zero")
+)
+
+// OperationLeg is a signed change of an account balance.
+// Negative amounts debit the account, positive amounts credit it.
+type OperationLeg struct {
+	AccountID int64           `json:"account_id"`
+	Currency  string          `json:"currency"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+// legsTransactions converts balanced legs to transactions. Legs of the same account and currency
+// are summed up, then debits of every currency are matched with credits in the order of the legs.
+// Currencies of the legs must be normalized.
+func legsTransactions(legs []OperationLeg) ([]Transaction, error) {
+	type key struct {
+		accID    int64
+		currency string
+	}
+
+	currencies := []string{}
+	keys := map[string][]key{}
+	sums := map[key]decimal.Decimal{}
+	for _, l := range legs {
+		k := key{l.AccountID, l.Currency}
+		if _, ok := sums[k]; !ok {
+			if _, ok := keys[l.Currency]; !ok {
+				currencies = append(currencies, l.Currency)
+			}
+			keys[l.Currency] = append(keys[l.Currency], k)
+			sums[k] = decimal.Zero
+		}
+		sums[k] = sums[k].Add(l.Amount)
+	}
+
+	txs := []Transaction{}
+	for _, c := range currencies {
+		type rest struct {
+			accID  int64
+			amount decimal.Decimal
+		}
+
+		var debits, credits []*rest
+		total := decimal.Zero
+		for _, k := range keys[c] {
+			sum := sums[k]
+			total = total.Add(sum)
+			switch {
+			case sum.IsNegative():
+				debits = append(debits, &rest{k.accID, sum.Neg()})
+			case sum.IsPositive():
+				credits = append(credits, &rest{k.accID, sum})
+			}
+		}
+
+		if !total.IsZero() {
+			return nil, errors.Wrapf(ErrUnbalancedOperation, "%s legs sum to %s", c, total)
+		}
+
+		for i, j := 0, 0; i < len(debits) && j < len(credits); {
+			d, cr := debits[i], credits[j]
+			amount := decimal.Min(d.amount, cr.amount)
+
+			txs = append(txs, Transaction{
+				From:     d.accID,
+				To:       cr.accID,
+				Currency: c,
+				Amount:   amount,
+			})
+
+			d.amount = d.amount.Sub(amount)
+			cr.amount = cr.amount.Sub(amount)
+			if d.amount.IsZero() {
+				i++
+			}
+			if cr.amount.IsZero() {
+				j++
+			}
+		}
+	}
+
+	if len(txs) == 0 {
+		return nil, ErrEmptyOperation
+	}
+
+	return txs, nil
+}
@@ -1,42 +1,161 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
 	redsync "gopkg.in/redsync.v1"
 )
 
+var (
+	// ErrLockNotAcquired is returned when all attempts to acquire the lock failed
+	ErrLockNotAcquired = errors.New("lock isn't acquired")
+	// ErrLockLost is returned when the account row was written by the holder of a newer lock
+	ErrLockLost = NewError(ErrorKindConflict, "lock_lost", "lock of the account expired and the account was changed by another request")
+)
+
 // Lock provides interface for simple distributed mechanism for
 // restricting access to the same object
 type Lock interface {
 	Lock() error
+	// LockContext acquires the lock like Lock, but stops waiting when the context is done
+	LockContext(ctx context.Context) error
 	Unlock() error
+	// Token returns the fencing token of the held lock. Tokens of a key grow with every
+	// acquisition, so writes of a holder whose lease expired can be told from the newer ones.
+	// Zero means the lock has no token.
+	Token() int64
 }
 
 // LockFactory provides interface for generating Locks
 type LockFactory interface {
-	Make(key string) Lock
+	// Make returns the lock of the key. The options override options of the factory.
+	Make(key string, opts ...LockOption) Lock
+}
+
+// LockOptions are the lease and the retry policy of locks
+type LockOptions struct {
+	// TTL is the lease of the lock. Held locks extend it until they are released.
+	TTL time.Duration
+	// Tries is the number of attempts to acquire the lock
+	Tries int
+	// RetryDelay is the delay between attempts
+	RetryDelay time.Duration
+}
+
+// DefaultLockOptions are the options of redsync mutexes
+var DefaultLockOptions = LockOptions{
+	TTL:        8 * time.Second,
+	Tries:      32,
+	RetryDelay: 500 * time.Millisecond,
+}
+
+// LockOption changes options of locks
+type LockOption func(o *LockOptions)
+
+// WithLockTTL sets the lease of locks
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(o *LockOptions) { o.TTL = ttl }
+}
+
+// WithLockTries sets the number of attempts to acquire locks
+func WithLockTries(tries int) LockOption {
+	return func(o *LockOptions) { o.Tries = tries }
+}
+
+// WithLockRetryDelay sets the delay between attempts to acquire locks
+func WithLockRetryDelay(delay time.Duration) LockOption {
+	return func(o *LockOptions) { o.RetryDelay = delay }
+}
+
+func makeLockOptions(base LockOptions, opts []LockOption) LockOptions {
+	for _, opt := range opts {
+		opt(&base)
+	}
+	return base
+}
+
+// done returns the done channel of the context. Nil contexts are never done.
+func done(ctx context.Context) <-chan struct{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Done()
 }
 
 // ─── LOCK IMPLEMENTATION ────────────────────────────────────────────────────────
 
 type lock struct {
-	m *redsync.Mutex
+	m     *redsync.Mutex
+	pool  *redis.Pool
+	key   string
+	opts  LockOptions
+	token int64
+
+	// stop finishes the extension of the lease, stopped is closed after it
+	stop    chan struct{}
+	stopped chan struct{}
 }
 
+// NewLock returns the lock of the mutex. Attempts to acquire it and its lease are the ones of the mutex.
+//
+// Deprecated: the lock has no fencing token and doesn't extend its lease, use NewLockFactory instead.
 func NewLock(m *redsync.Mutex) Lock {
-	return &lock{m}
+	return &lock{m: m, opts: LockOptions{Tries: 1}}
 }
 
 func (l *lock) Lock() error {
-	if err := l.m.Lock(); err != nil {
-		return errors.Wrap(err, "mutex locking failed")
+	return l.LockContext(context.Background())
+}
+
+func (l *lock) LockContext(ctx context.Context) error {
+	for i := 1; ; i++ {
+		err := l.m.Lock()
+		if err == nil {
+			break
+		}
+		if err != redsync.ErrFailed {
+			return errors.Wrap(err, "mutex locking failed")
+		}
+		if i >= l.opts.Tries {
+			return errors.Wrap(ErrLockNotAcquired, "mutex locking failed")
+		}
+
+		select {
+		case <-done(ctx):
+			return errors.Wrap(ctx.Err(), "mutex locking failed")
+		case <-time.After(l.opts.RetryDelay):
+		}
+	}
+
+	// Locks of NewLock have neither tokens nor options of the lease
+	if l.pool == nil {
+		return nil
 	}
 
+	token, err := l.nextToken()
+	if err != nil {
+		l.m.Unlock()
+		return errors.Wrap(err, "fencing token getting failed")
+	}
+
+	l.token = token
+	l.stop = make(chan struct{})
+	l.stopped = make(chan struct{})
+	go l.extend(l.stop, l.stopped)
+
 	return nil
 }
 
 func (l *lock) Unlock() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.stopped
+		l.stop = nil
+	}
+
 	if !l.m.Unlock() {
 		return errors.New("mutex releasing failed")
 	}
@@ -44,6 +163,38 @@ func (l *lock) Unlock() error {
 	return nil
 }
 
+func (l *lock) Token() int64 {
+	return l.token
+}
+
+// nextToken increments the counter of acquisitions of the key
+func (l *lock) nextToken() (int64, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(conn.Do("INCR", "fence:"+l.key))
+}
+
+// extend renews the lease until the lock is released. When the lease is lost anyway,
+// writes of the holder are rejected by the fencing token.
+func (l *lock) extend(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(l.opts.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !l.m.Extend() {
+				return
+			}
+		}
+	}
+}
+
 // ─── LOCK POOL IMPLEMENTATION ───────────────────────────────────────────────────
 
 type lockPool struct {
@@ -57,8 +208,16 @@ func NewLockPool(locks []Lock) Lock {
 }
 
 func (p *lockPool) Lock() error {
-	for _, l := range p.locks {
-		if err := l.Lock(); err != nil {
+	return p.LockContext(context.Background())
+}
+
+// LockContext acquires all locks in their order. Acquired locks are released if any of them fails.
+func (p *lockPool) LockContext(ctx context.Context) error {
+	for i, l := range p.locks {
+		if err := l.LockContext(ctx); err != nil {
+			for _, acquired := range p.locks[:i] {
+				acquired.Unlock()
+			}
 			return err
 		}
 	}
@@ -66,29 +225,97 @@ func (p *lockPool) Lock() error {
 	return nil
 }
 
+// Unlock releases all locks even if some of them fail and returns the first error
 func (p *lockPool) Unlock() error {
+	var first error
 	for _, l := range p.locks {
-		if err := l.Unlock(); err != nil {
-			return err
+		if err := l.Unlock(); err != nil && first == nil {
+			first = err
 		}
 	}
 
-	return nil
+	return first
+}
+
+// Token returns the largest token of the locks
+func (p *lockPool) Token() int64 {
+	var token int64
+	for _, l := range p.locks {
+		if t := l.Token(); t > token {
+			token = t
+		}
+	}
+
+	return token
+}
+
+// ─── ACCOUNTS LOCK ──────────────────────────────────────────────────────────────
+
+// fencesKey is the context key of fencing tokens of locked accounts
+type fencesKey struct{}
+
+// accountsLock locks accounts in the order of their ids
+type accountsLock struct {
+	Lock
+	ids   []int64
+	locks []Lock
+}
+
+// Fence returns the context in which writes of the locked accounts are fenced by tokens of their locks
+func (l *accountsLock) Fence(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tokens := map[int64]int64{}
+	if parent, ok := ctx.Value(fencesKey{}).(map[int64]int64); ok {
+		for id, token := range parent {
+			tokens[id] = token
+		}
+	}
+
+	for i, id := range l.ids {
+		if token := l.locks[i].Token(); token != 0 {
+			tokens[id] = token
+		}
+	}
+
+	return context.WithValue(ctx, fencesKey{}, tokens)
+}
+
+// fenceToken returns the fencing token of the account locked in the context
+func fenceToken(ctx context.Context, id int64) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+
+	tokens, _ := ctx.Value(fencesKey{}).(map[int64]int64)
+	token, ok := tokens[id]
+	return token, ok
 }
 
 // ─── LOCK FACTORY IMPLEMENTATION ────────────────────────────────────────────────
 
 type lockFactory struct {
-	s *redsync.Redsync
+	s    *redsync.Redsync
+	pool *redis.Pool
+	opts LockOptions
 }
 
-// NewLockFactory returns a factory that generates lock-objects by given key
-func NewLockFactory(pool *redis.Pool) LockFactory {
+// NewLockFactory returns a factory that generates lock-objects by given key.
+// Locks use DefaultLockOptions changed by the options.
+func NewLockFactory(pool *redis.Pool, opts ...LockOption) LockFactory {
 	return &lockFactory{
-		s: redsync.New([]redsync.Pool{pool}),
+		s:    redsync.New([]redsync.Pool{pool}),
+		pool: pool,
+		opts: makeLockOptions(DefaultLockOptions, opts),
 	}
 }
 
-func (f *lockFactory) Make(key string) Lock {
-	return NewLock(f.s.NewMutex(key))
+func (f *lockFactory) Make(key string, opts ...LockOption) Lock {
+	o := makeLockOptions(f.opts, opts)
+
+	// Attempts are made by the lock, so waiting between them stops with the context
+	m := f.s.NewMutex(key, redsync.SetExpiry(o.TTL), redsync.SetTries(1))
+	return &lock{m: m, pool: f.pool, key: key, opts: o}
 }
@@ -0,0 +1,1092 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrMemoryTxDone is returned by a memory unit of work which was already saved or reverted
+var ErrMemoryTxDone = errors.New("memory transaction is already finished")
+
+// ErrMemoryUniqueViolation is returned by memory repositories on duplicates of unique columns
+var ErrMemoryUniqueViolation = errors.New("unique constraint violation")
+
+const (
+	memAccounts          = "accounts"
+	memOperations        = "operations"
+	memTransactions      = "transactions"
+	memHolds             = "holds"
+	memPostings          = "postings"
+	memFXQuotes          = "fx_quotes"
+	memStatusChanges     = "account_status_changes"
+	memSchedules         = "schedules"
+	memScheduleAttempts  = "schedule_attempts"
+	memEvents            = "events"
+	memWebhooks          = "webhooks"
+	memWebhookDeliveries = "webhook_deliveries"
+)
+
+// ─── MEMORY STORE ───────────────────────────────────────────────────────────────
+
+// memoryStore keeps committed rows of all tables. Rows are stored as values,
+// so callers never share memory with the store.
+type memoryStore struct {
+	mu     sync.Mutex
+	seqs   map[string]int64
+	tables map[string]map[int64]interface{}
+	// claims are unique values written by unfinished transactions
+	claims map[string]*memoryTx
+}
+
+// memoryTx is a unit of work over the store. Writes are buffered until the commit,
+// reads see committed rows of other transactions and own writes (read committed).
+type memoryTx struct {
+	s      *memoryStore
+	mu     sync.Mutex
+	writes map[string]map[int64]interface{}
+	done   bool
+	// claims are guarded by the mutex of the store
+	claims []string
+}
+
+// nextID returns the next id of the table. Like database sequences, ids aren't reused after rollbacks.
+func (tx *memoryTx) nextID(table string) int64 {
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	tx.s.seqs[table]++
+	return tx.s.seqs[table]
+}
+
+func (tx *memoryTx) get(table string, id int64) (interface{}, bool) {
+	tx.mu.Lock()
+	v, ok := tx.writes[table][id]
+	tx.mu.Unlock()
+	if ok {
+		return v, true
+	}
+
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	v, ok = tx.s.tables[table][id]
+	return v, ok
+}
+
+// put writes the row. Rows written with explicit ids move the sequence past them,
+// so fixtures never collide with generated ids.
+func (tx *memoryTx) put(table string, id int64, v interface{}) {
+	tx.s.mu.Lock()
+	if tx.s.seqs[table] < id {
+		tx.s.seqs[table] = id
+	}
+	tx.s.mu.Unlock()
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.writes[table] == nil {
+		tx.writes[table] = map[int64]interface{}{}
+	}
+	tx.writes[table][id] = v
+}
+
+// claim reserves the unique value for the transaction until it finishes. Unlike databases,
+// which wait for the other transaction, values of unfinished transactions are rejected at once.
+func (tx *memoryTx) claim(value string) bool {
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	if owner, ok := tx.s.claims[value]; ok && owner != tx {
+		return false
+	}
+
+	tx.s.claims[value] = tx
+	tx.claims = append(tx.claims, value)
+	return true
+}
+
+// release frees values claimed by the transaction, the mutex of the store must be held
+func (tx *memoryTx) release() {
+	for _, v := range tx.claims {
+		delete(tx.s.claims, v)
+	}
+	tx.claims = nil
+}
+
+// list returns all rows of the table ordered by id
+func (tx *memoryTx) list(table string) []interface{} {
+	rows := map[int64]interface{}{}
+
+	tx.s.mu.Lock()
+	for id, v := range tx.s.tables[table] {
+		rows[id] = v
+	}
+	tx.s.mu.Unlock()
+
+	tx.mu.Lock()
+	for id, v := range tx.writes[table] {
+		rows[id] = v
+	}
+	tx.mu.Unlock()
+
+	ids := make([]int64, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	res := make([]interface{}, len(ids))
+	for i, id := range ids {
+		res[i] = rows[id]
+	}
+	return res
+}
+
+func (tx *memoryTx) commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrMemoryTxDone
+	}
+	tx.done = true
+
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	tx.release()
+	for table, rows := range tx.writes {
+		if tx.s.tables[table] == nil {
+			tx.s.tables[table] = map[int64]interface{}{}
+		}
+		for id, v := range rows {
+			tx.s.tables[table][id] = v
+		}
+	}
+
+	return nil
+}
+
+func (tx *memoryTx) rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrMemoryTxDone
+	}
+	tx.done = true
+	tx.writes = nil
+
+	tx.s.mu.Lock()
+	tx.release()
+	tx.s.mu.Unlock()
+
+	return nil
+}
+
+// ─── MEMORY UOW ─────────────────────────────────────────────────────────────────
+
+type memoryUOWPayments struct {
+	tx *memoryTx
+}
+
+func (u *memoryUOWPayments) Save() error {
+	return u.tx.commit()
+}
+
+func (u *memoryUOWPayments) Revert() error {
+	return u.tx.rollback()
+}
+
+func (u *memoryUOWPayments) Accounts() AccountsRepository {
+	return &memoryAccountsRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) Operations() OperationsRepository {
+	return &memoryOperationsRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) Holds() HoldsRepository {
+	return &memoryHoldsRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) Postings() PostingsRepository {
+	return &memoryPostingsRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) FXQuotes() FXQuotesRepository {
+	return &memoryFXQuotesRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) StatusChanges() StatusChangesRepository {
+	return &memoryStatusChangesRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) Schedules() SchedulesRepository {
+	return &memorySchedulesRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) ScheduleAttempts() ScheduleAttemptsRepository {
+	return &memoryScheduleAttemptsRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) Events() EventsRepository {
+	return &memoryEventsRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) Webhooks() WebhooksRepository {
+	return &memoryWebhooksRepository{u.tx}
+}
+
+func (u *memoryUOWPayments) WebhookDeliveries() WebhookDeliveriesRepository {
+	return &memoryWebhookDeliveriesRepository{u.tx}
+}
+
+type memoryUOWPaymentsFactory struct {
+	s *memoryStore
+}
+
+// NewMemoryUOWPaymentsFactory returns a factory of units of work over a new empty in-memory storage.
+// Changes of a unit of work are visible to others only after Save, Revert discards them.
+func NewMemoryUOWPaymentsFactory() UOWPaymentsFactory {
+	return &memoryUOWPaymentsFactory{
+		s: &memoryStore{
+			seqs:   map[string]int64{},
+			tables: map[string]map[int64]interface{}{},
+			claims: map[string]*memoryTx{},
+		},
+	}
+}
+
+func (f *memoryUOWPaymentsFactory) Make() (UOWPayments, error) {
+	return &memoryUOWPayments{
+		tx: &memoryTx{
+			s:      f.s,
+			writes: map[string]map[int64]interface{}{},
+		},
+	}, nil
+}
+
+// ─── MEMORY LOCK ────────────────────────────────────────────────────────────────
+
+type memoryLockFactory struct {
+	mu    sync.Mutex
+	locks map[string]*memoryKey
+	opts  LockOptions
+}
+
+// memoryKey is the state of a key shared by its locks
+type memoryKey struct {
+	held   chan struct{}
+	tokens int64
+}
+
+// NewMemoryLockFactory returns a factory of locks which work within the process.
+// It's enough for tests and a single node. Locks don't expire, so the TTL is unused,
+// acquisition waits for Tries * RetryDelay at most.
+func NewMemoryLockFactory(opts ...LockOption) LockFactory {
+	return &memoryLockFactory{
+		locks: map[string]*memoryKey{},
+		opts:  makeLockOptions(DefaultLockOptions, opts),
+	}
+}
+
+func (f *memoryLockFactory) Make(key string, opts ...LockOption) Lock {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	k, ok := f.locks[key]
+	if !ok {
+		k = &memoryKey{held: make(chan struct{}, 1)}
+		f.locks[key] = k
+	}
+
+	return &memoryLock{k: k, opts: makeLockOptions(f.opts, opts)}
+}
+
+type memoryLock struct {
+	k     *memoryKey
+	opts  LockOptions
+	token int64
+	// held is set while this lock holds the key, so only the holder releases it
+	held bool
+}
+
+func (l *memoryLock) Lock() error {
+	return l.LockContext(context.Background())
+}
+
+func (l *memoryLock) LockContext(ctx context.Context) error {
+	timer := time.NewTimer(time.Duration(l.opts.Tries) * l.opts.RetryDelay)
+	defer timer.Stop()
+
+	select {
+	case l.k.held <- struct{}{}:
+	case <-done(ctx):
+		return errors.Wrap(ctx.Err(), "mutex locking failed")
+	case <-timer.C:
+		return errors.Wrap(ErrLockNotAcquired, "mutex locking failed")
+	}
+
+	// Only the holder changes the counter
+	l.k.tokens++
+	l.token = l.k.tokens
+	l.held = true
+
+	return nil
+}
+
+func (l *memoryLock) Unlock() error {
+	if !l.held {
+		return errors.New("mutex releasing failed")
+	}
+
+	l.held = false
+	<-l.k.held
+	return nil
+}
+
+func (l *memoryLock) Token() int64 {
+	return l.token
+}
+
+// ─── MEMORY REPOSITORIES ────────────────────────────────────────────────────────
+
+type memoryAccountsRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryAccountsRepository) Create(ctx context.Context, a *Account) (*Account, error) {
+	if a.ID == 0 {
+		a.ID = r.tx.nextID(memAccounts)
+	} else if _, ok := r.tx.get(memAccounts, a.ID); ok {
+		return nil, ErrMemoryUniqueViolation
+	}
+
+	r.save(a)
+	return a, nil
+}
+
+func (r *memoryAccountsRepository) Update(ctx context.Context, a *Account) (*Account, error) {
+	if token, ok := fenceToken(ctx, a.ID); ok {
+		if v, ok := r.tx.get(memAccounts, a.ID); ok && v.(Account).Fence > token {
+			return nil, ErrLockLost
+		}
+		a.Fence = token
+	}
+
+	r.save(a)
+	return a, nil
+}
+
+// save writes the account filling the columns the database fills
+func (r *memoryAccountsRepository) save(a *Account) {
+	now := time.Now()
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = now
+	}
+	a.UpdatedAt = now
+	if a.Status == "" {
+		a.Status = AccountStatusActive
+	}
+
+	r.tx.put(memAccounts, a.ID, *a)
+}
+
+func (r *memoryAccountsRepository) Delete(ctx context.Context, id int64) error {
+	v, ok := r.tx.get(memAccounts, id)
+	if !ok {
+		return nil
+	}
+
+	a := v.(Account)
+	if a.DeletedAt == nil {
+		now := time.Now()
+		a.DeletedAt = &now
+		r.tx.put(memAccounts, id, a)
+	}
+
+	return nil
+}
+
+// Get returns the account even if it's closed, so operations with closed accounts fail with ErrAccountClosed
+func (r *memoryAccountsRepository) Get(ctx context.Context, id int64) (*Account, error) {
+	v, ok := r.tx.get(memAccounts, id)
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	a := v.(Account)
+	return &a, nil
+}
+
+func (r *memoryAccountsRepository) GetAll(ctx context.Context) ([]*Account, error) {
+	return r.find(func(a *Account) bool { return a.DeletedAt == nil }), nil
+}
+
+func (r *memoryAccountsRepository) Find(ctx context.Context, f AccountsFilter) ([]*Account, error) {
+	after, err := DecodeCursor(f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	as := r.find(r.filter(f))
+	if f.Order == SortOrderDesc {
+		reverseAccounts(as)
+	}
+
+	res := []*Account{}
+	for _, a := range as {
+		if after != 0 && ((f.Order == SortOrderDesc && a.ID >= after) || (f.Order != SortOrderDesc && a.ID <= after)) {
+			continue
+		}
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+		res = append(res, a)
+	}
+
+	return res, nil
+}
+
+func (r *memoryAccountsRepository) Count(ctx context.Context, f AccountsFilter) (int64, error) {
+	return int64(len(r.find(r.filter(f)))), nil
+}
+
+// filter returns the predicate of the filter except the page
+func (r *memoryAccountsRepository) filter(f AccountsFilter) func(a *Account) bool {
+	currency := NormalizeCurrency(f.Currency)
+
+	return func(a *Account) bool {
+		switch f.Deleted {
+		case DeletedStatusDeleted:
+			if a.DeletedAt == nil {
+				return false
+			}
+		case DeletedStatusAll:
+		default:
+			if a.DeletedAt != nil {
+				return false
+			}
+		}
+
+		return (currency == "" || a.Currency == currency) &&
+			strings.HasPrefix(a.Name, f.NamePrefix) &&
+			(f.MinAmount == nil || a.Amount.GreaterThanOrEqual(*f.MinAmount)) &&
+			(f.MaxAmount == nil || a.Amount.LessThanOrEqual(*f.MaxAmount)) &&
+			(f.CreatedFrom == nil || !a.CreatedAt.Before(*f.CreatedFrom)) &&
+			(f.CreatedTo == nil || a.CreatedAt.Before(*f.CreatedTo))
+	}
+}
+
+func (r *memoryAccountsRepository) GetByRole(ctx context.Context, role AccountRole, currency string) (*Account, error) {
+	as := r.find(func(a *Account) bool { return a.DeletedAt == nil && a.Role == role && a.Currency == currency })
+	if len(as) == 0 {
+		return nil, ErrAccountNotFound
+	}
+
+	return as[0], nil
+}
+
+func (r *memoryAccountsRepository) GetSystem(ctx context.Context) ([]*Account, error) {
+	as := r.find(func(a *Account) bool { return a.DeletedAt == nil && a.Role != AccountRoleUser })
+	sort.SliceStable(as, func(i, j int) bool {
+		if as[i].Currency != as[j].Currency {
+			return as[i].Currency < as[j].Currency
+		}
+		return as[i].Role < as[j].Role
+	})
+
+	return as, nil
+}
+
+func (r *memoryAccountsRepository) find(match func(a *Account) bool) []*Account {
+	as := []*Account{}
+	for _, v := range r.tx.list(memAccounts) {
+		a := v.(Account)
+		if match(&a) {
+			as = append(as, &a)
+		}
+	}
+	return as
+}
+
+func reverseAccounts(as []*Account) {
+	for i, j := 0, len(as)-1; i < j; i, j = i+1, j-1 {
+		as[i], as[j] = as[j], as[i]
+	}
+}
+
+type memoryOperationsRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryOperationsRepository) Create(ctx context.Context, o *Operation) (*Operation, error) {
+	for _, v := range r.tx.list(memOperations) {
+		other := v.(Operation)
+		if o.IdempotencyKey != nil && other.IdempotencyKey != nil && *o.IdempotencyKey == *other.IdempotencyKey {
+			return nil, ErrDuplicateIdempotencyKey
+		}
+		if (o.ReversalOf != nil && other.ReversalOf != nil && *o.ReversalOf == *other.ReversalOf) ||
+			(o.QuoteID != nil && other.QuoteID != nil && *o.QuoteID == *other.QuoteID) {
+			return nil, ErrMemoryUniqueViolation
+		}
+	}
+
+	if o.IdempotencyKey != nil && !r.tx.claim(memOperations+".idempotency_key:"+*o.IdempotencyKey) {
+		return nil, ErrDuplicateIdempotencyKey
+	}
+
+	now := time.Now()
+	o.ID = uint(r.tx.nextID(memOperations))
+	o.CreatedAt, o.UpdatedAt = now, now
+
+	for i := range o.Transactions {
+		t := &o.Transactions[i]
+		t.ID = uint(r.tx.nextID(memTransactions))
+		t.OperationID = o.ID
+		t.CreatedAt, t.UpdatedAt = now, now
+	}
+
+	r.tx.put(memOperations, int64(o.ID), *copyOperation(o))
+	return o, nil
+}
+
+func (r *memoryOperationsRepository) Get(ctx context.Context, id int64) (*Operation, error) {
+	v, ok := r.tx.get(memOperations, id)
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+
+	o := v.(Operation)
+	return copyOperation(&o), nil
+}
+
+func (r *memoryOperationsRepository) GetByAccID(ctx context.Context, id int64, f OperationsFilter) ([]*Operation, error) {
+	after, err := DecodeCursor(f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[OperationType]bool{}
+	for _, t := range f.Types {
+		types[t] = true
+	}
+
+	// Transactions of the account matching the transaction filters
+	txMatch := func(t *Transaction) bool {
+		return (t.From == id || t.To == id) &&
+			(f.Counterparty == 0 || t.From == f.Counterparty || t.To == f.Counterparty) &&
+			(f.MinAmount == nil || t.Amount.GreaterThanOrEqual(*f.MinAmount)) &&
+			(f.MaxAmount == nil || t.Amount.LessThanOrEqual(*f.MaxAmount))
+	}
+	txFiltered := f.Counterparty != 0 || f.MinAmount != nil || f.MaxAmount != nil
+
+	os := r.find(func(o *Operation) bool {
+		if !containsID(o.Participants, id) ||
+			(len(types) > 0 && !types[o.Type]) ||
+			(f.CreatedFrom != nil && o.CreatedAt.Before(*f.CreatedFrom)) ||
+			(f.CreatedTo != nil && !o.CreatedAt.Before(*f.CreatedTo)) {
+			return false
+		}
+
+		if !txFiltered {
+			return true
+		}
+		for i := range o.Transactions {
+			if txMatch(&o.Transactions[i]) {
+				return true
+			}
+		}
+		return false
+	})
+
+	if f.Order == SortOrderDesc {
+		for i, j := 0, len(os)-1; i < j; i, j = i+1, j-1 {
+			os[i], os[j] = os[j], os[i]
+		}
+	}
+
+	res := []*Operation{}
+	for _, o := range os {
+		oid := int64(o.ID)
+		if after != 0 && ((f.Order == SortOrderDesc && oid >= after) || (f.Order != SortOrderDesc && oid <= after)) {
+			continue
+		}
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+		res = append(res, o)
+	}
+
+	return res, nil
+}
+
+func (r *memoryOperationsRepository) GetReversal(ctx context.Context, id int64) (*Operation, error) {
+	os := r.find(func(o *Operation) bool { return o.ReversalOf != nil && int64(*o.ReversalOf) == id })
+	if len(os) == 0 {
+		return nil, ErrOperationNotFound
+	}
+
+	return os[0], nil
+}
+
+func (r *memoryOperationsRepository) GetByIdempotencyKey(ctx context.Context, key string) (*Operation, error) {
+	os := r.find(func(o *Operation) bool { return o.IdempotencyKey != nil && *o.IdempotencyKey == key })
+	if len(os) == 0 {
+		return nil, ErrOperationNotFound
+	}
+
+	return os[0], nil
+}
+
+func (r *memoryOperationsRepository) GetAll(ctx context.Context) ([]*Operation, error) {
+	return r.find(func(o *Operation) bool { return true }), nil
+}
+
+func (r *memoryOperationsRepository) find(match func(o *Operation) bool) []*Operation {
+	os := []*Operation{}
+	for _, v := range r.tx.list(memOperations) {
+		o := v.(Operation)
+		if match(&o) {
+			os = append(os, copyOperation(&o))
+		}
+	}
+	return os
+}
+
+// copyOperation copies the operation with its transactions and participants
+func copyOperation(o *Operation) *Operation {
+	c := *o
+	c.Transactions = append([]Transaction{}, o.Transactions...)
+	c.Participants = append([]int64{}, o.Participants...)
+	return &c
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryHoldsRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryHoldsRepository) Create(ctx context.Context, h *Hold) (*Hold, error) {
+	now := time.Now()
+	h.ID = uint(r.tx.nextID(memHolds))
+	h.CreatedAt, h.UpdatedAt = now, now
+
+	r.tx.put(memHolds, int64(h.ID), *h)
+	return h, nil
+}
+
+func (r *memoryHoldsRepository) Update(ctx context.Context, h *Hold) (*Hold, error) {
+	h.UpdatedAt = time.Now()
+	r.tx.put(memHolds, int64(h.ID), *h)
+	return h, nil
+}
+
+func (r *memoryHoldsRepository) Get(ctx context.Context, id int64) (*Hold, error) {
+	v, ok := r.tx.get(memHolds, id)
+	if !ok {
+		return nil, ErrHoldNotFound
+	}
+
+	h := v.(Hold)
+	return &h, nil
+}
+
+func (r *memoryHoldsRepository) GetExpired(ctx context.Context, now time.Time) ([]*Hold, error) {
+	hs := []*Hold{}
+	for _, v := range r.tx.list(memHolds) {
+		h := v.(Hold)
+		if h.Status == HoldStatusActive && !h.ExpiresAt.After(now) {
+			hs = append(hs, &h)
+		}
+	}
+
+	return hs, nil
+}
+
+type memoryPostingsRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryPostingsRepository) Create(ctx context.Context, p *Posting) (*Posting, error) {
+	p.ID = uint(r.tx.nextID(memPostings))
+	p.CreatedAt = time.Now()
+
+	r.tx.put(memPostings, int64(p.ID), *p)
+	return p, nil
+}
+
+func (r *memoryPostingsRepository) GetByAccID(ctx context.Context, id int64) ([]*Posting, error) {
+	ps := []*Posting{}
+	for _, p := range r.all() {
+		if p.AccountID == id {
+			ps = append(ps, p)
+		}
+	}
+
+	return ps, nil
+}
+
+func (r *memoryPostingsRepository) Balance(ctx context.Context, id int64) (decimal.Decimal, error) {
+	b := decimal.Zero
+	for _, p := range r.all() {
+		if p.AccountID == id {
+			b = b.Add(p.Amount)
+		}
+	}
+
+	return b, nil
+}
+
+func (r *memoryPostingsRepository) Balances(ctx context.Context) (map[int64]decimal.Decimal, error) {
+	balances := map[int64]decimal.Decimal{}
+	for _, p := range r.all() {
+		balances[p.AccountID] = balances[p.AccountID].Add(p.Amount)
+	}
+
+	return balances, nil
+}
+
+func (r *memoryPostingsRepository) Totals(ctx context.Context) (map[string]decimal.Decimal, error) {
+	totals := map[string]decimal.Decimal{}
+	for _, p := range r.all() {
+		totals[p.Currency] = totals[p.Currency].Add(p.Amount)
+	}
+
+	return totals, nil
+}
+
+func (r *memoryPostingsRepository) all() []*Posting {
+	ps := []*Posting{}
+	for _, v := range r.tx.list(memPostings) {
+		p := v.(Posting)
+		ps = append(ps, &p)
+	}
+	return ps
+}
+
+type memoryFXQuotesRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryFXQuotesRepository) Create(ctx context.Context, q *FXQuote) (*FXQuote, error) {
+	now := time.Now()
+	q.ID = uint(r.tx.nextID(memFXQuotes))
+	q.CreatedAt, q.UpdatedAt = now, now
+
+	r.tx.put(memFXQuotes, int64(q.ID), *q)
+	return q, nil
+}
+
+func (r *memoryFXQuotesRepository) Update(ctx context.Context, q *FXQuote) (*FXQuote, error) {
+	q.UpdatedAt = time.Now()
+	r.tx.put(memFXQuotes, int64(q.ID), *q)
+	return q, nil
+}
+
+func (r *memoryFXQuotesRepository) Get(ctx context.Context, id int64) (*FXQuote, error) {
+	v, ok := r.tx.get(memFXQuotes, id)
+	if !ok {
+		return nil, ErrFXQuoteNotFound
+	}
+
+	q := v.(FXQuote)
+	return &q, nil
+}
+
+type memoryStatusChangesRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryStatusChangesRepository) Create(ctx context.Context, c *AccountStatusChange) (*AccountStatusChange, error) {
+	now := time.Now()
+	c.ID = uint(r.tx.nextID(memStatusChanges))
+	c.CreatedAt, c.UpdatedAt = now, now
+
+	r.tx.put(memStatusChanges, int64(c.ID), *c)
+	return c, nil
+}
+
+func (r *memoryStatusChangesRepository) GetByAccID(ctx context.Context, accID int64) ([]*AccountStatusChange, error) {
+	cs := []*AccountStatusChange{}
+	for _, v := range r.tx.list(memStatusChanges) {
+		c := v.(AccountStatusChange)
+		if c.AccountID == accID {
+			cs = append(cs, &c)
+		}
+	}
+
+	return cs, nil
+}
+
+type memorySchedulesRepository struct {
+	tx *memoryTx
+}
+
+func (r *memorySchedulesRepository) Create(ctx context.Context, s *Schedule) (*Schedule, error) {
+	now := time.Now()
+	s.ID = uint(r.tx.nextID(memSchedules))
+	s.CreatedAt, s.UpdatedAt = now, now
+
+	r.tx.put(memSchedules, int64(s.ID), *s)
+	return s, nil
+}
+
+func (r *memorySchedulesRepository) Update(ctx context.Context, s *Schedule) (*Schedule, error) {
+	s.UpdatedAt = time.Now()
+	r.tx.put(memSchedules, int64(s.ID), *s)
+	return s, nil
+}
+
+func (r *memorySchedulesRepository) Get(ctx context.Context, id int64) (*Schedule, error) {
+	v, ok := r.tx.get(memSchedules, id)
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+
+	s := v.(Schedule)
+	return &s, nil
+}
+
+func (r *memorySchedulesRepository) GetByAccID(ctx context.Context, accID int64) ([]*Schedule, error) {
+	return r.find(func(s *Schedule) bool { return s.From == accID }), nil
+}
+
+func (r *memorySchedulesRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*Schedule, error) {
+	ss := r.find(func(s *Schedule) bool { return s.Status == ScheduleStatusActive && !s.NextAttemptAt.After(now) })
+	sort.SliceStable(ss, func(i, j int) bool { return ss[i].NextAttemptAt.Before(ss[j].NextAttemptAt) })
+	if len(ss) > limit {
+		ss = ss[:limit]
+	}
+
+	return ss, nil
+}
+
+func (r *memorySchedulesRepository) find(match func(s *Schedule) bool) []*Schedule {
+	ss := []*Schedule{}
+	for _, v := range r.tx.list(memSchedules) {
+		s := v.(Schedule)
+		if match(&s) {
+			ss = append(ss, &s)
+		}
+	}
+	return ss
+}
+
+type memoryScheduleAttemptsRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryScheduleAttemptsRepository) Create(ctx context.Context, a *ScheduleAttempt) (*ScheduleAttempt, error) {
+	now := time.Now()
+	a.ID = uint(r.tx.nextID(memScheduleAttempts))
+	a.CreatedAt, a.UpdatedAt = now, now
+
+	r.tx.put(memScheduleAttempts, int64(a.ID), *a)
+	return a, nil
+}
+
+func (r *memoryScheduleAttemptsRepository) GetByScheduleID(ctx context.Context, id int64) ([]*ScheduleAttempt, error) {
+	as := []*ScheduleAttempt{}
+	for _, v := range r.tx.list(memScheduleAttempts) {
+		a := v.(ScheduleAttempt)
+		if int64(a.ScheduleID) == id {
+			as = append(as, &a)
+		}
+	}
+
+	return as, nil
+}
+
+type memoryEventsRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryEventsRepository) Create(ctx context.Context, e *Event) (*Event, error) {
+	e.ID = uint(r.tx.nextID(memEvents))
+	e.CreatedAt = time.Now()
+
+	r.tx.put(memEvents, int64(e.ID), *e)
+	return e, nil
+}
+
+func (r *memoryEventsRepository) GetPending(ctx context.Context, afterID uint, limit int) ([]*Event, error) {
+	es := []*Event{}
+	for _, v := range r.tx.list(memEvents) {
+		e := v.(Event)
+		if e.PublishedAt == nil && e.ID > afterID {
+			es = append(es, &e)
+		}
+		if len(es) == limit {
+			break
+		}
+	}
+
+	return es, nil
+}
+
+func (r *memoryEventsRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	v, ok := r.tx.get(memEvents, int64(id))
+	if !ok {
+		return nil
+	}
+
+	e := v.(Event)
+	e.PublishedAt = &at
+	r.tx.put(memEvents, int64(id), e)
+	return nil
+}
+
+type memoryWebhooksRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryWebhooksRepository) Create(ctx context.Context, w *Webhook) (*Webhook, error) {
+	now := time.Now()
+	w.ID = uint(r.tx.nextID(memWebhooks))
+	w.CreatedAt, w.UpdatedAt = now, now
+
+	r.tx.put(memWebhooks, int64(w.ID), *copyWebhook(w))
+	return w, nil
+}
+
+func (r *memoryWebhooksRepository) Update(ctx context.Context, w *Webhook) (*Webhook, error) {
+	w.UpdatedAt = time.Now()
+	r.tx.put(memWebhooks, int64(w.ID), *copyWebhook(w))
+	return w, nil
+}
+
+func (r *memoryWebhooksRepository) Delete(ctx context.Context, id int64) error {
+	w, err := r.Get(ctx, id)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	w.DeletedAt = &now
+	r.tx.put(memWebhooks, id, *w)
+	return nil
+}
+
+func (r *memoryWebhooksRepository) Get(ctx context.Context, id int64) (*Webhook, error) {
+	v, ok := r.tx.get(memWebhooks, id)
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+
+	w := v.(Webhook)
+	if w.DeletedAt != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return copyWebhook(&w), nil
+}
+
+func (r *memoryWebhooksRepository) GetAll(ctx context.Context) ([]*Webhook, error) {
+	ws := []*Webhook{}
+	for _, v := range r.tx.list(memWebhooks) {
+		w := v.(Webhook)
+		if w.DeletedAt == nil {
+			ws = append(ws, copyWebhook(&w))
+		}
+	}
+
+	return ws, nil
+}
+
+// copyWebhook copies the webhook with its filters
+func copyWebhook(w *Webhook) *Webhook {
+	c := *w
+	c.Events = append(EventTypes{}, w.Events...)
+	c.Accounts = append(AccountIDs{}, w.Accounts...)
+	return &c
+}
+
+type memoryWebhookDeliveriesRepository struct {
+	tx *memoryTx
+}
+
+func (r *memoryWebhookDeliveriesRepository) Create(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error) {
+	if _, err := r.GetByEvent(ctx, d.WebhookID, d.EventID); err == nil {
+		return nil, ErrMemoryUniqueViolation
+	}
+
+	now := time.Now()
+	d.ID = uint(r.tx.nextID(memWebhookDeliveries))
+	d.CreatedAt, d.UpdatedAt = now, now
+
+	r.tx.put(memWebhookDeliveries, int64(d.ID), *d)
+	return d, nil
+}
+
+func (r *memoryWebhookDeliveriesRepository) Update(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error) {
+	d.UpdatedAt = time.Now()
+	r.tx.put(memWebhookDeliveries, int64(d.ID), *d)
+	return d, nil
+}
+
+func (r *memoryWebhookDeliveriesRepository) Get(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	v, ok := r.tx.get(memWebhookDeliveries, id)
+	if !ok {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	d := v.(WebhookDelivery)
+	return &d, nil
+}
+
+func (r *memoryWebhookDeliveriesRepository) GetByEvent(ctx context.Context, webhookID, eventID uint) (*WebhookDelivery, error) {
+	ds := r.find(func(d *WebhookDelivery) bool { return d.WebhookID == webhookID && d.EventID == eventID })
+	if len(ds) == 0 {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	return ds[0], nil
+}
+
+func (r *memoryWebhookDeliveriesRepository) GetByWebhookID(ctx context.Context, webhookID int64) ([]*WebhookDelivery, error) {
+	ds := r.find(func(d *WebhookDelivery) bool { return int64(d.WebhookID) == webhookID })
+	for i, j := 0, len(ds)-1; i < j; i, j = i+1, j-1 {
+		ds[i], ds[j] = ds[j], ds[i]
+	}
+
+	return ds, nil
+}
+
+func (r *memoryWebhookDeliveriesRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	ds := r.find(func(d *WebhookDelivery) bool {
+		return d.Status == WebhookDeliveryPending && !d.NextAttemptAt.After(now)
+	})
+	sort.SliceStable(ds, func(i, j int) bool { return ds[i].NextAttemptAt.Before(ds[j].NextAttemptAt) })
+	if len(ds) > limit {
+		ds = ds[:limit]
+	}
+
+	return ds, nil
+}
+
+func (r *memoryWebhookDeliveriesRepository) find(match func(d *WebhookDelivery) bool) []*WebhookDelivery {
+	ds := []*WebhookDelivery{}
+	for _, v := range r.tx.list(memWebhookDeliveries) {
+		d := v.(WebhookDelivery)
+		if match(&d) {
+			ds = append(ds, &d)
+		}
+	}
+	return ds
+}
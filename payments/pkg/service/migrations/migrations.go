@@ -0,0 +1,300 @@
+// Package migrations keeps the versioned schema of the payments database.
+//
+// Migrations are SQL scripts of every supported dialect compiled into the binary.
+// Applied versions are recorded in the schema_migrations table, every migration
+// is applied in its own transaction together with its record.
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownDialect is returned for databases without migrations
+	ErrUnknownDialect = errors.New("dialect has no migrations")
+	// ErrNotMigrated is returned when the database has pending migrations
+	ErrNotMigrated = errors.New("schema is not migrated, run `payments migrate up`")
+	// ErrUnknownVersion is returned when the database is migrated by a newer release
+	ErrUnknownVersion = errors.New("schema has versions unknown to the release")
+)
+
+// Migration is a versioned change of the schema
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration with the time it was applied at. AppliedAt is nil for pending migrations.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a record of the applied migration
+type schemaMigration struct {
+	Version   uint `gorm:"primary_key;auto_increment:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// dialects are migrations of the supported dialects in the order of versions
+var dialects = map[string][]Migration{
+	"postgres": postgres,
+	"sqlite3":  sqlite,
+}
+
+// Migrator applies and reverts migrations of the database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns the migrator of the database with migrations of its dialect
+func New(db *gorm.DB) (*Migrator, error) {
+	name := db.Dialect().GetName()
+	ms, ok := dialects[name]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownDialect, name)
+	}
+
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+// Up applies all pending migrations of the database and returns them
+func Up(db *gorm.DB) ([]Migration, error) {
+	m, err := New(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Up()
+}
+
+// Check returns ErrNotMigrated if the database has pending migrations
+// and ErrUnknownVersion if it's migrated by a newer release.
+func Check(db *gorm.DB) error {
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+
+	ss, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range ss {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	if pending > 0 {
+		return errors.Wrapf(ErrNotMigrated, "%d pending migrations", pending)
+	}
+
+	return nil
+}
+
+// Status returns all migrations of the dialect with times they were applied at
+func (m *Migrator) Status() ([]*Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	ss := make([]*Status, len(m.migrations))
+	for i, mg := range m.migrations {
+		ss[i] = &Status{Migration: mg}
+		if r, ok := applied[mg.Version]; ok {
+			appliedAt := r.AppliedAt
+			ss[i].AppliedAt = &appliedAt
+			delete(applied, mg.Version)
+		}
+	}
+
+	if len(applied) > 0 {
+		return nil, errors.Wrapf(ErrUnknownVersion, "%d unknown versions", len(applied))
+	}
+
+	return ss, nil
+}
+
+// Up applies all pending migrations and returns them
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.db.Exec(createTable).Error; err != nil {
+		return nil, errors.Wrap(err, "schema_migrations table creating failed")
+	}
+
+	ss, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, s := range ss {
+		if s.AppliedAt != nil {
+			continue
+		}
+
+		err := m.apply(s.Migration.Up, func(tx *gorm.DB) error {
+			return tx.Create(&schemaMigration{Version: s.Version, Name: s.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return done, errors.Wrapf(err, "migration %d_%s applying failed", s.Version, s.Name)
+		}
+
+		done = append(done, s.Migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the given number of the latest applied migrations and returns them
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	ss, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for i := len(ss) - 1; i >= 0 && len(done) < steps; i-- {
+		s := ss[i]
+		if s.AppliedAt == nil {
+			continue
+		}
+
+		err := m.apply(s.Migration.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&schemaMigration{Version: s.Version}).Error
+		})
+		if err != nil {
+			return done, errors.Wrapf(err, "migration %d_%s reverting failed", s.Version, s.Name)
+		}
+
+		done = append(done, s.Migration)
+	}
+
+	return done, nil
+}
+
+// apply executes the script and records it in the same transaction
+func (m *Migrator) apply(script string, record func(tx *gorm.DB) error) error {
+	tx := m.db.Begin()
+	if err := tx.Error; err != nil {
+		return err
+	}
+
+	// Scripts of some dialects have no changes for the version
+	if strings.TrimSpace(script) != "" {
+		if err := tx.Exec(script).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// applied returns records of applied migrations by their versions
+func (m *Migrator) applied() (map[uint]schemaMigration, error) {
+	res := map[uint]schemaMigration{}
+	if !m.db.HasTable(&schemaMigration{}) {
+		return res, nil
+	}
+
+	rs := []schemaMigration{}
+	if err := m.db.Order("version").Find(&rs).Error; err != nil {
+		return nil, errors.Wrap(err, "applied migrations getting failed")
+	}
+
+	for _, r := range rs {
+		res[r.Version] = r
+	}
+
+	return res, nil
+}
+
+// createTable is the same for all dialects
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	applied_at timestamp NOT NULL
+)`
+
+// ledgerOpeningsUp writes opening postings of balances which aren't covered by postings, like balances
+// of accounts created before the ledger. The world account of the currency takes the offsetting posting
+// and is created if it's missing. Opening postings belong to no operation.
+const ledgerOpeningsUp = `
+	CREATE TEMPORARY TABLE ledger_openings AS
+	SELECT a.id AS account_id, a.currency, a.amount AS balance,
+		ROUND(a.amount - COALESCE((SELECT SUM(p.amount) FROM postings p WHERE p.account_id = a.id), 0), 8) AS amount
+	FROM accounts a WHERE COALESCE(a.role, '') <> 'world';
+	DELETE FROM ledger_openings WHERE amount = 0;
+
+	INSERT INTO accounts (name, currency, amount, held, role, status, created_at, updated_at)
+	SELECT DISTINCT 'world', o.currency, 0, 0, 'world', 'active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+	FROM ledger_openings o WHERE NOT EXISTS (
+		SELECT 1 FROM accounts w WHERE w.role = 'world' AND w.currency = o.currency AND w.deleted_at IS NULL);
+
+	INSERT INTO postings (created_at, operation_id, transaction_id, account_id, currency, amount, balance)
+	SELECT CURRENT_TIMESTAMP, 0, 0, o.account_id, o.currency, o.amount, o.balance
+	FROM ledger_openings o ORDER BY o.account_id;
+
+	INSERT INTO postings (created_at, operation_id, transaction_id, account_id, currency, amount, balance)
+	SELECT CURRENT_TIMESTAMP, 0, 0, w.id, o.currency, -o.amount,
+		ROUND(w.amount - (SELECT SUM(o2.amount) FROM ledger_openings o2 WHERE o2.currency = o.currency AND o2.account_id <= o.account_id), 8)
+	FROM ledger_openings o, accounts w
+	WHERE w.role = 'world' AND w.currency = o.currency AND w.deleted_at IS NULL
+	ORDER BY o.currency, o.account_id;
+
+	UPDATE accounts SET amount = amount - (SELECT SUM(o.amount) FROM ledger_openings o WHERE o.currency = accounts.currency)
+	WHERE role = 'world' AND deleted_at IS NULL AND currency IN (SELECT currency FROM ledger_openings);
+
+	DROP TABLE ledger_openings;`
+
+// ledgerOpeningsDown removes opening postings and returns their money to world accounts.
+// Created world accounts are kept.
+const ledgerOpeningsDown = `
+	UPDATE accounts SET amount = amount - (
+		SELECT SUM(p.amount) FROM postings p WHERE p.account_id = accounts.id AND p.operation_id = 0 AND p.transaction_id = 0)
+	WHERE role = 'world' AND id IN (SELECT account_id FROM postings WHERE operation_id = 0 AND transaction_id = 0);
+
+	DELETE FROM postings WHERE operation_id = 0 AND transaction_id = 0;`
+
+// legacyWorldUp replaces the id of the outside world used by operations created before system accounts
+// with the world account of the currency, the world account is created if it's missing.
+const legacyWorldUp = `
+	INSERT INTO accounts (name, currency, amount, held, role, status, created_at, updated_at)
+	SELECT DISTINCT 'world', t.currency, 0, 0, 'world', 'active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+	FROM transactions t WHERE (t."from" = -1 OR t."to" = -1) AND NOT EXISTS (
+		SELECT 1 FROM accounts w WHERE w.role = 'world' AND w.currency = t.currency AND w.deleted_at IS NULL);
+
+	UPDATE operation_participants SET account_id = (
+		SELECT MIN(w.id) FROM transactions t, accounts w
+		WHERE t.operation_id = operation_participants.operation_id AND (t."from" = -1 OR t."to" = -1)
+			AND w.role = 'world' AND w.currency = t.currency AND w.deleted_at IS NULL)
+	WHERE account_id = -1 AND operation_id IN (SELECT operation_id FROM transactions WHERE "from" = -1 OR "to" = -1);
+
+	UPDATE transactions SET "from" = (
+		SELECT MIN(w.id) FROM accounts w WHERE w.role = 'world' AND w.currency = transactions.currency AND w.deleted_at IS NULL)
+	WHERE "from" = -1;
+
+	UPDATE transactions SET "to" = (
+		SELECT MIN(w.id) FROM accounts w WHERE w.role = 'world' AND w.currency = transactions.currency AND w.deleted_at IS NULL)
+	WHERE "to" = -1;`
@@ -0,0 +1,415 @@
+package migrations_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+	"github.com/deterok/go_test_task/payments/pkg/service/migrations"
+)
+
+// models are all tables of the service
+var models = []interface{}{
+	service.Account{},
+	service.Operation{},
+	service.OperationParticipant{},
+	service.Transaction{},
+	service.Hold{},
+	service.Posting{},
+	service.FXQuote{},
+	service.AccountStatusChange{},
+	service.Schedule{},
+	service.ScheduleAttempt{},
+	service.Event{},
+	service.Webhook{},
+	service.WebhookDelivery{},
+}
+
+// getSQLite returns a new sqlite database in a temporary directory and the function which removes it
+func getSQLite(t *testing.T) (*gorm.DB, func()) {
+	dir, err := ioutil.TempDir("", "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open("sqlite3", "file:"+filepath.Join(dir, "test.db")+"?_txlock=immediate&_busy_timeout=10000")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	db.LogMode(false)
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func versions(ms []migrations.Migration) []uint {
+	res := []uint{}
+	for _, m := range ms {
+		res = append(res, m.Version)
+	}
+	return res
+}
+
+func TestMigrator(t *testing.T) {
+	db, remove := getSQLite(t)
+	defer remove()
+
+	assert.Equal(t, migrations.ErrNotMigrated, errors.Cause(migrations.Check(db)))
+
+	m, err := migrations.New(db)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	ms, err := m.Up()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []uint{1, 2, 3, 4, 5, 6}, versions(ms))
+	assert.NoError(t, migrations.Check(db))
+	assert.True(t, db.HasTable(&service.Account{}))
+
+	ss, err := m.Status()
+	assert.NoError(t, err)
+	for _, s := range ss {
+		assert.NotNil(t, s.AppliedAt, "migration %d", s.Version)
+	}
+
+	// Applied migrations are skipped
+	ms, err = m.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, ms)
+
+	ms, err = m.Down(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{6, 5, 4}, versions(ms))
+	assert.Equal(t, migrations.ErrNotMigrated, errors.Cause(migrations.Check(db)))
+	assert.False(t, db.Dialect().HasColumn("accounts", "fence"))
+
+	// The accounts table is copied without the column, but keeps its triggers
+	triggers := 0
+	assert.NoError(t, db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND tbl_name = 'accounts'").Row().Scan(&triggers))
+	assert.Equal(t, 2, triggers)
+
+	ss, err = m.Status()
+	assert.NoError(t, err)
+	assert.NotNil(t, ss[2].AppliedAt)
+	assert.Nil(t, ss[3].AppliedAt)
+	assert.Nil(t, ss[4].AppliedAt)
+	assert.Nil(t, ss[5].AppliedAt)
+
+	ms, err = m.Down(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 2, 1}, versions(ms))
+	assert.False(t, db.HasTable(&service.Account{}))
+
+	ms, err = m.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3, 4, 5, 6}, versions(ms))
+}
+
+func TestMigrator_unknownVersion(t *testing.T) {
+	db, remove := getSQLite(t)
+	defer remove()
+
+	_, err := migrations.Up(db)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The schema is migrated by a newer release
+	assert.NoError(t, db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (1000, 'newer', CURRENT_TIMESTAMP)").Error)
+
+	assert.Equal(t, migrations.ErrUnknownVersion, errors.Cause(migrations.Check(db)))
+	_, err = migrations.Up(db)
+	assert.Equal(t, migrations.ErrUnknownVersion, errors.Cause(err))
+}
+
+// Migrations create the schema of the models
+func TestMigrator_models(t *testing.T) {
+	migrated, remove := getSQLite(t)
+	defer remove()
+
+	_, err := migrations.Up(migrated)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	auto, remove := getSQLite(t)
+	defer remove()
+
+	if !assert.NoError(t, auto.AutoMigrate(models...).Error) {
+		t.FailNow()
+	}
+
+	for _, model := range models {
+		table := auto.NewScope(model).TableName()
+		assert.Equal(t, columns(t, auto, table), columns(t, migrated, table), table)
+		assert.Equal(t, indexes(t, auto, table), indexes(t, migrated, table), table)
+	}
+}
+
+func TestMigrator_accountsAmountNonNegative(t *testing.T) {
+	db, remove := getSQLite(t)
+	defer remove()
+
+	_, err := migrations.Up(db)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	user := "INSERT INTO accounts (name, currency, amount, held, role) VALUES ('user', 'USD', ?, 0, '')"
+	assert.Error(t, db.Exec(user, "-0.01").Error)
+	assert.NoError(t, db.Exec(user, "0").Error)
+	assert.Error(t, db.Exec("UPDATE accounts SET amount = ? WHERE role = ''", "-1").Error)
+
+	world := "INSERT INTO accounts (name, currency, amount, held, role) VALUES ('world', 'USD', ?, 0, 'world')"
+	assert.NoError(t, db.Exec(world, "-10").Error)
+}
+
+// Balances without postings get opening postings against the world account
+func TestMigrator_ledgerOpenings(t *testing.T) {
+	db, remove := getSQLite(t)
+	defer remove()
+
+	m, err := migrations.New(db)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = m.Up()
+	assert.NoError(t, err)
+	_, err = m.Down(2)
+	assert.NoError(t, err)
+
+	account := "INSERT INTO accounts (id, name, currency, amount, held, role) VALUES (?, ?, ?, ?, 0, ?)"
+	posting := "INSERT INTO postings (operation_id, transaction_id, account_id, currency, amount, balance) VALUES (1, 1, ?, ?, ?, ?)"
+	assert.NoError(t, db.Exec(account, 1, "world", "USD", "-5", "world").Error)
+	// Created before the ledger
+	assert.NoError(t, db.Exec(account, 2, "old", "USD", "10", "").Error)
+	assert.NoError(t, db.Exec(account, 3, "old", "EUR", "7.5", "").Error)
+	// Has postings of a part of the balance
+	assert.NoError(t, db.Exec(account, 4, "partial", "USD", "8", "").Error)
+	assert.NoError(t, db.Exec(posting, 4, "USD", "5", "5").Error)
+	assert.NoError(t, db.Exec(posting, 1, "USD", "-5", "-5").Error)
+	// Created after the ledger
+	assert.NoError(t, db.Exec(account, 5, "new", "USD", "0", "").Error)
+
+	ms, err := m.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{5, 6}, versions(ms))
+
+	// The world account of EUR is created
+	world := int64(0)
+	assert.NoError(t, db.Raw("SELECT id FROM accounts WHERE role = 'world' AND currency = 'EUR'").Row().Scan(&world))
+
+	assert.Equal(t, map[string]string{"USD": "0", "EUR": "0"}, ledgerTotals(t, db))
+	assert.Equal(t, map[int64]string{1: "-18", 2: "10", 3: "7.5", 4: "8", 5: "0", world: "-7.5"}, ledgerBalanced(t, db))
+
+	openings := 0
+	assert.NoError(t, db.Raw("SELECT COUNT(*) FROM postings WHERE operation_id = 0").Row().Scan(&openings))
+	assert.Equal(t, 6, openings)
+
+	ms, err = m.Down(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{6, 5}, versions(ms))
+
+	amount := ""
+	assert.NoError(t, db.Raw("SELECT amount FROM accounts WHERE id = 1").Row().Scan(&amount))
+	assert.Equal(t, "-5", amount)
+	assert.NoError(t, db.Raw("SELECT COUNT(*) FROM postings").Row().Scan(&openings))
+	assert.Equal(t, 2, openings)
+}
+
+// Opening postings of accounts with postings keep the balance of the account
+func TestMigrator_ledgerOpeningsPartial(t *testing.T) {
+	db, remove := getSQLite(t)
+	defer remove()
+
+	m, err := migrations.New(db)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = m.Up()
+	assert.NoError(t, err)
+	_, err = m.Down(2)
+	assert.NoError(t, err)
+
+	account := "INSERT INTO accounts (id, name, currency, amount, held, role) VALUES (?, ?, 'USD', ?, 0, ?)"
+	posting := "INSERT INTO postings (operation_id, transaction_id, account_id, currency, amount, balance) VALUES (1, 1, ?, 'USD', ?, ?)"
+	assert.NoError(t, db.Exec(account, 1, "world", "-5", "world").Error)
+	assert.NoError(t, db.Exec(account, 2, "partial", "8", "").Error)
+	assert.NoError(t, db.Exec(posting, 2, "5", "5").Error)
+	assert.NoError(t, db.Exec(posting, 1, "-5", "-5").Error)
+
+	_, err = m.Up()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var amount, balance decimal.Decimal
+	row := db.Raw("SELECT amount, balance FROM postings WHERE operation_id = 0 AND account_id = 2").Row()
+	assert.NoError(t, row.Scan(&amount, &balance))
+	assert.Equal(t, "3", amount.String())
+	assert.Equal(t, "8", balance.String())
+
+	row = db.Raw("SELECT amount, balance FROM postings WHERE operation_id = 0 AND account_id = 1").Row()
+	assert.NoError(t, row.Scan(&amount, &balance))
+	assert.Equal(t, "-3", amount.String())
+	assert.Equal(t, "-8", balance.String())
+
+	assert.Equal(t, map[string]string{"USD": "0"}, ledgerTotals(t, db))
+	assert.Equal(t, map[int64]string{1: "-8", 2: "8"}, ledgerBalanced(t, db))
+}
+
+// Operations of the legacy world id move to world accounts
+func TestMigrator_legacyWorld(t *testing.T) {
+	db, remove := getSQLite(t)
+	defer remove()
+
+	m, err := migrations.New(db)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = m.Up()
+	assert.NoError(t, err)
+	_, err = m.Down(1)
+	assert.NoError(t, err)
+
+	// Deposits of USD and EUR, the USD world account exists
+	assert.NoError(t, db.Exec("INSERT INTO accounts (id, name, currency, amount, held, role) VALUES (1, 'world', 'USD', 0, 0, 'world')").Error)
+	for op, currency := range map[int]string{1: "USD", 2: "EUR"} {
+		assert.NoError(t, db.Exec("INSERT INTO operations (id, type) VALUES (?, 0)", op).Error)
+		assert.NoError(t, db.Exec(`INSERT INTO transactions (operation_id, "from", "to", currency, amount) VALUES (?, -1, 10, ?, 5)`, op, currency).Error)
+		assert.NoError(t, db.Exec("INSERT INTO operation_participants (operation_id, account_id) VALUES (?, -1), (?, 10)", op, op).Error)
+	}
+	// Withdrawal
+	assert.NoError(t, db.Exec("INSERT INTO operations (id, type) VALUES (3, 0)").Error)
+	assert.NoError(t, db.Exec(`INSERT INTO transactions (operation_id, "from", "to", currency, amount) VALUES (3, 10, -1, 'USD', 1)`).Error)
+	assert.NoError(t, db.Exec("INSERT INTO operation_participants (operation_id, account_id) VALUES (3, 10), (3, -1)").Error)
+
+	ms, err := m.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{6}, versions(ms))
+
+	eur := int64(0)
+	assert.NoError(t, db.Raw("SELECT id FROM accounts WHERE role = 'world' AND currency = 'EUR'").Row().Scan(&eur))
+
+	// The EUR world account is created
+	assert.Equal(t, int64(2), eur)
+
+	assert.Equal(t, []string{"1 1 10", "2 2 10", "3 10 1"},
+		values(t, db, `SELECT operation_id || ' ' || "from" || ' ' || "to" FROM transactions ORDER BY operation_id`))
+	assert.Equal(t, []string{"1 1", "1 10", "2 2", "2 10", "3 1", "3 10"},
+		values(t, db, "SELECT operation_id || ' ' || account_id FROM operation_participants ORDER BY operation_id, account_id"))
+}
+
+// values returns the first column of the query rows
+func values(t *testing.T, db *gorm.DB, query string) []string {
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// ledgerTotals returns sums of postings by currencies
+func ledgerTotals(t *testing.T, db *gorm.DB) map[string]string {
+	rows, err := db.Raw("SELECT currency, ROUND(SUM(amount), 8) FROM postings GROUP BY currency").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	res := map[string]string{}
+	for rows.Next() {
+		var currency string
+		var sum decimal.Decimal
+		if err := rows.Scan(&currency, &sum); err != nil {
+			t.Fatal(err)
+		}
+		res[currency] = sum.String()
+	}
+	return res
+}
+
+// ledgerBalanced returns balances of accounts which are equal to sums of their postings
+func ledgerBalanced(t *testing.T, db *gorm.DB) map[int64]string {
+	rows, err := db.Raw(`SELECT a.id, a.amount, ROUND(COALESCE(SUM(p.amount), 0), 8) FROM accounts a
+		LEFT JOIN postings p ON p.account_id = a.id GROUP BY a.id, a.amount`).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	res := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var amount, sum decimal.Decimal
+		if err := rows.Scan(&id, &amount, &sum); err != nil {
+			t.Fatal(err)
+		}
+		if amount.Equal(sum) {
+			res[id] = amount.String()
+		}
+	}
+	return res
+}
+
+// columns returns columns of the table by names, added columns are the last ones in the table
+func columns(t *testing.T, db *gorm.DB, table string) []string {
+	rows, err := db.Raw("SELECT name, type, COALESCE(dflt_value, ''), pk FROM pragma_table_info(?) ORDER BY name", table).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var name, typ, dflt, pk string
+		if err := rows.Scan(&name, &typ, &dflt, &pk); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, name+" "+typ+" "+dflt+" "+pk)
+	}
+	return res
+}
+
+func indexes(t *testing.T, db *gorm.DB, table string) map[string]string {
+	rows, err := db.Raw(`SELECT il.name, il."unique", ii.name FROM pragma_index_list(?) il, pragma_index_info(il.name) ii
+		ORDER BY il.name, ii.seqno`, table).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	res := map[string]string{}
+	for rows.Next() {
+		var name, unique, column string
+		if err := rows.Scan(&name, &unique, &column); err != nil {
+			t.Fatal(err)
+		}
+		res[name] += unique + " " + column + ","
+	}
+	return res
+}
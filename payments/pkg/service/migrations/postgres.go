@@ -0,0 +1,277 @@
+package migrations
+
+// postgres are migrations of the postgres dialect
+var postgres = []Migration{
+	{
+		Version: 1,
+		Name:    "initial",
+		Up:      postgresInitialUp,
+		Down:    initialDown,
+	},
+	{
+		// Participants are stored in the operation_participants table, the array column is left
+		// by releases before it. The column is copied into the table for operations without rows.
+		Version: 2,
+		Name:    "drop_operations_participants",
+		Up: `
+			DO $$
+			BEGIN
+				IF EXISTS (SELECT 1 FROM information_schema.columns
+					WHERE table_schema = current_schema() AND table_name = 'operations' AND column_name = 'participants') THEN
+
+					INSERT INTO operation_participants (operation_id, account_id)
+					SELECT o.id, p.account_id FROM operations o, unnest(o.participants) WITH ORDINALITY AS p(account_id, n)
+					WHERE NOT EXISTS (SELECT 1 FROM operation_participants op WHERE op.operation_id = o.id)
+					ORDER BY o.id, p.n;
+
+					ALTER TABLE operations DROP COLUMN participants;
+				END IF;
+			END
+			$$;`,
+		// The GIN index serves only the containment query of the history, participants @> ARRAY[?]::integer[].
+		// The equivalent ? = ANY(participants) predicate scans the whole table.
+		Down: `
+			ALTER TABLE operations ADD COLUMN participants integer[];
+			UPDATE operations o SET participants = ARRAY(
+				SELECT p.account_id FROM operation_participants p WHERE p.operation_id = o.id ORDER BY p.id);
+			CREATE INDEX idx_operations_participants ON operations USING GIN (participants);`,
+	},
+	{
+		// Only system accounts may have negative balances
+		Version: 3,
+		Name:    "accounts_amount_non_negative",
+		Up: `
+			ALTER TABLE accounts ADD CONSTRAINT accounts_amount_non_negative
+				CHECK (COALESCE(role, '') <> '' OR amount >= 0);`,
+		Down: `
+			ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_amount_non_negative;`,
+	},
+	{
+		// Writes of accounts under a lock are fenced by the token of the lock
+		Version: 4,
+		Name:    "accounts_fence",
+		Up: `
+			ALTER TABLE accounts ADD COLUMN fence bigint NOT NULL DEFAULT 0;`,
+		Down: `
+			ALTER TABLE accounts DROP COLUMN fence;`,
+	},
+	{
+		// Balances of accounts created before the ledger have no postings
+		Version: 5,
+		Name:    "ledger_openings",
+		Up:      ledgerOpeningsUp,
+		Down:    ledgerOpeningsDown,
+	},
+	{
+		// Releases with migrations read only world accounts, so the legacy id isn't restored
+		Version: 6,
+		Name:    "legacy_world",
+		Up:      legacyWorldUp,
+	},
+}
+
+// postgresInitialUp is the schema created by gorm AutoMigrate before migrations.
+// Existing tables and indexes are kept.
+const postgresInitialUp = `
+	CREATE TABLE IF NOT EXISTS "accounts" (
+		"id" bigserial PRIMARY KEY,
+		"name" text,
+		"currency" text,
+		"amount" decimal(20,8),
+		"held" decimal(20,8),
+		"role" text,
+		"status" text DEFAULT 'active',
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone
+	);
+	CREATE INDEX IF NOT EXISTS idx_accounts_role ON "accounts"("role");
+	CREATE INDEX IF NOT EXISTS idx_accounts_status ON "accounts"("status");
+	CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON "accounts"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_accounts_name ON "accounts"("name");
+	CREATE INDEX IF NOT EXISTS idx_accounts_currency ON "accounts"("currency");
+
+	CREATE TABLE IF NOT EXISTS "operations" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"type" integer,
+		"reversal_of" integer,
+		"reason" text,
+		"idempotency_key" text,
+		"request_hash" text,
+		"quote_id" integer,
+		"rate" decimal(20,10)
+	);
+	CREATE INDEX IF NOT EXISTS idx_operations_deleted_at ON "operations"(deleted_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS uix_operations_reversal_of ON "operations"(reversal_of);
+	CREATE UNIQUE INDEX IF NOT EXISTS uix_operations_idempotency_key ON "operations"(idempotency_key);
+	CREATE UNIQUE INDEX IF NOT EXISTS uix_operations_quote_id ON "operations"(quote_id);
+
+	CREATE TABLE IF NOT EXISTS "operation_participants" (
+		"id" serial PRIMARY KEY,
+		"operation_id" integer,
+		"account_id" bigint
+	);
+	CREATE INDEX IF NOT EXISTS idx_operation_participants_operation_id ON "operation_participants"(operation_id);
+	CREATE INDEX IF NOT EXISTS idx_operation_participants_account_id ON "operation_participants"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "transactions" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"operation_id" integer,
+		"from" bigint,
+		"to" bigint,
+		"currency" text,
+		"amount" decimal(20,8)
+	);
+	CREATE INDEX IF NOT EXISTS idx_transactions_operation_id ON "transactions"(operation_id);
+	CREATE INDEX IF NOT EXISTS idx_transactions_from ON "transactions"("from");
+	CREATE INDEX IF NOT EXISTS idx_transactions_to ON "transactions"("to");
+	CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON "transactions"(deleted_at);
+
+	CREATE TABLE IF NOT EXISTS "holds" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"account_id" bigint,
+		"currency" text,
+		"amount" decimal(20,8),
+		"captured" decimal(20,8),
+		"status" integer,
+		"expires_at" timestamp with time zone,
+		"operation_id" integer
+	);
+	CREATE INDEX IF NOT EXISTS idx_holds_deleted_at ON "holds"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_holds_account_id ON "holds"(account_id);
+	CREATE INDEX IF NOT EXISTS idx_holds_status ON "holds"("status");
+	CREATE INDEX IF NOT EXISTS idx_holds_expires_at ON "holds"(expires_at);
+
+	CREATE TABLE IF NOT EXISTS "postings" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"operation_id" integer,
+		"transaction_id" integer,
+		"account_id" bigint,
+		"currency" text,
+		"amount" decimal(20,8),
+		"balance" decimal(20,8)
+	);
+	CREATE INDEX IF NOT EXISTS idx_postings_currency ON "postings"("currency");
+	CREATE INDEX IF NOT EXISTS idx_postings_operation_id ON "postings"(operation_id);
+	CREATE INDEX IF NOT EXISTS idx_postings_transaction_id ON "postings"(transaction_id);
+	CREATE INDEX IF NOT EXISTS idx_postings_account_id ON "postings"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "fx_quotes" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"from" text,
+		"to" text,
+		"rate" decimal(20,10),
+		"expires_at" timestamp with time zone,
+		"operation_id" integer
+	);
+	CREATE INDEX IF NOT EXISTS idx_fx_quotes_deleted_at ON "fx_quotes"(deleted_at);
+
+	CREATE TABLE IF NOT EXISTS "account_status_changes" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"account_id" bigint,
+		"from" text,
+		"to" text,
+		"reason" text,
+		"operation_id" integer
+	);
+	CREATE INDEX IF NOT EXISTS idx_account_status_changes_deleted_at ON "account_status_changes"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_account_status_changes_account_id ON "account_status_changes"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "schedules" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"from" bigint,
+		"to" bigint,
+		"currency" text,
+		"amount" decimal(20,8),
+		"period" text,
+		"every" integer,
+		"start_at" timestamp with time zone,
+		"end_at" timestamp with time zone,
+		"max_retries" integer,
+		"retry_delay" bigint,
+		"status" text,
+		"occurrence" integer,
+		"attempts" integer,
+		"next_attempt_at" timestamp with time zone
+	);
+	CREATE INDEX IF NOT EXISTS idx_schedules_deleted_at ON "schedules"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_schedules_from ON "schedules"("from");
+	CREATE INDEX IF NOT EXISTS idx_schedules_status ON "schedules"("status");
+	CREATE INDEX IF NOT EXISTS idx_schedules_next_attempt_at ON "schedules"(next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS "schedule_attempts" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"schedule_id" integer,
+		"occurrence" integer,
+		"run_at" timestamp with time zone,
+		"operation_id" integer,
+		"error" text
+	);
+	CREATE INDEX IF NOT EXISTS idx_schedule_attempts_deleted_at ON "schedule_attempts"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_schedule_attempts_schedule_id ON "schedule_attempts"(schedule_id);
+
+	CREATE TABLE IF NOT EXISTS "events" (
+		"id" serial PRIMARY KEY,
+		"type" text,
+		"account_id" bigint,
+		"payload" jsonb,
+		"created_at" timestamp with time zone,
+		"published_at" timestamp with time zone
+	);
+	CREATE INDEX IF NOT EXISTS idx_events_published_at ON "events"(published_at);
+	CREATE INDEX IF NOT EXISTS idx_events_account_id ON "events"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "webhooks" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"url" text,
+		"events" text,
+		"accounts" text,
+		"secret" text
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON "webhooks"(deleted_at);
+
+	CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+		"id" serial PRIMARY KEY,
+		"created_at" timestamp with time zone,
+		"updated_at" timestamp with time zone,
+		"deleted_at" timestamp with time zone,
+		"webhook_id" integer,
+		"event_id" integer,
+		"event_type" text,
+		"body" jsonb,
+		"status" text,
+		"attempts" integer,
+		"next_attempt_at" timestamp with time zone,
+		"response_status" integer,
+		"error" text,
+		"delivered_at" timestamp with time zone
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON "webhook_deliveries"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON "webhook_deliveries"("status");
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON "webhook_deliveries"(next_attempt_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_delivery_event ON "webhook_deliveries"(webhook_id, event_id);`
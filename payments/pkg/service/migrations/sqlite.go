@@ -0,0 +1,303 @@
+package migrations
+
+// sqlite are migrations of the sqlite3 dialect
+var sqlite = []Migration{
+	{
+		Version: 1,
+		Name:    "initial",
+		Up:      sqliteInitialUp,
+		Down:    initialDown,
+	},
+	{
+		// The participants array has never been supported by sqlite
+		Version: 2,
+		Name:    "drop_operations_participants",
+	},
+	{
+		// There are no check constraints added to existing tables, triggers guard the balance instead
+		Version: 3,
+		Name:    "accounts_amount_non_negative",
+		Up:      sqliteAccountsTriggers,
+		Down: `
+			DROP TRIGGER IF EXISTS accounts_amount_non_negative_insert;
+			DROP TRIGGER IF EXISTS accounts_amount_non_negative_update;`,
+	},
+	{
+		// Writes of accounts under a lock are fenced by the token of the lock
+		Version: 4,
+		Name:    "accounts_fence",
+		Up: `
+			ALTER TABLE accounts ADD COLUMN "fence" bigint NOT NULL DEFAULT 0;`,
+		// Columns can't be dropped, the table is copied without the column
+		Down: `
+			CREATE TABLE "accounts_down" (
+				"id" integer primary key autoincrement,
+				"name" varchar(255),
+				"currency" varchar(255),
+				"amount" decimal(20,8),
+				"held" decimal(20,8),
+				"role" varchar(255),
+				"status" varchar(255) DEFAULT 'active',
+				"created_at" datetime,
+				"updated_at" datetime,
+				"deleted_at" datetime
+			);
+			INSERT INTO accounts_down (id, name, currency, amount, held, role, status, created_at, updated_at, deleted_at)
+			SELECT id, name, currency, amount, held, role, status, created_at, updated_at, deleted_at FROM accounts;
+			DROP TABLE accounts;
+			ALTER TABLE accounts_down RENAME TO accounts;` + sqliteAccountsIndexes + sqliteAccountsTriggers,
+	},
+	{
+		// Balances of accounts created before the ledger have no postings
+		Version: 5,
+		Name:    "ledger_openings",
+		Up:      ledgerOpeningsUp,
+		Down:    ledgerOpeningsDown,
+	},
+	{
+		// Releases with migrations read only world accounts, so the legacy id isn't restored
+		Version: 6,
+		Name:    "legacy_world",
+		Up:      legacyWorldUp,
+	},
+}
+
+// sqliteInitialUp is the schema created by gorm AutoMigrate before migrations.
+// Existing tables and indexes are kept.
+const sqliteInitialUp = `
+	CREATE TABLE IF NOT EXISTS "accounts" (
+		"id" integer primary key autoincrement,
+		"name" varchar(255),
+		"currency" varchar(255),
+		"amount" decimal(20,8),
+		"held" decimal(20,8),
+		"role" varchar(255),
+		"status" varchar(255) DEFAULT 'active',
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime
+	);
+` + sqliteAccountsIndexes + `
+
+	CREATE TABLE IF NOT EXISTS "operations" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"type" integer,
+		"reversal_of" integer,
+		"reason" varchar(255),
+		"idempotency_key" varchar(255),
+		"request_hash" varchar(255),
+		"quote_id" integer,
+		"rate" decimal(20,10)
+	);
+	CREATE INDEX IF NOT EXISTS idx_operations_deleted_at ON "operations"(deleted_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS uix_operations_reversal_of ON "operations"(reversal_of);
+	CREATE UNIQUE INDEX IF NOT EXISTS uix_operations_idempotency_key ON "operations"(idempotency_key);
+	CREATE UNIQUE INDEX IF NOT EXISTS uix_operations_quote_id ON "operations"(quote_id);
+
+	CREATE TABLE IF NOT EXISTS "operation_participants" (
+		"id" integer primary key autoincrement,
+		"operation_id" integer,
+		"account_id" bigint
+	);
+	CREATE INDEX IF NOT EXISTS idx_operation_participants_operation_id ON "operation_participants"(operation_id);
+	CREATE INDEX IF NOT EXISTS idx_operation_participants_account_id ON "operation_participants"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "transactions" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"operation_id" integer,
+		"from" bigint,
+		"to" bigint,
+		"currency" varchar(255),
+		"amount" decimal(20,8)
+	);
+	CREATE INDEX IF NOT EXISTS idx_transactions_operation_id ON "transactions"(operation_id);
+	CREATE INDEX IF NOT EXISTS idx_transactions_from ON "transactions"("from");
+	CREATE INDEX IF NOT EXISTS idx_transactions_to ON "transactions"("to");
+	CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON "transactions"(deleted_at);
+
+	CREATE TABLE IF NOT EXISTS "holds" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"account_id" bigint,
+		"currency" varchar(255),
+		"amount" decimal(20,8),
+		"captured" decimal(20,8),
+		"status" integer,
+		"expires_at" datetime,
+		"operation_id" integer
+	);
+	CREATE INDEX IF NOT EXISTS idx_holds_deleted_at ON "holds"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_holds_account_id ON "holds"(account_id);
+	CREATE INDEX IF NOT EXISTS idx_holds_status ON "holds"("status");
+	CREATE INDEX IF NOT EXISTS idx_holds_expires_at ON "holds"(expires_at);
+
+	CREATE TABLE IF NOT EXISTS "postings" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"operation_id" integer,
+		"transaction_id" integer,
+		"account_id" bigint,
+		"currency" varchar(255),
+		"amount" decimal(20,8),
+		"balance" decimal(20,8)
+	);
+	CREATE INDEX IF NOT EXISTS idx_postings_currency ON "postings"("currency");
+	CREATE INDEX IF NOT EXISTS idx_postings_operation_id ON "postings"(operation_id);
+	CREATE INDEX IF NOT EXISTS idx_postings_transaction_id ON "postings"(transaction_id);
+	CREATE INDEX IF NOT EXISTS idx_postings_account_id ON "postings"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "fx_quotes" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"from" varchar(255),
+		"to" varchar(255),
+		"rate" decimal(20,10),
+		"expires_at" datetime,
+		"operation_id" integer
+	);
+	CREATE INDEX IF NOT EXISTS idx_fx_quotes_deleted_at ON "fx_quotes"(deleted_at);
+
+	CREATE TABLE IF NOT EXISTS "account_status_changes" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"account_id" bigint,
+		"from" varchar(255),
+		"to" varchar(255),
+		"reason" varchar(255),
+		"operation_id" integer
+	);
+	CREATE INDEX IF NOT EXISTS idx_account_status_changes_deleted_at ON "account_status_changes"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_account_status_changes_account_id ON "account_status_changes"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "schedules" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"from" bigint,
+		"to" bigint,
+		"currency" varchar(255),
+		"amount" decimal(20,8),
+		"period" varchar(255),
+		"every" integer,
+		"start_at" datetime,
+		"end_at" datetime,
+		"max_retries" integer,
+		"retry_delay" bigint,
+		"status" varchar(255),
+		"occurrence" integer,
+		"attempts" integer,
+		"next_attempt_at" datetime
+	);
+	CREATE INDEX IF NOT EXISTS idx_schedules_deleted_at ON "schedules"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_schedules_from ON "schedules"("from");
+	CREATE INDEX IF NOT EXISTS idx_schedules_status ON "schedules"("status");
+	CREATE INDEX IF NOT EXISTS idx_schedules_next_attempt_at ON "schedules"(next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS "schedule_attempts" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"schedule_id" integer,
+		"occurrence" integer,
+		"run_at" datetime,
+		"operation_id" integer,
+		"error" varchar(255)
+	);
+	CREATE INDEX IF NOT EXISTS idx_schedule_attempts_deleted_at ON "schedule_attempts"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_schedule_attempts_schedule_id ON "schedule_attempts"(schedule_id);
+
+	CREATE TABLE IF NOT EXISTS "events" (
+		"id" integer primary key autoincrement,
+		"type" varchar(255),
+		"account_id" bigint,
+		"payload" blob,
+		"created_at" datetime,
+		"published_at" datetime
+	);
+	CREATE INDEX IF NOT EXISTS idx_events_published_at ON "events"(published_at);
+	CREATE INDEX IF NOT EXISTS idx_events_account_id ON "events"(account_id);
+
+	CREATE TABLE IF NOT EXISTS "webhooks" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"url" varchar(255),
+		"events" text,
+		"accounts" text,
+		"secret" varchar(255)
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON "webhooks"(deleted_at);
+
+	CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+		"id" integer primary key autoincrement,
+		"created_at" datetime,
+		"updated_at" datetime,
+		"deleted_at" datetime,
+		"webhook_id" integer,
+		"event_id" integer,
+		"event_type" varchar(255),
+		"body" blob,
+		"status" varchar(255),
+		"attempts" integer,
+		"next_attempt_at" datetime,
+		"response_status" integer,
+		"error" varchar(255),
+		"delivered_at" datetime
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON "webhook_deliveries"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON "webhook_deliveries"("status");
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON "webhook_deliveries"(next_attempt_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_delivery_event ON "webhook_deliveries"(webhook_id, event_id);`
+
+// initialDown drops all tables of the initial schema
+const initialDown = `
+	DROP TABLE IF EXISTS webhook_deliveries;
+	DROP TABLE IF EXISTS webhooks;
+	DROP TABLE IF EXISTS events;
+	DROP TABLE IF EXISTS schedule_attempts;
+	DROP TABLE IF EXISTS schedules;
+	DROP TABLE IF EXISTS account_status_changes;
+	DROP TABLE IF EXISTS fx_quotes;
+	DROP TABLE IF EXISTS postings;
+	DROP TABLE IF EXISTS holds;
+	DROP TABLE IF EXISTS transactions;
+	DROP TABLE IF EXISTS operation_participants;
+	DROP TABLE IF EXISTS operations;
+	DROP TABLE IF EXISTS accounts;`
+
+// sqliteAccountsIndexes are indexes of the accounts table
+const sqliteAccountsIndexes = `
+	CREATE INDEX IF NOT EXISTS idx_accounts_role ON "accounts"("role");
+	CREATE INDEX IF NOT EXISTS idx_accounts_status ON "accounts"("status");
+	CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON "accounts"(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_accounts_name ON "accounts"("name");
+	CREATE INDEX IF NOT EXISTS idx_accounts_currency ON "accounts"("currency");`
+
+// sqliteAccountsTriggers reject negative balances of user accounts
+const sqliteAccountsTriggers = `
+	CREATE TRIGGER accounts_amount_non_negative_insert BEFORE INSERT ON accounts
+	WHEN COALESCE(NEW.role, '') = '' AND NEW.amount < 0
+	BEGIN
+		SELECT RAISE(ABORT, 'accounts_amount_non_negative');
+	END;
+
+	CREATE TRIGGER accounts_amount_non_negative_update BEFORE UPDATE OF amount, role ON accounts
+	WHEN COALESCE(NEW.role, '') = '' AND NEW.amount < 0
+	BEGIN
+		SELECT RAISE(ABORT, 'accounts_amount_non_negative');
+	END;`
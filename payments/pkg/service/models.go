@@ -3,17 +3,49 @@ package service
 import (
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+
+	"github.com/deterok/go_test_task/payments/pkg/service/migrations"
 )
 
-func InitModels(db *gorm.DB) error {
-	err := db.AutoMigrate(
-		Account{},
-		Operation{},
-		Transaction{},
-	).Error
+// InitModels checks the schema is migrated and creates system accounts of the given currencies
+// and of all currencies already used by accounts.
+func InitModels(db *gorm.DB, currencies ...string) error {
+	if err := migrations.Check(db); err != nil {
+		return err
+	}
+
+	// Accounts of earlier releases may have no role
+	used := []string{}
+	if err := db.Model(&Account{}).Where("COALESCE(role, '') = ?", AccountRoleUser).Pluck("DISTINCT currency", &used).Error; err != nil {
+		return errors.Wrap(err, "accounts currencies getting failed")
+	}
+
+	all := append(append([]string{}, currencies...), used...)
+
+	seen := map[string]bool{}
+	for _, c := range all {
+		c = NormalizeCurrency(c)
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+
+		if err := initSystemAccounts(db, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	if err != nil {
-		return errors.Wrap(err, "migration failed")
+// initSystemAccounts creates missing system accounts of the currency
+func initSystemAccounts(db *gorm.DB, currency string) error {
+	for _, role := range SystemAccountRoles {
+		a := Account{}
+		req := db.Where(Account{Role: role, Currency: currency}).Attrs(Account{Name: string(role)})
+		if err := req.FirstOrCreate(&a).Error; err != nil {
+			return errors.Wrapf(err, "%s account (%s) createing failed", role, currency)
+		}
 	}
 
 	return nil
@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/jinzhu/gorm"
-	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 )
@@ -14,6 +15,14 @@ type OperationType int
 const (
 	OperationTypeDeposit OperationType = iota
 	OperationTypeTransfer
+	OperationTypeWithdrawal
+	OperationTypeReversal
+	OperationTypeCapture
+	OperationTypeAdjustment
+	OperationTypeExchange
+	OperationTypeClosure
+	OperationTypeBatch
+	OperationTypeMultiLeg
 )
 
 func (t OperationType) String() string {
@@ -22,46 +31,133 @@ func (t OperationType) String() string {
 		return "Deposit"
 	case OperationTypeTransfer:
 		return "Transfer"
+	case OperationTypeWithdrawal:
+		return "Withdrawal"
+	case OperationTypeReversal:
+		return "Reversal"
+	case OperationTypeCapture:
+		return "Capture"
+	case OperationTypeAdjustment:
+		return "Adjustment"
+	case OperationTypeExchange:
+		return "Exchange"
+	case OperationTypeClosure:
+		return "Closure"
+	case OperationTypeBatch:
+		return "Batch"
+	case OperationTypeMultiLeg:
+		return "MultiLeg"
 	}
 	return ""
 }
 
-var ErrOperationNotFound = errors.Errorf("operation not found")
+var (
+	ErrOperationNotFound        = NewError(ErrorKindNotFound, "operation_not_found", "operation not found")
+	ErrOperationAlreadyReversed = NewError(ErrorKindConflict, "operation_already_reversed", "operation already reversed")
+	ErrOperationNotReversible   = NewError(ErrorKindConflict, "operation_not_reversible", "operation can't be reversed")
+)
+
+// ErrDuplicateIdempotencyKey is returned by repositories when the idempotency key of the created operation
+// is already taken by another operation
+var ErrDuplicateIdempotencyKey = errors.New("idempotency key is already taken")
 
 // Operation is a transactions grouping object
 type Operation struct {
 	gorm.Model
-	Participants pq.Int64Array `gorm:"type:integer[]"`
+	// Participants are the accounts whose history includes the operation
+	Participants []int64 `gorm:"-"`
 	Type         OperationType
 	Transactions []Transaction
+
+	// ParticipantRows store Participants in the database
+	ParticipantRows []OperationParticipant `json:"-"`
+
+	// ReversalOf links a reversal operation with the operation it compensates
+	ReversalOf *uint `gorm:"unique_index"`
+	Reason     string
+
+	// IdempotencyKey is a client key which prevents creating the same operation twice
+	IdempotencyKey *string `gorm:"unique_index"`
+	RequestHash    string  `json:"-"`
+
+	// QuoteID and Rate describe the exchange rate used by an exchange operation
+	QuoteID *uint            `gorm:"unique_index"`
+	Rate    *decimal.Decimal `sql:"type:decimal(20,10);"`
+}
+
+// BeforeCreate writes Participants into rows of the join table
+func (o *Operation) BeforeCreate() error {
+	o.ParticipantRows = make([]OperationParticipant, len(o.Participants))
+	for i, id := range o.Participants {
+		o.ParticipantRows[i] = OperationParticipant{AccountID: id}
+	}
+	return nil
+}
+
+// AfterFind restores Participants from rows of the join table in the order of writing
+func (o *Operation) AfterFind() error {
+	sort.Slice(o.ParticipantRows, func(i, j int) bool { return o.ParticipantRows[i].ID < o.ParticipantRows[j].ID })
+
+	o.Participants = make([]int64, len(o.ParticipantRows))
+	for i, p := range o.ParticipantRows {
+		o.Participants[i] = p.AccountID
+	}
+	return nil
+}
+
+// OperationParticipant links an operation with an account whose history includes it
+type OperationParticipant struct {
+	ID          uint  `gorm:"primary_key"`
+	OperationID uint  `gorm:"index"`
+	AccountID   int64 `gorm:"index"`
 }
 
 // Transaction is an atomic unit account changes
 type Transaction struct {
 	gorm.Model
-	OperationID uint
-	From        int64
-	To          int64
+	OperationID uint  `gorm:"index"`
+	From        int64 `gorm:"index"`
+	To          int64 `gorm:"index"`
 
 	Currency string
 	Amount   decimal.Decimal `sql:"type:decimal(20,8);"`
 }
 
+// OperationsFilter describes the requested part of the operations history of an account.
+// Empty fields don't restrict the history.
+type OperationsFilter struct {
+	Page
+
+	Types        []OperationType
+	CreatedFrom  *time.Time // inclusive
+	CreatedTo    *time.Time // exclusive
+	Counterparty int64
+
+	// MinAmount and MaxAmount restrict amounts of the account transactions
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+}
+
+// OperationsPage is a part of the operations history.
+// NextCursor is empty on the last page.
+type OperationsPage struct {
+	Operations []*Operation `json:"operations"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
 type OperationsRepository interface {
+	// Create saves the operation with its transactions. It returns ErrDuplicateIdempotencyKey
+	// if another operation has the idempotency key, including operations of concurrent units of work.
 	Create(ctx context.Context, o *Operation) (*Operation, error)
 
 	Get(ctx context.Context, id int64) (*Operation, error)
-	GetByAccID(ctx context.Context, id int64) ([]*Operation, error)
+	// GetByAccID returns operations of the account matching the filter. Zero limit returns all operations.
+	GetByAccID(ctx context.Context, id int64, f OperationsFilter) ([]*Operation, error)
+	GetReversal(ctx context.Context, id int64) (*Operation, error)
+	GetByIdempotencyKey(ctx context.Context, key string) (*Operation, error)
 	GetAll(ctx context.Context) ([]*Operation, error)
 }
 
-func InitModel(db gorm.DB) error {
-	if err := db.AutoMigrate(&Account{}).Error; err != nil {
-		return errors.Wrap(err, "migration failed")
-	}
-	return nil
-}
-
 type operationsRepository struct {
 	db *gorm.DB
 }
@@ -70,21 +166,31 @@ func NewOperationsRepository(db *gorm.DB) OperationsRepository {
 	return &operationsRepository{db}
 }
 
-func (r *operationsRepository) Init() error {
-	if err := r.db.AutoMigrate(&Account{}).Error; err != nil {
-		return errors.Wrap(err, "migration failed")
-	}
-	return nil
-}
-
 func (r *operationsRepository) Create(ctx context.Context, o *Operation) (*Operation, error) {
 	if err := r.db.Create(&o).Error; err != nil {
+		if o.IdempotencyKey != nil && isUniqueViolation(errors.Cause(err), "operations", "idempotency_key") {
+			return nil, ErrDuplicateIdempotencyKey
+		}
 		return nil, err
 	}
 
 	return o, nil
 }
 
+// isUniqueViolation reports whether the row was rejected by the unique index of the table column.
+// Errors of drivers are told without importing the drivers: postgres errors have the SQLSTATE
+// and the name of the constraint, sqlite errors have the documented message of the constraint.
+func isUniqueViolation(err error, table, column string) bool {
+	if e, ok := err.(interface{ Get(field byte) string }); ok {
+		return e.Get('C') == pgUniqueViolation && e.Get('n') == "uix_"+table+"_"+column
+	}
+
+	return err.Error() == "UNIQUE constraint failed: "+table+"."+column
+}
+
+// pgUniqueViolation is the SQLSTATE of unique violations of postgres
+const pgUniqueViolation = "23505"
+
 func (r *operationsRepository) Get(ctx context.Context, id int64) (*Operation, error) {
 	op := Operation{}
 	if err := r.db.Set("gorm:auto_preload", true).Find(&op, id).Error; err != nil {
@@ -98,10 +204,50 @@ func (r *operationsRepository) Get(ctx context.Context, id int64) (*Operation, e
 	return &op, nil
 }
 
-func (r *operationsRepository) GetByAccID(ctx context.Context, id int64) ([]*Operation, error) {
+func (r *operationsRepository) GetByAccID(ctx context.Context, id int64, f OperationsFilter) ([]*Operation, error) {
 	o := []*Operation{}
 
-	req := r.db.Set("gorm:auto_preload", true).Where("(?) = ANY(participants)", id)
+	after, err := DecodeCursor(f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := r.db.Model(&OperationParticipant{}).Select("operation_id").Where("account_id = ?", id)
+	req := r.db.Set("gorm:auto_preload", true).Where("id IN (?)", participants.QueryExpr())
+
+	if len(f.Types) > 0 {
+		req = req.Where("type IN (?)", f.Types)
+	}
+	if f.CreatedFrom != nil {
+		req = req.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		req = req.Where("created_at < ?", *f.CreatedTo)
+	}
+
+	if f.Counterparty != 0 || f.MinAmount != nil || f.MaxAmount != nil {
+		txs := r.db.Table("transactions").Select("operation_id").Where(`("from" = ? OR "to" = ?)`, id, id)
+		if f.Counterparty != 0 {
+			txs = txs.Where(`("from" = ? OR "to" = ?)`, f.Counterparty, f.Counterparty)
+		}
+		if f.MinAmount != nil {
+			txs = txs.Where("amount >= ?", *f.MinAmount)
+		}
+		if f.MaxAmount != nil {
+			txs = txs.Where("amount <= ?", *f.MaxAmount)
+		}
+		req = req.Where("id IN (?)", txs.QueryExpr())
+	}
+
+	cond, order := f.cursorCond()
+	if after != 0 {
+		req = req.Where(cond, after)
+	}
+	req = req.Order(order)
+
+	if f.Limit > 0 {
+		req = req.Limit(f.Limit)
+	}
 
 	if err := req.Find(&o).Error; err != nil {
 		if gorm.IsRecordNotFoundError(err) {
@@ -114,6 +260,32 @@ func (r *operationsRepository) GetByAccID(ctx context.Context, id int64) ([]*Ope
 	return o, nil
 }
 
+func (r *operationsRepository) GetReversal(ctx context.Context, id int64) (*Operation, error) {
+	op := Operation{}
+	if err := r.db.Set("gorm:auto_preload", true).Where("reversal_of = ?", id).First(&op).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrOperationNotFound
+		}
+
+		return nil, err
+	}
+
+	return &op, nil
+}
+
+func (r *operationsRepository) GetByIdempotencyKey(ctx context.Context, key string) (*Operation, error) {
+	op := Operation{}
+	if err := r.db.Set("gorm:auto_preload", true).Where("idempotency_key = ?", key).First(&op).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrOperationNotFound
+		}
+
+		return nil, err
+	}
+
+	return &op, nil
+}
+
 func (r *operationsRepository) GetAll(ctx context.Context) ([]*Operation, error) {
 	ops := []*Operation{}
 	if err := r.db.Set("gorm:auto_preload", true).Find(&ops).Error; err != nil {
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// outboxLockKey guards the outbox, so events are relayed by one replica at a time
+	outboxLockKey = "outbox"
+	// pendingEventsLimit is the biggest number of events read from the outbox at once
+	pendingEventsLimit = 100
+)
+
+// EventType is the name of a domain event
+type EventType string
+
+const (
+	EventAccountCreated       EventType = "AccountCreated"
+	EventAccountStatusChanged EventType = "AccountStatusChanged"
+	EventOperationCompleted   EventType = "OperationCompleted"
+)
+
+// Event is a domain event stored in the outbox. Events are written in the same transaction as
+// the changes they describe and are published after the commit by the Relay.
+type Event struct {
+	ID   uint      `gorm:"primary_key" json:"id"`
+	Type EventType `json:"type"`
+	// AccountID is the account the event belongs to. Events of an account are published in order.
+	// An operation produces an event for every account it touches.
+	AccountID int64           `gorm:"index" json:"account_id"`
+	Payload   json.RawMessage `json:"payload"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `gorm:"index" json:"-"`
+}
+
+// emit writes the event into the outbox of the unit of work
+func emit(ctx context.Context, uow UOWPayments, t EventType, accID int64, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "event (%s) payload encoding failed", t)
+	}
+
+	e := &Event{Type: t, AccountID: accID, Payload: data}
+	if _, err := uow.Events().Create(ctx, e); err != nil {
+		return errors.Wrapf(err, "event (%s) createing failed", t)
+	}
+
+	return nil
+}
+
+// EventsRepository describes interaction with a repository that can saves and stores Events.
+type EventsRepository interface {
+	Create(ctx context.Context, e *Event) (*Event, error)
+
+	// GetPending returns unpublished events with ids greater than afterID, the oldest first
+	GetPending(ctx context.Context, afterID uint, limit int) ([]*Event, error)
+	MarkPublished(ctx context.Context, id uint, at time.Time) error
+}
+
+// Relay delivers events from the outbox to the publisher
+type Relay interface {
+	// Relay publishes all pending events and returns the number of published ones.
+	// Events are delivered at least once: an event is published again if its publishing
+	// wasn't recorded. A failed event holds back later events of its account until it's published.
+	Relay(ctx context.Context) (int, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type eventsRepository struct {
+	db *gorm.DB
+}
+
+func NewEventsRepository(db *gorm.DB) EventsRepository {
+	return &eventsRepository{db}
+}
+
+func (r *eventsRepository) Create(ctx context.Context, e *Event) (*Event, error) {
+	if err := r.db.Create(e).Error; err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *eventsRepository) GetPending(ctx context.Context, afterID uint, limit int) ([]*Event, error) {
+	es := []*Event{}
+
+	req := r.db.Where("published_at IS NULL AND id > ?", afterID).Order("id").Limit(limit)
+	if err := req.Find(&es).Error; err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+func (r *eventsRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	return r.db.Model(&Event{ID: id}).UpdateColumn("published_at", at).Error
+}
+
+type relay struct {
+	lockf LockFactory
+	uowf  UOWPaymentsFactory
+	pub   Publisher
+}
+
+// NewRelay returns a Relay which reads events from the same storage as PaymentsService
+func NewRelay(lockf LockFactory, uowf UOWPaymentsFactory, pub Publisher) Relay {
+	return &relay{
+		lockf: lockf,
+		uowf:  uowf,
+		pub:   pub,
+	}
+}
+
+func (r *relay) Relay(ctx context.Context) (int, error) {
+	lock := r.lockf.Make(outboxLockKey)
+	if err := lock.LockContext(ctx); err != nil {
+		return 0, errors.Wrap(err, "mutex (outbox) locking failed")
+	}
+	defer lock.Unlock()
+
+	var (
+		published int
+		lastID    uint
+		failed    error
+		blocked   = map[int64]bool{}
+	)
+	for {
+		es, err := r.pending(ctx, lastID)
+		if err != nil {
+			return published, err
+		}
+
+		if len(es) == 0 {
+			return published, failed
+		}
+
+		for _, e := range es {
+			lastID = e.ID
+			if blocked[e.AccountID] {
+				continue
+			}
+
+			if err := r.pub.Publish(ctx, e); err != nil {
+				blocked[e.AccountID] = true
+				failed = errors.Wrapf(err, "event (%d) publishing failed", e.ID)
+				continue
+			}
+
+			if err := r.markPublished(ctx, e.ID); err != nil {
+				return published, err
+			}
+			published++
+		}
+	}
+}
+
+func (r *relay) pending(ctx context.Context, afterID uint) ([]*Event, error) {
+	uow, err := r.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	es, err := uow.Events().GetPending(ctx, afterID, pendingEventsLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "pending events getting failed")
+	}
+
+	return es, nil
+}
+
+func (r *relay) markPublished(ctx context.Context, id uint) error {
+	uow, err := r.uowf.Make()
+	if err != nil {
+		return errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if err := uow.Events().MarkPublished(ctx, id, time.Now()); err != nil {
+		uow.Revert()
+		return errors.Wrapf(err, "event (%d) update failed", id)
+	}
+
+	return nil
+}
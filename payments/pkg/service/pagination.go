@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+const (
+	// DefaultPageLimit is used when a page is requested without explicit limit
+	DefaultPageLimit = 50
+	// MaxPageLimit is the biggest page size
+	MaxPageLimit = 500
+)
+
+var ErrInvalidCursor = NewError(ErrorKindValidation, "invalid_cursor", "cursor is invalid")
+
+// SortOrder is the direction of the list ordering
+type SortOrder string
+
+const (
+	SortOrderAsc  SortOrder = "asc"
+	SortOrderDesc SortOrder = "desc"
+)
+
+// Page describes the requested part of the list. Lists are ordered by id,
+// the cursor points to the last item of the previous page.
+type Page struct {
+	Cursor string
+	Limit  int
+	Order  SortOrder
+}
+
+// EncodeCursor returns the opaque cursor pointing to the item with the id
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodeCursor returns the id of the item the cursor points to. Empty cursor points to the beginning of the list.
+func DecodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+// normalize fills the defaults of the page
+func (p Page) normalize() Page {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.Order != SortOrderDesc {
+		p.Order = SortOrderAsc
+	}
+	return p
+}
+
+// cursorCond returns the id condition and the ordering of the page query
+func (p Page) cursorCond() (cond string, order string) {
+	if p.Order == SortOrderDesc {
+		return "id < ?", "id DESC"
+	}
+	return "id > ?", "id ASC"
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Publisher delivers events to downstream services. Delivery is at-least-once:
+// the same event can be published more than once, consumers deduplicate events by id.
+type Publisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
+
+// ─── LOG PUBLISHER ──────────────────────────────────────────────────────────────
+
+type logPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogPublisher returns a Publisher which writes events to w as JSON lines
+func NewLogPublisher(w io.Writer) Publisher {
+	return &logPublisher{w: w}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, e *Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return json.NewEncoder(p.w).Encode(e)
+}
+
+// ─── WEBHOOK PUBLISHER ──────────────────────────────────────────────────────────
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher returns a Publisher which posts events to the url as JSON.
+// Any response status except 2xx is a failed delivery.
+func NewWebhookPublisher(url string, client *http.Client) Publisher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &webhookPublisher{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "event encoding failed")
+	}
+
+	req, err := http.NewRequest("POST", p.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "webhook request createing failed")
+	}
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-Event-ID", strconv.FormatUint(uint64(e.ID), 10))
+	req.Header.Set("X-Event-Type", string(e.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "webhook request failed")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ─── MULTI PUBLISHER ────────────────────────────────────────────────────────────
+
+type multiPublisher struct {
+	pubs []Publisher
+}
+
+// NewMultiPublisher returns a Publisher which publishes events with all given publishers in turn.
+// Publishing fails if any publisher fails, so the event is published again by all of them.
+func NewMultiPublisher(pubs ...Publisher) Publisher {
+	return &multiPublisher{pubs}
+}
+
+func (p *multiPublisher) Publish(ctx context.Context, e *Event) error {
+	for _, pub := range p.pubs {
+		if err := pub.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ─── MEMORY PUBLISHER ───────────────────────────────────────────────────────────
+
+// MemoryPublisher keeps published events in memory. It's used by tests.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []*Event
+
+	// Fail makes publishing of the event fail if it returns an error
+	Fail func(e *Event) error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, e *Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Fail != nil {
+		if err := p.Fail(e); err != nil {
+			return err
+		}
+	}
+
+	p.events = append(p.events, e)
+	return nil
+}
+
+// Events returns all published events in the order of publishing
+func (p *MemoryPublisher) Events() []*Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*Event{}, p.events...)
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+var ErrSystemAccountAdjustment = NewError(ErrorKindConflict, "system_account_adjustment", "system accounts can't be adjusted")
+
+// Drift describes a difference between the cached balance of an account
+// and the balance computed from the history of its operations
+type Drift struct {
+	AccountID  int64           `json:"account_id"`
+	Currency   string          `json:"currency"`
+	Amount     decimal.Decimal `json:"amount"`
+	Computed   decimal.Decimal `json:"computed"`
+	Difference decimal.Decimal `json:"difference"`
+
+	// AdjustmentID is the id of the operation which fixed the drift
+	AdjustmentID *uint `json:"adjustment_id,omitempty"`
+}
+
+// Reconciler verifies balances of accounts against their operations history
+type Reconciler interface {
+	// Reconcile returns drifts of all accounts
+	Reconcile(ctx context.Context) ([]*Drift, error)
+	// Fix writes an adjustment operation which explains the drift in the history of the account.
+	// The cached balance of the account isn't changed.
+	Fix(ctx context.Context, d *Drift) (*Operation, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type reconciler struct {
+	s *basicPaymentsService
+}
+
+// NewReconciler returns a Reconciler which works with the same storage as PaymentsService
+func NewReconciler(lockf LockFactory, uowf UOWPaymentsFactory) Reconciler {
+	return &reconciler{
+		s: &basicPaymentsService{
+			lockf: lockf,
+			uowf:  uowf,
+		},
+	}
+}
+
+func (r *reconciler) Reconcile(ctx context.Context) ([]*Drift, error) {
+	uow, err := r.s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+
+	accs, err := uow.Accounts().GetAll(ctx)
+	uow.Save()
+	if err != nil {
+		return nil, errors.Wrap(err, "accounts getting failed")
+	}
+
+	drifts := []*Drift{}
+	for _, a := range accs {
+		d, err := r.check(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if d != nil {
+			drifts = append(drifts, d)
+		}
+	}
+
+	return drifts, nil
+}
+
+func (r *reconciler) Fix(ctx context.Context, d *Drift) (*Operation, error) {
+	world, err := r.s.systemAccount(ctx, AccountRoleWorld, d.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := r.s.getLock(d.AccountID, world.ID)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d) locking failed", d.AccountID)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := r.s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	// The drift could be changed since it was found
+	actual, err := r.drift(ctx, uow, d.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if actual == nil {
+		return nil, nil
+	}
+
+	*d = *actual
+
+	a, err := uow.Accounts().Get(ctx, d.AccountID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", d.AccountID)
+	}
+
+	if a.IsSystem() {
+		return nil, ErrSystemAccountAdjustment
+	}
+
+	if world, err = uow.Accounts().Get(ctx, world.ID); err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", world.ID)
+	}
+
+	t := Transaction{
+		From:     world.ID,
+		To:       a.ID,
+		Currency: a.Currency,
+		Amount:   d.Difference,
+	}
+	if d.Difference.IsNegative() {
+		t.From, t.To, t.Amount = a.ID, world.ID, d.Difference.Neg()
+	}
+
+	o := &Operation{
+		Type:         OperationTypeAdjustment,
+		Transactions: []Transaction{t},
+		Participants: []int64{world.ID, a.ID},
+		Reason:       "reconciliation",
+	}
+
+	if _, err := uow.Operations().Create(ctx, o); err != nil {
+		uow.Revert()
+		return nil, errors.Wrap(err, "operation createing failed")
+	}
+
+	// The cached balance of the account is already right, only the world account is changed
+	world.Amount = world.Amount.Sub(d.Difference)
+
+	postings := []*Posting{
+		{
+			OperationID:   o.ID,
+			TransactionID: o.Transactions[0].ID,
+			AccountID:     a.ID,
+			Currency:      a.Currency,
+			Amount:        d.Difference,
+			Balance:       a.Amount,
+		},
+		{
+			OperationID:   o.ID,
+			TransactionID: o.Transactions[0].ID,
+			AccountID:     world.ID,
+			Currency:      world.Currency,
+			Amount:        d.Difference.Neg(),
+			Balance:       world.Amount,
+		},
+	}
+
+	for _, p := range postings {
+		if _, err := uow.Postings().Create(ctx, p); err != nil {
+			uow.Revert()
+			return nil, errors.Wrapf(err, "posting (%d) createing failed", p.AccountID)
+		}
+	}
+
+	if _, err := uow.Accounts().Update(ctx, world); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "account (%d) update failed", world.ID)
+	}
+
+	d.AdjustmentID = &o.ID
+
+	return o, nil
+}
+
+// check computes the drift of the account under the account lock
+func (r *reconciler) check(ctx context.Context, accID int64) (*Drift, error) {
+	lock := r.s.getLock(accID)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d) locking failed", accID)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := r.s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	return r.drift(ctx, uow, accID)
+}
+
+// drift recomputes the balance of the account from its transactions.
+// Returns nil if the balance is right.
+//
+// Opening postings of the ledger backfill stand for the balance the account had before the ledger,
+// so the history of accounts with them starts at the opening: transactions without postings are
+// summed up by the opening.
+func (r *reconciler) drift(ctx context.Context, uow UOWPayments, accID int64) (*Drift, error) {
+	a, err := uow.Accounts().Get(ctx, accID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", accID)
+	}
+
+	ops, err := uow.Operations().GetByAccID(ctx, accID, OperationsFilter{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) operations getting failed", accID)
+	}
+
+	ps, err := uow.Postings().GetByAccID(ctx, accID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) postings getting failed", accID)
+	}
+
+	computed := decimal.Zero
+	opened := false
+	posted := map[uint]bool{}
+	for _, p := range ps {
+		if p.IsOpening() {
+			computed = computed.Add(p.Amount)
+			opened = true
+			continue
+		}
+		posted[p.TransactionID] = true
+	}
+
+	for _, o := range ops {
+		for _, t := range o.Transactions {
+			if opened && !posted[t.ID] {
+				continue
+			}
+			if t.To == accID {
+				computed = computed.Add(t.Amount)
+			}
+			if t.From == accID {
+				computed = computed.Sub(t.Amount)
+			}
+		}
+	}
+
+	if computed.Equal(a.Amount) {
+		return nil, nil
+	}
+
+	return &Drift{
+		AccountID:  a.ID,
+		Currency:   a.Currency,
+		Amount:     a.Amount,
+		Computed:   computed,
+		Difference: a.Amount.Sub(computed),
+	}, nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// DefaultScheduleRetryDelay is used when a schedule is created without explicit retry delay
+	DefaultScheduleRetryDelay = time.Hour
+	// dueSchedulesLimit is the biggest number of schedules executed by one run of the scheduler
+	dueSchedulesLimit = 100
+)
+
+var (
+	ErrScheduleNotFound  = NewError(ErrorKindNotFound, "schedule_not_found", "schedule not found")
+	ErrScheduleNotActive = NewError(ErrorKindConflict, "schedule_not_active", "schedule isn't active")
+	ErrSchedulePeriod    = NewError(ErrorKindValidation, "unknown_schedule_period", "schedule period is unknown")
+	ErrScheduleEnd       = NewError(ErrorKindValidation, "schedule_end_before_start", "schedule must end after its start")
+)
+
+// SchedulePeriod is the unit of the interval between runs of a schedule
+type SchedulePeriod string
+
+const (
+	SchedulePeriodOnce  SchedulePeriod = "once"
+	SchedulePeriodDay   SchedulePeriod = "day"
+	SchedulePeriodWeek  SchedulePeriod = "week"
+	SchedulePeriodMonth SchedulePeriod = "month"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+	ScheduleStatusFailed    ScheduleStatus = "failed" // the transfer can never succeed, e.g. an account is closed
+)
+
+// ScheduleSpec describes a standing order
+type ScheduleSpec struct {
+	From     int64
+	To       int64
+	Currency string
+	Amount   decimal.Decimal
+
+	// Period and Every set the interval between runs: every 2 weeks, every month
+	Period SchedulePeriod
+	Every  int
+
+	// StartAt is the time of the first run, EndAt is the latest possible run time (inclusive).
+	// Runs of monthly schedules fall on the day of the start or on the last day of shorter months.
+	StartAt time.Time
+	EndAt   *time.Time
+
+	// MaxRetries is the number of retries of a failed run. The run is skipped after the last retry.
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// Schedule is a standing order executing the same transfer periodically
+type Schedule struct {
+	gorm.Model
+	From       int64 `gorm:"index"`
+	To         int64
+	Currency   string
+	Amount     decimal.Decimal `sql:"type:decimal(20,8);"`
+	Period     SchedulePeriod
+	Every      int
+	StartAt    time.Time
+	EndAt      *time.Time
+	MaxRetries int
+	RetryDelay time.Duration
+	Status     ScheduleStatus `gorm:"index"`
+
+	// Occurrence is the number of the current run counted from the start
+	Occurrence int
+	// Attempts is the number of failed attempts of the current run
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+}
+
+// RunAt returns the planned time of the current run
+func (s *Schedule) RunAt() time.Time {
+	return s.occurrence(s.Occurrence)
+}
+
+// occurrence returns the planned time of the n-th run
+func (s *Schedule) occurrence(n int) time.Time {
+	k := n * s.Every
+	switch s.Period {
+	case SchedulePeriodDay:
+		return s.StartAt.AddDate(0, 0, k)
+	case SchedulePeriodWeek:
+		return s.StartAt.AddDate(0, 0, 7*k)
+	case SchedulePeriodMonth:
+		y, m, d := s.StartAt.Date()
+		first := time.Date(y, m+time.Month(k), 1, 0, 0, 0, 0, s.StartAt.Location())
+		if last := first.AddDate(0, 1, -1).Day(); d > last {
+			d = last
+		}
+		hh, mm, ss := s.StartAt.Clock()
+		return time.Date(first.Year(), first.Month(), d, hh, mm, ss, s.StartAt.Nanosecond(), s.StartAt.Location())
+	}
+
+	return s.StartAt
+}
+
+// reschedule moves the schedule to the first run which isn't in the past
+func (s *Schedule) reschedule(now time.Time) {
+	s.Occurrence, s.Attempts = 0, 0
+	for s.Period != SchedulePeriodOnce && s.RunAt().Before(now) {
+		s.Occurrence++
+	}
+	s.plan()
+}
+
+// advance moves the schedule to the next run
+func (s *Schedule) advance() {
+	s.Occurrence++
+	s.Attempts = 0
+	s.plan()
+}
+
+// retry plans another attempt of the current run or skips the run after the last retry
+func (s *Schedule) retry(now time.Time) {
+	s.Attempts++
+	if s.Attempts > s.MaxRetries {
+		s.advance()
+		return
+	}
+
+	s.NextAttemptAt = now.Add(s.RetryDelay)
+}
+
+// plan sets the time of the next attempt or completes the schedule after the last run
+func (s *Schedule) plan() {
+	next := s.RunAt()
+	if (s.Period == SchedulePeriodOnce && s.Occurrence > 0) || (s.EndAt != nil && next.After(*s.EndAt)) {
+		s.Status = ScheduleStatusCompleted
+		return
+	}
+
+	s.NextAttemptAt = next
+}
+
+// ScheduleAttempt is a record of an execution of a schedule run
+type ScheduleAttempt struct {
+	gorm.Model
+	ScheduleID uint `gorm:"index"`
+	Occurrence int
+	RunAt      time.Time
+
+	// OperationID is the id of the transfer made by the successful attempt
+	OperationID *uint
+	Error       string
+}
+
+// SchedulesRepository describes interaction with a repository that can saves and stores Schedules.
+type SchedulesRepository interface {
+	Create(ctx context.Context, s *Schedule) (*Schedule, error)
+	Update(ctx context.Context, s *Schedule) (*Schedule, error)
+
+	Get(ctx context.Context, id int64) (*Schedule, error)
+	GetByAccID(ctx context.Context, accID int64) ([]*Schedule, error)
+	// GetDue returns active schedules whose next attempt time has come, the oldest first
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*Schedule, error)
+}
+
+// ScheduleAttemptsRepository describes interaction with a repository that can saves and stores ScheduleAttempts.
+type ScheduleAttemptsRepository interface {
+	Create(ctx context.Context, a *ScheduleAttempt) (*ScheduleAttempt, error)
+
+	GetByScheduleID(ctx context.Context, id int64) ([]*ScheduleAttempt, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type schedulesRepository struct {
+	db *gorm.DB
+}
+
+func NewSchedulesRepository(db *gorm.DB) SchedulesRepository {
+	return &schedulesRepository{db}
+}
+
+func (r *schedulesRepository) Create(ctx context.Context, s *Schedule) (*Schedule, error) {
+	if err := r.db.Create(s).Error; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (r *schedulesRepository) Update(ctx context.Context, s *Schedule) (*Schedule, error) {
+	if err := r.db.Save(s).Error; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (r *schedulesRepository) Get(ctx context.Context, id int64) (*Schedule, error) {
+	s := Schedule{}
+	if err := r.db.Find(&s, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrScheduleNotFound
+		}
+
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (r *schedulesRepository) GetByAccID(ctx context.Context, accID int64) ([]*Schedule, error) {
+	ss := []*Schedule{}
+
+	if err := r.db.Where(`"from" = ?`, accID).Order("id").Find(&ss).Error; err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+func (r *schedulesRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*Schedule, error) {
+	ss := []*Schedule{}
+
+	req := r.db.Where("status = ? AND next_attempt_at <= ?", ScheduleStatusActive, now).Order("next_attempt_at").Limit(limit)
+	if err := req.Find(&ss).Error; err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+type scheduleAttemptsRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleAttemptsRepository(db *gorm.DB) ScheduleAttemptsRepository {
+	return &scheduleAttemptsRepository{db}
+}
+
+func (r *scheduleAttemptsRepository) Create(ctx context.Context, a *ScheduleAttempt) (*ScheduleAttempt, error) {
+	if err := r.db.Create(a).Error; err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *scheduleAttemptsRepository) GetByScheduleID(ctx context.Context, id int64) ([]*ScheduleAttempt, error) {
+	as := []*ScheduleAttempt{}
+
+	if err := r.db.Where("schedule_id = ?", id).Order("id").Find(&as).Error; err != nil {
+		return nil, err
+	}
+
+	return as, nil
+}
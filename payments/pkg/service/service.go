@@ -2,45 +2,99 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 )
 
 var (
-	ErrDifferentCurrencies = errors.New("accounts currencies must be same")
-	ErrBalanceTooLow       = errors.New("balance too low")
+	ErrDifferentCurrencies = NewError(ErrorKindValidation, "different_currencies", "accounts currencies must be same")
+	ErrSameAccount         = NewError(ErrorKindValidation, "same_account", "donor and recipient accounts must differ")
+	ErrBalanceTooLow       = NewError(ErrorKindInsufficientFunds, "balance_too_low", "balance too low")
 )
 
 // PaymentsService describes the interface of the system of account management and cash transactions over this acounts
 type PaymentsService interface {
 	CreateAccount(ctx context.Context, name, currency string) (*Account, error)
 	GetAccount(ctx context.Context, id int64) (*Account, error)
-	GetAccounts(ctx context.Context) ([]*Account, error)
-	GetAccountOperations(ctx context.Context, accID int64) ([]*Operation, error)
+	GetAccounts(ctx context.Context, f AccountsFilter) (*AccountsPage, error)
+	GetAccountOperations(ctx context.Context, accID int64, f OperationsFilter) (*OperationsPage, error)
+	FreezeAccount(ctx context.Context, id int64, reason string) (*Account, error)
+	UnfreezeAccount(ctx context.Context, id int64, reason string) (*Account, error)
+	CloseAccount(ctx context.Context, id int64, sweepTo int64, reason string) (*Account, error)
+	GetAccountStatusHistory(ctx context.Context, id int64) ([]*AccountStatusChange, error)
 	MakeDeposit(ctx context.Context, to int64, currency string, amount decimal.Decimal) (*Operation, error)
 	MakeTransfer(ctx context.Context, from, to int64, currency string, amount decimal.Decimal) (*Operation, error)
+	MakeBatchTransfer(ctx context.Context, legs []TransferLeg) (*Operation, error)
+	MakeOperation(ctx context.Context, legs []OperationLeg) (*Operation, error)
+	MakeWithdrawal(ctx context.Context, from int64, currency string, amount decimal.Decimal) (*Operation, error)
+	ReverseOperation(ctx context.Context, operationID int64, reason string) (*Operation, error)
+
+	AuthorizeHold(ctx context.Context, accID int64, currency string, amount decimal.Decimal, ttl time.Duration) (*Hold, error)
+	CaptureHold(ctx context.Context, holdID int64, to int64, amount decimal.Decimal) (*Operation, error)
+	VoidHold(ctx context.Context, holdID int64) (*Hold, error)
+	ExpireHolds(ctx context.Context) ([]*Hold, error)
+
+	CreateSchedule(ctx context.Context, spec ScheduleSpec) (*Schedule, error)
+	GetSchedule(ctx context.Context, id int64) (*Schedule, error)
+	GetAccountSchedules(ctx context.Context, accID int64) ([]*Schedule, error)
+	UpdateSchedule(ctx context.Context, id int64, spec ScheduleSpec) (*Schedule, error)
+	CancelSchedule(ctx context.Context, id int64) (*Schedule, error)
+	GetScheduleAttempts(ctx context.Context, id int64) ([]*ScheduleAttempt, error)
+	RunDueSchedules(ctx context.Context) ([]*ScheduleAttempt, error)
+
+	CreateWebhook(ctx context.Context, spec WebhookSpec) (*Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (*Webhook, error)
+	GetWebhooks(ctx context.Context) ([]*Webhook, error)
+	UpdateWebhook(ctx context.Context, id int64, spec WebhookSpec) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	GetWebhookDeliveries(ctx context.Context, id int64) ([]*WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, deliveryID int64) (*WebhookDelivery, error)
+
+	QuoteFee(ctx context.Context, t OperationType, currency string, amount decimal.Decimal) (decimal.Decimal, error)
+
+	QuoteExchange(ctx context.Context, from, to string, ttl time.Duration) (*FXQuote, error)
+	MakeExchangeTransfer(ctx context.Context, from, to int64, amount decimal.Decimal, quoteID int64) (*Operation, error)
+
+	CheckLedger(ctx context.Context) error
+
+	GetCurrencies(ctx context.Context) ([]*Currency, error)
+	GetSystemAccounts(ctx context.Context) ([]*Account, error)
 }
 
 // ─── INTERFACE REALIZATION ──────────────────────────────────────────────────────
 
 type basicPaymentsService struct {
-	lockf LockFactory
-	uowf  UOWPaymentsFactory
+	lockf      LockFactory
+	uowf       UOWPaymentsFactory
+	currencies CurrencyRegistry
+	fx         FXRateProvider
+	fees       *FeeSchedule
 }
 
 // NewBasicPaymentsService returns a naive implementation of PaymentsService.
-func NewBasicPaymentsService(lockf LockFactory, uowf UOWPaymentsFactory) PaymentsService {
+// Operations are free when the fee schedule is nil.
+func NewBasicPaymentsService(lockf LockFactory, uowf UOWPaymentsFactory, currencies CurrencyRegistry, fx FXRateProvider, fees *FeeSchedule) PaymentsService {
 	return &basicPaymentsService{
-		lockf: lockf,
-		uowf:  uowf,
+		lockf:      lockf,
+		uowf:       uowf,
+		currencies: currencies,
+		fx:         fx,
+		fees:       fees,
 	}
 }
 
 // CreateAccount creates new account
 func (s *basicPaymentsService) CreateAccount(ctx context.Context, name, currency string) (*Account, error) {
+	c, err := s.currencies.Get(currency)
+	if err != nil {
+		return nil, err
+	}
+
 	uow, err := s.uowf.Make()
 	if err != nil {
 		return nil, errors.Wrap(err, "uow context createing failed")
@@ -49,12 +103,19 @@ func (s *basicPaymentsService) CreateAccount(ctx context.Context, name, currency
 
 	a := &Account{
 		Name:     name,
-		Currency: currency,
+		Currency: c.Code,
+		Status:   AccountStatusActive,
 	}
 
 	a, err = uow.Accounts().Create(ctx, a)
 	if err != nil {
 		uow.Revert()
+		return nil, errors.Wrap(err, "account createing failed")
+	}
+
+	if err := emit(ctx, uow, EventAccountCreated, a.ID, a); err != nil {
+		uow.Revert()
+		return nil, err
 	}
 
 	return a, nil
@@ -76,45 +137,225 @@ func (s *basicPaymentsService) GetAccount(ctx context.Context, id int64) (*Accou
 	return a, nil
 }
 
-// GetAccounts returns all accounts in the system
-func (s *basicPaymentsService) GetAccounts(ctx context.Context) ([]*Account, error) {
+// GetAccounts returns the page of accounts matching the filter
+func (s *basicPaymentsService) GetAccounts(ctx context.Context, f AccountsFilter) (*AccountsPage, error) {
 	uow, err := s.uowf.Make()
 	if err != nil {
 		return nil, errors.Wrap(err, "uow context createing failed")
 	}
 	defer uow.Save()
 
-	a, err := uow.Accounts().GetAll(ctx)
+	// One extra account shows whether the next page exists
+	f.Page = f.Page.normalize()
+	limit := f.Limit
+	f.Limit++
+
+	a, err := uow.Accounts().Find(ctx, f)
 	if err != nil {
 		return nil, errors.Wrap(err, "accounts getting failed")
 	}
 
-	return a, nil
+	page := &AccountsPage{Accounts: a}
+	if len(a) > limit {
+		page.Accounts = a[:limit]
+		page.NextCursor = EncodeCursor(a[limit-1].ID)
+	}
+
+	if f.WithTotal {
+		total, err := uow.Accounts().Count(ctx, f)
+		if err != nil {
+			return nil, errors.Wrap(err, "accounts counting failed")
+		}
+		page.Total = &total
+	}
+
+	return page, nil
 }
 
-// GetAccountOperations returns operations list of the account
-func (s *basicPaymentsService) GetAccountOperations(ctx context.Context, accID int64) ([]*Operation, error) {
+// GetAccountOperations returns the page of the account operations matching the filter
+func (s *basicPaymentsService) GetAccountOperations(ctx context.Context, accID int64, f OperationsFilter) (*OperationsPage, error) {
 	uow, err := s.uowf.Make()
 	if err != nil {
 		return nil, errors.Wrap(err, "uow context createing failed")
 	}
 	defer uow.Save()
 
-	a, err := uow.Operations().GetByAccID(ctx, accID)
+	// One extra operation shows whether the next page exists
+	f.Page = f.Page.normalize()
+	limit := f.Limit
+	f.Limit++
+
+	ops, err := uow.Operations().GetByAccID(ctx, accID, f)
 	if err != nil {
 		return nil, errors.Wrap(err, "accounts getting failed")
 	}
 
+	page := &OperationsPage{Operations: ops}
+	if len(ops) > limit {
+		page.Operations = ops[:limit]
+		page.NextCursor = EncodeCursor(int64(ops[limit-1].ID))
+	}
+
+	return page, nil
+}
+
+// FreezeAccount blocks all operations of the account until it's unfrozen
+func (s *basicPaymentsService) FreezeAccount(ctx context.Context, id int64, reason string) (*Account, error) {
+	return s.changeStatus(ctx, id, AccountStatusActive, AccountStatusFrozen, reason)
+}
+
+// UnfreezeAccount makes the frozen account active again
+func (s *basicPaymentsService) UnfreezeAccount(ctx context.Context, id int64, reason string) (*Account, error) {
+	return s.changeStatus(ctx, id, AccountStatusFrozen, AccountStatusActive, reason)
+}
+
+// CloseAccount closes the active or frozen account. Accounts with money are closed only with
+// a sweep account: the whole balance is sent to it by the closure operation.
+// Closed accounts are soft deleted and can't take part in operations anymore.
+func (s *basicPaymentsService) CloseAccount(ctx context.Context, id int64, sweepTo int64, reason string) (*Account, error) {
+	accIDs := []int64{id}
+	if sweepTo != 0 {
+		accIDs = append(accIDs, sweepTo)
+	}
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%v) locking failed", accIDs)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	a, err := uow.Accounts().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", id)
+	}
+
+	if a.IsSystem() {
+		return nil, ErrSystemAccount
+	}
+
+	if a.Status == AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+
+	if a.Held.IsPositive() {
+		return nil, ErrAccountHasHolds
+	}
+
+	var opID *uint
+	if !a.Amount.IsZero() {
+		if sweepTo == 0 {
+			return nil, ErrAccountNotEmpty
+		}
+
+		o := &Operation{
+			Type: OperationTypeClosure,
+			Transactions: []Transaction{
+				{
+					From:     id,
+					To:       sweepTo,
+					Currency: a.Currency,
+					Amount:   a.Amount,
+				},
+			},
+			Participants: []int64{id, sweepTo},
+			Reason:       reason,
+		}
+
+		if err := s.createOperation(ctx, uow, o); err != nil {
+			uow.Revert()
+			return nil, err
+		}
+		opID = &o.ID
+
+		if a, err = uow.Accounts().Get(ctx, id); err != nil {
+			uow.Revert()
+			return nil, errors.Wrapf(err, "account (%d) getting failed", id)
+		}
+
+		if !a.Amount.IsZero() {
+			uow.Revert()
+			return nil, ErrAccountNotEmpty
+		}
+	}
+
+	if err := s.setStatus(ctx, uow, a, AccountStatusClosed, reason, opID); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	if err := uow.Accounts().Delete(ctx, id); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "account (%d) deleting failed", id)
+	}
+
+	if a, err = uow.Accounts().Get(ctx, id); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "account (%d) getting failed", id)
+	}
+
 	return a, nil
 }
 
-// MakeDeposit creates new deposit operation for the account
+// GetAccountStatusHistory returns status changes of the account from the oldest to the newest
+func (s *basicPaymentsService) GetAccountStatusHistory(ctx context.Context, id int64) ([]*AccountStatusChange, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Accounts().Get(ctx, id); err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", id)
+	}
+
+	cs, err := uow.StatusChanges().GetByAccID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) status history getting failed", id)
+	}
+
+	return cs, nil
+}
+
+// MakeDeposit creates new deposit operation for the account. The fee of the deposit is paid by the recipient.
+// Requests with an idempotency key in the context return the originally created operation on replays.
 func (s *basicPaymentsService) MakeDeposit(ctx context.Context, to int64, currency string, amount decimal.Decimal) (*Operation, error) {
-	lock := s.getLock(to)
-	if err := lock.Lock(); err != nil {
+	currency, err := s.checkAmount(currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	world, err := s.systemAccount(ctx, AccountRoleWorld, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := s.withFee(ctx, OperationTypeDeposit, to, []Transaction{
+		{
+			From:     world.ID,
+			To:       to,
+			Currency: currency,
+			Amount:   amount,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accIDs := participants(txs)
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
 		return nil, errors.Wrapf(err, "mutex (%d) locking failed", to)
 	}
 	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
 
 	uow, err := s.uowf.Make()
 	if err != nil {
@@ -122,127 +363,1653 @@ func (s *basicPaymentsService) MakeDeposit(ctx context.Context, to int64, curren
 	}
 	defer uow.Save()
 
-	a, err := uow.Accounts().Get(ctx, to)
+	key := IdempotencyKey(ctx)
+	hash := requestHash(OperationTypeDeposit, world.ID, to, currency, amount)
+	if o, err := s.findReplay(ctx, uow, key, hash); o != nil || err != nil {
+		return o, err
+	}
+
+	o := &Operation{
+		Type:           OperationTypeDeposit,
+		Transactions:   txs,
+		Participants:   accIDs,
+		IdempotencyKey: keyOrNil(key),
+		RequestHash:    hash,
+	}
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// MakeTransfer creates new transfer operation for the pair of accounts. The fee of the transfer is paid by the donor.
+// Requests with an idempotency key in the context return the originally created operation on replays.
+func (s *basicPaymentsService) MakeTransfer(ctx context.Context, from int64, to int64, currency string, amount decimal.Decimal) (*Operation, error) {
+	if from == to {
+		return nil, ErrSameAccount
+	}
+
+	currency, err := s.checkAmount(currency, amount)
 	if err != nil {
-		return nil, errors.Wrapf(err, "account (%d) getting failed", to)
+		return nil, err
 	}
 
-	a.Amount = a.Amount.Add(amount)
+	txs, err := s.withFee(ctx, OperationTypeTransfer, from, []Transaction{
+		{
+			From:     from,
+			To:       to,
+			Currency: currency,
+			Amount:   amount,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	if a.Currency != currency {
-		return nil, ErrDifferentCurrencies
+	accIDs := participants(txs)
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d, %d) locking failed", from, to)
 	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
 
-	if _, err := uow.Accounts().Update(ctx, a); err != nil {
-		uow.Revert()
-		return nil, errors.Wrapf(err, "account (%d) update failed", a.ID)
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	key := IdempotencyKey(ctx)
+	hash := requestHash(OperationTypeTransfer, from, to, currency, amount)
+	if o, err := s.findReplay(ctx, uow, key, hash); o != nil || err != nil {
+		return o, err
 	}
 
 	o := &Operation{
-		Type: OperationTypeDeposit,
-		Transactions: []Transaction{
+		Type:           OperationTypeTransfer,
+		Transactions:   txs,
+		Participants:   accIDs,
+		IdempotencyKey: keyOrNil(key),
+		RequestHash:    hash,
+	}
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// MakeBatchTransfer creates one batch operation with transactions of all the legs.
+// The legs are applied all together or not at all, every leg pays the transfer fee.
+// Requests with an idempotency key in the context return the originally created operation on replays.
+func (s *basicPaymentsService) MakeBatchTransfer(ctx context.Context, legs []TransferLeg) (*Operation, error) {
+	if len(legs) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	if len(legs) > MaxBatchLegs {
+		return nil, ErrBatchTooLarge
+	}
+
+	normalized := make([]TransferLeg, len(legs))
+	txs := make([]Transaction, 0, len(legs))
+	for i, l := range legs {
+		if l.From == l.To {
+			return nil, errors.Wrapf(ErrSameAccount, "leg %d", i)
+		}
+
+		currency, err := s.checkAmount(l.Currency, l.Amount)
+		if err != nil {
+			return nil, errors.Wrapf(err, "leg %d", i)
+		}
+
+		l.Currency = currency
+		normalized[i] = l
+
+		legTxs, err := s.withFee(ctx, OperationTypeTransfer, l.From, []Transaction{
 			{
-				From:     WorldAccountID,
-				To:       to,
+				From:     l.From,
+				To:       l.To,
 				Currency: currency,
-				Amount:   amount,
+				Amount:   l.Amount,
 			},
-		},
-		Participants: []int64{WorldAccountID, to},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, legTxs...)
 	}
 
-	if _, err := uow.Operations().Create(ctx, o); err != nil {
-		return nil, errors.Wrap(err, "operation createing failed")
+	accIDs := participants(txs)
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%v) locking failed", accIDs)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	key := IdempotencyKey(ctx)
+	hash := batchRequestHash(normalized)
+	if o, err := s.findReplay(ctx, uow, key, hash); o != nil || err != nil {
+		return o, err
+	}
+
+	o := &Operation{
+		Type:           OperationTypeBatch,
+		Transactions:   txs,
+		Participants:   accIDs,
+		IdempotencyKey: keyOrNil(key),
+		RequestHash:    hash,
+	}
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
 	}
 
 	return o, nil
 }
 
-// MakeTransfer creates new transfer operation for the pair of accounts
-func (s *basicPaymentsService) MakeTransfer(ctx context.Context, from int64, to int64, currency string, amount decimal.Decimal) (*Operation, error) {
+// MakeOperation creates a multi-leg operation which moves money between any accounts.
+// Legs of every currency must sum to zero, the legs are applied atomically and no fees are charged.
+// Requests with an idempotency key in the context return the originally created operation on replays.
+func (s *basicPaymentsService) MakeOperation(ctx context.Context, legs []OperationLeg) (*Operation, error) {
+	if len(legs) == 0 {
+		return nil, ErrEmptyOperation
+	}
+
+	if len(legs) > MaxOperationLegs {
+		return nil, ErrTooManyLegs
+	}
+
+	normalized := make([]OperationLeg, len(legs))
+	for i, l := range legs {
+		currency, err := s.checkAmount(l.Currency, l.Amount.Abs())
+		if err != nil {
+			return nil, errors.Wrapf(err, "leg %d", i)
+		}
+
+		l.Currency = currency
+		normalized[i] = l
+	}
+
+	txs, err := legsTransactions(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	accIDs := participants(txs)
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%v) locking failed", accIDs)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
 	uow, err := s.uowf.Make()
 	if err != nil {
 		return nil, errors.Wrap(err, "uow context createing failed")
 	}
 	defer uow.Save()
 
-	a1, err := uow.Accounts().Get(ctx, from)
-	if err != nil {
-		return nil, errors.Wrapf(err, "account (%d) getting failed", from)
+	key := IdempotencyKey(ctx)
+	hash := legsRequestHash(normalized)
+	if o, err := s.findReplay(ctx, uow, key, hash); o != nil || err != nil {
+		return o, err
 	}
 
-	a2, err := uow.Accounts().Get(ctx, to)
-	if err != nil {
-		return nil, errors.Wrapf(err, "account (%d) getting failed", to)
+	o := &Operation{
+		Type:           OperationTypeMultiLeg,
+		Transactions:   txs,
+		Participants:   accIDs,
+		IdempotencyKey: keyOrNil(key),
+		RequestHash:    hash,
 	}
 
-	if a1.Currency != currency || a1.Currency != a2.Currency {
-		return nil, ErrDifferentCurrencies
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
 	}
 
-	if a1.Amount.LessThan(amount) {
-		return nil, ErrBalanceTooLow
+	return o, nil
+}
+
+// MakeWithdrawal creates new withdrawal operation that takes money out of the account
+func (s *basicPaymentsService) MakeWithdrawal(ctx context.Context, from int64, currency string, amount decimal.Decimal) (*Operation, error) {
+	currency, err := s.checkAmount(currency, amount)
+	if err != nil {
+		return nil, err
 	}
 
-	a1.Amount = a1.Amount.Sub(amount)
-	a2.Amount = a2.Amount.Add(amount)
+	world, err := s.systemAccount(ctx, AccountRoleWorld, currency)
+	if err != nil {
+		return nil, err
+	}
 
-	if _, err := uow.Accounts().Update(ctx, a1); err != nil {
-		uow.Revert()
-		return nil, errors.Wrapf(err, "account (%d) update failed", a1.ID)
+	lock := s.getLock(from, world.ID)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d) locking failed", from)
 	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
 
-	if _, err := uow.Accounts().Update(ctx, a2); err != nil {
-		uow.Revert()
-		return nil, errors.Wrapf(err, "account (%d) update failed", a2.ID)
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
 	}
+	defer uow.Save()
 
 	o := &Operation{
+		Type: OperationTypeWithdrawal,
 		Transactions: []Transaction{
 			{
 				From:     from,
-				To:       to,
+				To:       world.ID,
 				Currency: currency,
 				Amount:   amount,
 			},
 		},
-		Participants: []int64{from, to},
+		Participants: []int64{from, world.ID},
 	}
-	if _, err := uow.Operations().Create(ctx, o); err != nil {
-		return nil, errors.Wrap(err, "operation createing failed")
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
 	}
 
 	return o, nil
 }
 
-// ─── HELPER METHODS ─────────────────────────────────────────────────────────────
+// ReverseOperation creates new operation which compensates all transactions of the given operation
+func (s *basicPaymentsService) ReverseOperation(ctx context.Context, operationID int64, reason string) (*Operation, error) {
+	orig, err := s.getOperation(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if orig.Type == OperationTypeReversal {
+		return nil, ErrOperationNotReversible
+	}
+
+	txs := make([]Transaction, len(orig.Transactions))
+	for i, t := range orig.Transactions {
+		txs[i] = Transaction{
+			From:     t.To,
+			To:       t.From,
+			Currency: t.Currency,
+			Amount:   t.Amount,
+		}
+	}
+
+	accIDs := participants(txs)
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%v) locking failed", accIDs)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Operations().GetReversal(ctx, operationID); err == nil {
+		return nil, ErrOperationAlreadyReversed
+	} else if errors.Cause(err) != ErrOperationNotFound {
+		return nil, errors.Wrapf(err, "operation (%d) reversal getting failed", operationID)
+	}
+
+	o := &Operation{
+		Type:         OperationTypeReversal,
+		Transactions: txs,
+		Participants: participants(txs),
+		ReversalOf:   &orig.ID,
+		Reason:       reason,
+	}
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// AuthorizeHold reserves money on the account. Reserved money can't be spent by other operations.
+func (s *basicPaymentsService) AuthorizeHold(ctx context.Context, accID int64, currency string, amount decimal.Decimal, ttl time.Duration) (*Hold, error) {
+	currency, err := s.checkAmount(currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+
+	lock := s.getLock(accID)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d) locking failed", accID)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	a, err := uow.Accounts().Get(ctx, accID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", accID)
+	}
+
+	if a.Currency != currency {
+		return nil, ErrDifferentCurrencies
+	}
+
+	if err := a.checkStatus(false); err != nil {
+		return nil, err
+	}
+
+	if a.Available().LessThan(amount) {
+		return nil, ErrBalanceTooLow
+	}
+
+	a.Held = a.Held.Add(amount)
+
+	if _, err := uow.Accounts().Update(ctx, a); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "account (%d) update failed", a.ID)
+	}
+
+	h := &Hold{
+		AccountID: accID,
+		Currency:  currency,
+		Amount:    amount,
+		Status:    HoldStatusActive,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if _, err := uow.Holds().Create(ctx, h); err != nil {
+		uow.Revert()
+		return nil, errors.Wrap(err, "hold createing failed")
+	}
+
+	return h, nil
+}
+
+// CaptureHold sends the held money to the recipient account. Zero amount means full capture.
+// The rest of the hold is released after a partial capture.
+func (s *basicPaymentsService) CaptureHold(ctx context.Context, holdID int64, to int64, amount decimal.Decimal) (*Operation, error) {
+	h, err := s.getHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := s.getLock(h.AccountID, to)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d, %d) locking failed", h.AccountID, to)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	h, err = s.releaseHold(ctx, uow, holdID)
+	if err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	if h.ExpiresAt.Before(time.Now()) {
+		uow.Revert()
+		return nil, ErrHoldExpired
+	}
+
+	if amount.IsZero() {
+		amount = h.Amount
+	}
+
+	if _, err := s.checkAmount(h.Currency, amount); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	if amount.GreaterThan(h.Amount) {
+		uow.Revert()
+		return nil, ErrHoldAmountExceeded
+	}
+
+	o := &Operation{
+		Type: OperationTypeCapture,
+		Transactions: []Transaction{
+			{
+				From:     h.AccountID,
+				To:       to,
+				Currency: h.Currency,
+				Amount:   amount,
+			},
+		},
+		Participants: []int64{h.AccountID, to},
+	}
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	h.Status = HoldStatusCaptured
+	h.Captured = amount
+	h.OperationID = &o.ID
+
+	if _, err := uow.Holds().Update(ctx, h); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "hold (%d) update failed", h.ID)
+	}
+
+	return o, nil
+}
+
+// VoidHold cancels the hold and makes the held money available again
+func (s *basicPaymentsService) VoidHold(ctx context.Context, holdID int64) (*Hold, error) {
+	return s.closeHold(ctx, holdID, HoldStatusVoided)
+}
+
+// ExpireHolds releases all stale holds
+func (s *basicPaymentsService) ExpireHolds(ctx context.Context) ([]*Hold, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+
+	hs, err := uow.Holds().GetExpired(ctx, time.Now())
+	uow.Save()
+	if err != nil {
+		return nil, errors.Wrap(err, "expired holds getting failed")
+	}
+
+	expired := make([]*Hold, 0, len(hs))
+	for _, h := range hs {
+		h, err := s.closeHold(ctx, int64(h.ID), HoldStatusExpired)
+		if err != nil {
+			if errors.Cause(err) == ErrHoldNotActive {
+				continue
+			}
+			return expired, err
+		}
+		expired = append(expired, h)
+	}
+
+	return expired, nil
+}
+
+// CreateSchedule creates a standing order. Runs planned before now are skipped.
+func (s *basicPaymentsService) CreateSchedule(ctx context.Context, spec ScheduleSpec) (*Schedule, error) {
+	sch := &Schedule{Status: ScheduleStatusActive}
+	if err := s.applyScheduleSpec(ctx, sch, spec); err != nil {
+		return nil, err
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Schedules().Create(ctx, sch); err != nil {
+		uow.Revert()
+		return nil, errors.Wrap(err, "schedule createing failed")
+	}
+
+	return sch, nil
+}
+
+// GetSchedule returns the schedule by id
+func (s *basicPaymentsService) GetSchedule(ctx context.Context, id int64) (*Schedule, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	sch, err := uow.Schedules().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schedule (%d) getting failed", id)
+	}
+
+	return sch, nil
+}
+
+// GetAccountSchedules returns schedules paying from the account
+func (s *basicPaymentsService) GetAccountSchedules(ctx context.Context, accID int64) ([]*Schedule, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	ss, err := uow.Schedules().GetByAccID(ctx, accID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) schedules getting failed", accID)
+	}
+
+	return ss, nil
+}
+
+// UpdateSchedule replaces the spec of the active schedule. The schedule is planned from scratch,
+// runs planned before now are skipped.
+func (s *basicPaymentsService) UpdateSchedule(ctx context.Context, id int64, spec ScheduleSpec) (*Schedule, error) {
+	lock := s.lockf.Make(scheduleLockKey(id))
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (schedule %d) locking failed", id)
+	}
+	defer lock.Unlock()
+
+	sch, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sch.Status != ScheduleStatusActive {
+		return nil, ErrScheduleNotActive
+	}
+
+	if err := s.applyScheduleSpec(ctx, sch, spec); err != nil {
+		return nil, err
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Schedules().Update(ctx, sch); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "schedule (%d) update failed", id)
+	}
+
+	return sch, nil
+}
+
+// CancelSchedule stops the active schedule
+func (s *basicPaymentsService) CancelSchedule(ctx context.Context, id int64) (*Schedule, error) {
+	lock := s.lockf.Make(scheduleLockKey(id))
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (schedule %d) locking failed", id)
+	}
+	defer lock.Unlock()
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	sch, err := uow.Schedules().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schedule (%d) getting failed", id)
+	}
+
+	if sch.Status != ScheduleStatusActive {
+		return nil, ErrScheduleNotActive
+	}
+
+	sch.Status = ScheduleStatusCancelled
+
+	if _, err := uow.Schedules().Update(ctx, sch); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "schedule (%d) update failed", id)
+	}
+
+	return sch, nil
+}
+
+// GetScheduleAttempts returns all attempts of the schedule from the oldest to the newest
+func (s *basicPaymentsService) GetScheduleAttempts(ctx context.Context, id int64) ([]*ScheduleAttempt, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Schedules().Get(ctx, id); err != nil {
+		return nil, errors.Wrapf(err, "schedule (%d) getting failed", id)
+	}
+
+	as, err := uow.ScheduleAttempts().GetByScheduleID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schedule (%d) attempts getting failed", id)
+	}
+
+	return as, nil
+}
+
+// RunDueSchedules makes transfers of the schedules whose time has come and returns the attempts.
+// Every schedule is locked while it's executed, so replicas never execute the same run concurrently.
+// Transfers are made with idempotency keys of the runs, a run is never paid twice.
+func (s *basicPaymentsService) RunDueSchedules(ctx context.Context) ([]*ScheduleAttempt, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+
+	due, err := uow.Schedules().GetDue(ctx, time.Now(), dueSchedulesLimit)
+	uow.Save()
+	if err != nil {
+		return nil, errors.Wrap(err, "due schedules getting failed")
+	}
+
+	attempts := make([]*ScheduleAttempt, 0, len(due))
+	for _, sch := range due {
+		a, err := s.runSchedule(ctx, int64(sch.ID))
+		if err != nil {
+			return attempts, err
+		}
+		if a != nil {
+			attempts = append(attempts, a)
+		}
+	}
+
+	return attempts, nil
+}
+
+// CreateWebhook subscribes the url to events. The secret of the webhook is generated.
+func (s *basicPaymentsService) CreateWebhook(ctx context.Context, spec WebhookSpec) (*Webhook, error) {
+	if err := checkWebhookSpec(spec); err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	w := &Webhook{
+		URL:      spec.URL,
+		Events:   spec.Events,
+		Accounts: spec.Accounts,
+		Secret:   secret,
+	}
+
+	if _, err := uow.Webhooks().Create(ctx, w); err != nil {
+		uow.Revert()
+		return nil, errors.Wrap(err, "webhook createing failed")
+	}
+
+	return w, nil
+}
+
+// GetWebhook returns the webhook by id
+func (s *basicPaymentsService) GetWebhook(ctx context.Context, id int64) (*Webhook, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	w, err := uow.Webhooks().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "webhook (%d) getting failed", id)
+	}
+
+	return w, nil
+}
+
+// GetWebhooks returns all webhooks
+func (s *basicPaymentsService) GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	ws, err := uow.Webhooks().GetAll(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhooks getting failed")
+	}
+
+	return ws, nil
+}
+
+// UpdateWebhook replaces the url and the filters of the webhook. Planned deliveries are sent to the new url.
+func (s *basicPaymentsService) UpdateWebhook(ctx context.Context, id int64, spec WebhookSpec) (*Webhook, error) {
+	if err := checkWebhookSpec(spec); err != nil {
+		return nil, err
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	w, err := uow.Webhooks().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "webhook (%d) getting failed", id)
+	}
+
+	w.URL = spec.URL
+	w.Events = spec.Events
+	w.Accounts = spec.Accounts
+
+	if _, err := uow.Webhooks().Update(ctx, w); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "webhook (%d) update failed", id)
+	}
+
+	return w, nil
+}
+
+// DeleteWebhook unsubscribes the webhook. Its pending deliveries are dropped.
+func (s *basicPaymentsService) DeleteWebhook(ctx context.Context, id int64) error {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Webhooks().Get(ctx, id); err != nil {
+		return errors.Wrapf(err, "webhook (%d) getting failed", id)
+	}
+
+	if err := uow.Webhooks().Delete(ctx, id); err != nil {
+		uow.Revert()
+		return errors.Wrapf(err, "webhook (%d) deleting failed", id)
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns deliveries of the webhook from the newest to the oldest
+func (s *basicPaymentsService) GetWebhookDeliveries(ctx context.Context, id int64) ([]*WebhookDelivery, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.Webhooks().Get(ctx, id); err != nil {
+		return nil, errors.Wrapf(err, "webhook (%d) getting failed", id)
+	}
+
+	ds, err := uow.WebhookDeliveries().GetByWebhookID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "webhook (%d) deliveries getting failed", id)
+	}
+
+	return ds, nil
+}
+
+// RedeliverWebhook plans the delivered or dead delivery again with a fresh number of attempts
+func (s *basicPaymentsService) RedeliverWebhook(ctx context.Context, deliveryID int64) (*WebhookDelivery, error) {
+	lock := s.lockf.Make(webhooksLockKey)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "mutex (webhooks) locking failed")
+	}
+	defer lock.Unlock()
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	d, err := uow.WebhookDeliveries().Get(ctx, deliveryID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "webhook delivery (%d) getting failed", deliveryID)
+	}
+
+	if d.Status == WebhookDeliveryPending {
+		return nil, ErrWebhookDeliveryPending
+	}
+
+	if _, err := uow.Webhooks().Get(ctx, int64(d.WebhookID)); err != nil {
+		return nil, errors.Wrapf(err, "webhook (%d) getting failed", d.WebhookID)
+	}
+
+	d.Status = WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+
+	if _, err := uow.WebhookDeliveries().Update(ctx, d); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "webhook delivery (%d) update failed", deliveryID)
+	}
+
+	return d, nil
+}
+
+// QuoteFee returns the fee of the operation of the type without making the operation
+func (s *basicPaymentsService) QuoteFee(ctx context.Context, t OperationType, currency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	c, err := s.currencies.Get(currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	if err := c.CheckAmount(amount); err != nil {
+		return decimal.Zero, err
+	}
+
+	return s.fees.Fee(t, c, amount), nil
+}
+
+// QuoteExchange locks the current exchange rate of the currency pair for the ttl
+func (s *basicPaymentsService) QuoteExchange(ctx context.Context, from, to string, ttl time.Duration) (*FXQuote, error) {
+	if ttl <= 0 {
+		ttl = DefaultFXQuoteTTL
+	}
+
+	src, err := s.currencies.Get(from)
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := s.currencies.Get(to)
+	if err != nil {
+		return nil, err
+	}
+
+	if src.Code == dst.Code {
+		return nil, ErrSameCurrencies
+	}
+
+	rate, err := s.fx.Rate(ctx, src.Code, dst.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	q := &FXQuote{
+		From:      src.Code,
+		To:        dst.Code,
+		Rate:      rate,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if _, err := uow.FXQuotes().Create(ctx, q); err != nil {
+		uow.Revert()
+		return nil, errors.Wrap(err, "quote createing failed")
+	}
+
+	return q, nil
+}
+
+// MakeExchangeTransfer sends money between accounts with different currencies by the quoted rate.
+// The operation has two legs: the donor pays the treasury of the source currency
+// and the treasury of the target currency pays the recipient. The target amount is rounded down.
+func (s *basicPaymentsService) MakeExchangeTransfer(ctx context.Context, from, to int64, amount decimal.Decimal, quoteID int64) (*Operation, error) {
+	q, err := s.getQuote(ctx, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkAmount(q.From, amount); err != nil {
+		return nil, err
+	}
+
+	dst, err := s.currencies.Get(q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := amount.Mul(q.Rate).Truncate(dst.Precision)
+	if !converted.IsPositive() {
+		return nil, ErrExchangeTooSmall
+	}
+
+	srcTreasury, err := s.systemAccount(ctx, AccountRoleTreasury, q.From)
+	if err != nil {
+		return nil, err
+	}
+
+	dstTreasury, err := s.systemAccount(ctx, AccountRoleTreasury, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := []Transaction{
+		{
+			From:     from,
+			To:       srcTreasury.ID,
+			Currency: q.From,
+			Amount:   amount,
+		},
+		{
+			From:     dstTreasury.ID,
+			To:       to,
+			Currency: q.To,
+			Amount:   converted,
+		},
+	}
+
+	accIDs := participants(txs)
+
+	lock := s.getLock(accIDs...)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%v) locking failed", accIDs)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	q, err = uow.FXQuotes().Get(ctx, quoteID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "quote (%d) getting failed", quoteID)
+	}
+
+	if q.OperationID != nil {
+		return nil, ErrFXQuoteAlreadyUsed
+	}
+
+	if time.Now().After(q.ExpiresAt) {
+		return nil, ErrFXQuoteExpired
+	}
+
+	o := &Operation{
+		Type:         OperationTypeExchange,
+		Transactions: txs,
+		Participants: accIDs,
+		QuoteID:      &q.ID,
+		Rate:         &q.Rate,
+	}
+
+	if err := s.createOperation(ctx, uow, o); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	q.OperationID = &o.ID
+
+	if _, err := uow.FXQuotes().Update(ctx, q); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "quote (%d) update failed", q.ID)
+	}
+
+	return o, nil
+}
+
+// CheckLedger verifies the ledger invariants: the sum of all postings in every currency is zero
+// and the cached balance of every account is equal to the sum of its postings
+func (s *basicPaymentsService) CheckLedger(ctx context.Context) error {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	totals, err := uow.Postings().Totals(ctx)
+	if err != nil {
+		return errors.Wrap(err, "postings totals getting failed")
+	}
+
+	for currency, total := range totals {
+		if !total.IsZero() {
+			return errors.Wrapf(ErrLedgerUnbalanced, "sum of %s postings is %s", currency, total)
+		}
+	}
+
+	balances, err := uow.Postings().Balances(ctx)
+	if err != nil {
+		return errors.Wrap(err, "postings balances getting failed")
+	}
+
+	accs, err := uow.Accounts().GetAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "accounts getting failed")
+	}
+
+	for _, a := range accs {
+		if b := balances[a.ID]; !a.Amount.Equal(b) {
+			return errors.Wrapf(ErrLedgerUnbalanced, "account (%d) amount is %s, postings sum is %s", a.ID, a.Amount, b)
+		}
+	}
+
+	return nil
+}
+
+// ─── HELPER METHODS ─────────────────────────────────────────────────────────────
+
+// getHold loads the hold in a separate uow context
+func (s *basicPaymentsService) getHold(ctx context.Context, id int64) (*Hold, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	h, err := uow.Holds().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "hold (%d) getting failed", id)
+	}
+
+	return h, nil
+}
+
+// releaseHold returns the held money of an active hold back to the available balance of the account.
+// The account must be locked by the caller.
+func (s *basicPaymentsService) releaseHold(ctx context.Context, uow UOWPayments, holdID int64) (*Hold, error) {
+	h, err := uow.Holds().Get(ctx, holdID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "hold (%d) getting failed", holdID)
+	}
+
+	if h.Status != HoldStatusActive {
+		return nil, ErrHoldNotActive
+	}
+
+	a, err := uow.Accounts().Get(ctx, h.AccountID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", h.AccountID)
+	}
+
+	a.Held = a.Held.Sub(h.Amount)
+
+	if _, err := uow.Accounts().Update(ctx, a); err != nil {
+		return nil, errors.Wrapf(err, "account (%d) update failed", a.ID)
+	}
+
+	return h, nil
+}
+
+// closeHold releases the hold and marks it with the given status
+func (s *basicPaymentsService) closeHold(ctx context.Context, holdID int64, status HoldStatus) (*Hold, error) {
+	h, err := s.getHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := s.getLock(h.AccountID)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d) locking failed", h.AccountID)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	h, err = s.releaseHold(ctx, uow, holdID)
+	if err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	h.Status = status
+
+	if _, err := uow.Holds().Update(ctx, h); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "hold (%d) update failed", h.ID)
+	}
+
+	return h, nil
+}
+
+// findReplay returns the operation created earlier with the same idempotency key.
+// Returns nil if the key is empty or wasn't used yet.
+func (s *basicPaymentsService) findReplay(ctx context.Context, uow UOWPayments, key, hash string) (*Operation, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	o, err := uow.Operations().GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		if errors.Cause(err) == ErrOperationNotFound {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "operation getting by idempotency key failed")
+	}
+
+	if o.RequestHash != hash {
+		return nil, ErrIdempotencyKeyConflict
+	}
+
+	return o, nil
+}
+
+// keyOrNil converts an empty idempotency key to NULL
+func keyOrNil(key string) *string {
+	if key == "" {
+		return nil
+	}
+
+	return &key
+}
+
+// getQuote loads the quote in a separate uow context
+func (s *basicPaymentsService) getQuote(ctx context.Context, id int64) (*FXQuote, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	q, err := uow.FXQuotes().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "quote (%d) getting failed", id)
+	}
+
+	return q, nil
+}
+
+// getOperation loads the operation in a separate uow context
+func (s *basicPaymentsService) getOperation(ctx context.Context, id int64) (*Operation, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	o, err := uow.Operations().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "operation (%d) getting failed", id)
+	}
+
+	return o, nil
+}
+
+// createOperation saves the operation and applies its transactions to the ledger.
+// Every transaction produces a debit posting for the donor and a credit posting for the recipient,
+// cached balances of the accounts are updated with the running balances of the postings.
+// Balances of user accounts are checked after all transactions are applied.
+func (s *basicPaymentsService) createOperation(ctx context.Context, uow UOWPayments, o *Operation) error {
+	if _, err := uow.Operations().Create(ctx, o); err != nil {
+		// A concurrent request with the same key locked other accounts, so findReplay didn't see its operation
+		if errors.Cause(err) == ErrDuplicateIdempotencyKey {
+			return errors.Wrap(ErrIdempotencyKeyConflict, "operation createing failed")
+		}
+		return errors.Wrap(err, "operation createing failed")
+	}
+
+	accs := map[int64]*Account{}
+	getAcc := func(id int64, currency string) (*Account, error) {
+		a, ok := accs[id]
+		if !ok {
+			var err error
+			if a, err = uow.Accounts().Get(ctx, id); err != nil {
+				return nil, errors.Wrapf(err, "account (%d) getting failed", id)
+			}
+			accs[id] = a
+		}
+
+		if a.Currency != currency {
+			return nil, ErrDifferentCurrencies
+		}
+
+		return a, nil
+	}
+
+	postings := make([]*Posting, 0, len(o.Transactions)*2)
+	for _, t := range o.Transactions {
+		from, err := getAcc(t.From, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		to, err := getAcc(t.To, t.Currency)
+		if err != nil {
+			return err
+		}
+
+		if err := from.checkStatus(o.Type == OperationTypeClosure); err != nil {
+			return err
+		}
+
+		if err := to.checkStatus(false); err != nil {
+			return err
+		}
+
+		from.Amount = from.Amount.Sub(t.Amount)
+		postings = append(postings, &Posting{
+			OperationID:   o.ID,
+			TransactionID: t.ID,
+			AccountID:     from.ID,
+			Currency:      t.Currency,
+			Amount:        t.Amount.Neg(),
+			Balance:       from.Amount,
+		})
+
+		to.Amount = to.Amount.Add(t.Amount)
+		postings = append(postings, &Posting{
+			OperationID:   o.ID,
+			TransactionID: t.ID,
+			AccountID:     to.ID,
+			Currency:      t.Currency,
+			Amount:        t.Amount,
+			Balance:       to.Amount,
+		})
+	}
+
+	for _, a := range accs {
+		if !a.IsSystem() && a.Available().IsNegative() {
+			return ErrBalanceTooLow
+		}
+	}
+
+	for _, p := range postings {
+		if _, err := uow.Postings().Create(ctx, p); err != nil {
+			return errors.Wrapf(err, "posting (%d) createing failed", p.AccountID)
+		}
+	}
+
+	ids := make([]int64, 0, len(accs))
+	for id, a := range accs {
+		if _, err := uow.Accounts().Update(ctx, a); err != nil {
+			return errors.Wrapf(err, "account (%d) update failed", a.ID)
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if err := emit(ctx, uow, EventOperationCompleted, id, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changeStatus moves the account from one status to another and records the change
+func (s *basicPaymentsService) changeStatus(ctx context.Context, id int64, from, to AccountStatus, reason string) (*Account, error) {
+	lock := s.getLock(id)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%d) locking failed", id)
+	}
+	defer lock.Unlock()
+	ctx = lock.Fence(ctx)
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	a, err := uow.Accounts().Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account (%d) getting failed", id)
+	}
+
+	if a.IsSystem() {
+		return nil, ErrSystemAccount
+	}
+
+	if a.Status != from {
+		switch a.Status {
+		case AccountStatusClosed:
+			return nil, ErrAccountClosed
+		case AccountStatusFrozen:
+			return nil, ErrAccountFrozen
+		}
+		return nil, ErrAccountNotFrozen
+	}
+
+	if err := s.setStatus(ctx, uow, a, to, reason, nil); err != nil {
+		uow.Revert()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// setStatus updates the status of the account and appends the change to the status history
+func (s *basicPaymentsService) setStatus(ctx context.Context, uow UOWPayments, a *Account, status AccountStatus, reason string, opID *uint) error {
+	c := &AccountStatusChange{
+		AccountID:   a.ID,
+		From:        a.Status,
+		To:          status,
+		Reason:      reason,
+		OperationID: opID,
+	}
+
+	if _, err := uow.StatusChanges().Create(ctx, c); err != nil {
+		return errors.Wrapf(err, "account (%d) status change createing failed", a.ID)
+	}
+
+	a.Status = status
+	if _, err := uow.Accounts().Update(ctx, a); err != nil {
+		return errors.Wrapf(err, "account (%d) update failed", a.ID)
+	}
+
+	return emit(ctx, uow, EventAccountStatusChanged, a.ID, c)
+}
+
+// applyScheduleSpec checks the spec and plans the schedule by it
+func (s *basicPaymentsService) applyScheduleSpec(ctx context.Context, sch *Schedule, spec ScheduleSpec) error {
+	if spec.From == spec.To {
+		return ErrSameAccount
+	}
+
+	currency, err := s.checkAmount(spec.Currency, spec.Amount)
+	if err != nil {
+		return err
+	}
+
+	switch spec.Period {
+	case SchedulePeriodOnce, SchedulePeriodDay, SchedulePeriodWeek, SchedulePeriodMonth:
+	default:
+		return ErrSchedulePeriod
+	}
+
+	now := time.Now()
+	if spec.StartAt.IsZero() {
+		spec.StartAt = now
+	}
+	if spec.EndAt != nil && spec.EndAt.Before(spec.StartAt) {
+		return ErrScheduleEnd
+	}
+	if spec.Every <= 0 {
+		spec.Every = 1
+	}
+	if spec.RetryDelay <= 0 {
+		spec.RetryDelay = DefaultScheduleRetryDelay
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	for _, id := range []int64{spec.From, spec.To} {
+		a, err := uow.Accounts().Get(ctx, id)
+		if err != nil {
+			return errors.Wrapf(err, "account (%d) getting failed", id)
+		}
+		if a.Currency != currency {
+			return ErrDifferentCurrencies
+		}
+		if a.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+	}
+
+	sch.From = spec.From
+	sch.To = spec.To
+	sch.Currency = currency
+	sch.Amount = spec.Amount
+	sch.Period = spec.Period
+	sch.Every = spec.Every
+	sch.StartAt = spec.StartAt
+	sch.EndAt = spec.EndAt
+	sch.MaxRetries = spec.MaxRetries
+	sch.RetryDelay = spec.RetryDelay
+	sch.reschedule(now)
+
+	return nil
+}
+
+// runSchedule makes the transfer of the current run of the due schedule and records the attempt.
+// Failed runs are retried, runs which can never succeed fail the schedule.
+func (s *basicPaymentsService) runSchedule(ctx context.Context, id int64) (*ScheduleAttempt, error) {
+	lock := s.lockf.Make(scheduleLockKey(id))
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (schedule %d) locking failed", id)
+	}
+	defer lock.Unlock()
+
+	sch, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another replica could execute the run while the lock was awaited
+	now := time.Now()
+	if sch.Status != ScheduleStatusActive || sch.NextAttemptAt.After(now) {
+		return nil, nil
+	}
+
+	a := &ScheduleAttempt{
+		ScheduleID: sch.ID,
+		Occurrence: sch.Occurrence,
+		RunAt:      sch.RunAt(),
+	}
+
+	// Updates replan the schedule, so the key has to tell apart runs planned within the same second
+	key := fmt.Sprintf("schedule:%d:%d", sch.ID, a.RunAt.UnixNano())
+	o, err := s.MakeTransfer(WithIdempotencyKey(ctx, key), sch.From, sch.To, sch.Currency, sch.Amount)
+	switch {
+	case err == nil:
+		a.OperationID = &o.ID
+		sch.advance()
+	case isPermanent(err):
+		a.Error = err.Error()
+		sch.Status = ScheduleStatusFailed
+	default:
+		a.Error = err.Error()
+		sch.retry(now)
+	}
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.ScheduleAttempts().Create(ctx, a); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "schedule (%d) attempt createing failed", id)
+	}
+
+	if _, err := uow.Schedules().Update(ctx, sch); err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "schedule (%d) update failed", id)
+	}
+
+	return a, nil
+}
+
+// isPermanent reports whether retries of the failed operation are pointless
+func isPermanent(err error) bool {
+	switch KindOf(err) {
+	case ErrorKindValidation, ErrorKindNotFound:
+		return true
+	}
+
+	cause := errors.Cause(err)
+	return cause == ErrAccountClosed || cause == ErrIdempotencyKeyConflict
+}
+
+func scheduleLockKey(id int64) string {
+	return fmt.Sprintf("schedule:%d", id)
+}
+
+// GetSystemAccounts returns system accounts of all currencies with their balances
+func (s *basicPaymentsService) GetSystemAccounts(ctx context.Context) ([]*Account, error) {
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	a, err := uow.Accounts().GetSystem(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "system accounts getting failed")
+	}
+
+	return a, nil
+}
+
+// GetCurrencies returns all currencies known to the system
+func (s *basicPaymentsService) GetCurrencies(ctx context.Context) ([]*Currency, error) {
+	return s.currencies.All(), nil
+}
+
+// checkAmount normalizes the currency code and checks that the amount is positive and fits its precision.
+// Negative amounts would reverse the direction of the money.
+func (s *basicPaymentsService) checkAmount(currency string, amount decimal.Decimal) (string, error) {
+	if !amount.IsPositive() {
+		return "", errors.Wrapf(ErrAmountNotPositive, "got %s", amount)
+	}
+
+	c, err := s.currencies.Get(currency)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.CheckAmount(amount); err != nil {
+		return "", err
+	}
+
+	return c.Code, nil
+}
+
+// withFee appends the fee leg of the main transaction to the transactions.
+// The fee is paid by the payer to the fees account of the currency.
+func (s *basicPaymentsService) withFee(ctx context.Context, t OperationType, payer int64, txs []Transaction) ([]Transaction, error) {
+	main := txs[0]
+
+	c, err := s.currencies.Get(main.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	fee := s.fees.Fee(t, c, main.Amount)
+	if !fee.IsPositive() {
+		return txs, nil
+	}
+
+	fees, err := s.systemAccount(ctx, AccountRoleFees, c.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(txs, Transaction{
+		From:     payer,
+		To:       fees.ID,
+		Currency: c.Code,
+		Amount:   fee,
+	}), nil
+}
+
+// systemAccount returns the system account with the role for the currency.
+// Accounts of currencies which appeared after the startup are created on the first use.
+func (s *basicPaymentsService) systemAccount(ctx context.Context, role AccountRole, currency string) (*Account, error) {
+	lock := s.lockf.Make(fmt.Sprintf("system:%s:%s", role, currency))
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "mutex (%s, %s) locking failed", role, currency)
+	}
+	defer lock.Unlock()
+
+	uow, err := s.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	a, err := uow.Accounts().GetByRole(ctx, role, currency)
+	if err == nil {
+		return a, nil
+	}
+
+	if errors.Cause(err) != ErrAccountNotFound {
+		return nil, errors.Wrapf(err, "%s account (%s) getting failed", role, currency)
+	}
+
+	a, err = uow.Accounts().Create(ctx, &Account{
+		Name:     string(role),
+		Currency: currency,
+		Role:     role,
+	})
+	if err != nil {
+		uow.Revert()
+		return nil, errors.Wrapf(err, "%s account (%s) createing failed", role, currency)
+	}
+
+	return a, nil
+}
+
+// participants returns unique ids of the accounts participating in the transactions
+func participants(txs []Transaction) []int64 {
+	ids := []int64{}
+	seen := map[int64]bool{}
+	for _, t := range txs {
+		for _, id := range []int64{t.From, t.To} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
 
-// getLocksKeys sorts account ids and converts them to string
-func (s *basicPaymentsService) getLocksKeys(accIDs ...int64) []string {
-	sort.Slice(accIDs, func(i, j int) bool { return accIDs[i] < accIDs[j] })
+// getLocksIDs sorts unique account ids
+func (s *basicPaymentsService) getLocksIDs(accIDs ...int64) []int64 {
+	sorted := append([]int64{}, accIDs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
 
-	strIDs := make([]string, len(accIDs))
-	for i, id := range accIDs {
-		strIDs[i] = strconv.FormatInt(id, 10)
+	ids := make([]int64, 0, len(sorted))
+	for i, id := range sorted {
+		if i > 0 && sorted[i-1] == id {
+			continue
+		}
+		ids = append(ids, id)
 	}
 
-	return strIDs
+	return ids
 }
 
-func (s *basicPaymentsService) getLock(accIDs ...int64) Lock {
-	keys := s.getLocksKeys(accIDs...)
-	locks := make([]Lock, len(keys))
+// getLock returns the lock of the accounts. Its Fence makes writes of the accounts rows
+// check tokens of the locks.
+func (s *basicPaymentsService) getLock(accIDs ...int64) *accountsLock {
+	ids := s.getLocksIDs(accIDs...)
+	locks := make([]Lock, len(ids))
 
-	for i, key := range keys {
-		locks[i] = s.lockf.Make(key)
+	for i, id := range ids {
+		locks[i] = s.lockf.Make(strconv.FormatInt(id, 10))
 	}
 
-	return NewLockPool(locks)
+	return &accountsLock{Lock: NewLockPool(locks), ids: ids, locks: locks}
 }
 
 // New returns a PaymentsService with all of the expected middleware wired in.
-func New(lockf LockFactory, uowf UOWPaymentsFactory, middleware []Middleware) PaymentsService {
-	var svc PaymentsService = NewBasicPaymentsService(lockf, uowf)
+func New(lockf LockFactory, uowf UOWPaymentsFactory, currencies CurrencyRegistry, fx FXRateProvider, fees *FeeSchedule, middleware []Middleware) PaymentsService {
+	var svc PaymentsService = NewBasicPaymentsService(lockf, uowf, currencies, fx, fees)
 	for _, m := range middleware {
 		svc = m(svc)
 	}
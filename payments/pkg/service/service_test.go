@@ -2,13 +2,25 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/service/migrations"
 )
 
 //
@@ -23,16 +35,162 @@ func getDB() *gorm.DB {
 		panic(err)
 	}
 
+	if _, err := migrations.Up(db); err != nil {
+		panic(err)
+	}
+
 	if err := InitModels(db); err != nil {
 		panic(err)
 	}
 	db.Exec("DELETE FROM accounts;")
 	db.Exec("DELETE FROM operations;")
+	db.Exec("DELETE FROM operation_participants;")
 	db.Exec("DELETE FROM transactions;")
+	db.Exec("DELETE FROM holds;")
+	db.Exec("DELETE FROM postings;")
+	db.Exec("DELETE FROM fx_quotes;")
+	db.Exec("DELETE FROM account_status_changes;")
+	db.Exec("DELETE FROM schedules;")
+	db.Exec("DELETE FROM schedule_attempts;")
+	db.Exec("DELETE FROM events;")
+	db.Exec("DELETE FROM webhooks;")
+	db.Exec("DELETE FROM webhook_deliveries;")
+	// Fixtures use small explicit ids, system accounts are created by the sequence
+	db.Exec("ALTER SEQUENCE accounts_id_seq RESTART WITH 1000;")
 
 	return db
 }
 
+// getSQLite returns a new sqlite database in a temporary directory and the function which removes it
+func getSQLite() (*gorm.DB, func()) {
+	dir, err := ioutil.TempDir("", "payments")
+	if err != nil {
+		panic(err)
+	}
+
+	dsn := "file:" + filepath.Join(dir, "test.db") + "?_txlock=immediate&_busy_timeout=10000&_journal_mode=WAL"
+	db, err := gorm.Open("sqlite3", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := migrations.Up(db); err != nil {
+		panic(err)
+	}
+
+	if err := InitModels(db); err != nil {
+		panic(err)
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// testStorageEnv selects the storage of the tests. The tests run against the in-memory storage
+// by default, "postgres" runs them against postgresql and redis of docker-compose,
+// "sqlite" runs them against a temporary sqlite database.
+const testStorageEnv = "PAYMENTS_TEST_STORAGE"
+
+func usePostgres() bool {
+	return os.Getenv(testStorageEnv) == "postgres"
+}
+
+// testStorage is a clean storage for a test
+type testStorage struct {
+	lockf LockFactory
+	uowf  UOWPaymentsFactory
+	close func()
+}
+
+func getStorage() *testStorage {
+	switch os.Getenv(testStorageEnv) {
+	case "postgres":
+	case "sqlite":
+		db, remove := getSQLite()
+		return &testStorage{
+			lockf: NewMemoryLockFactory(),
+			uowf:  NewUOWPaymentsFactory(db),
+			close: remove,
+		}
+	default:
+		return &testStorage{
+			lockf: NewMemoryLockFactory(),
+			uowf:  NewMemoryUOWPaymentsFactory(),
+			close: func() {},
+		}
+	}
+
+	db := getDB()
+	redis := getRedis()
+
+	return &testStorage{
+		lockf: NewLockFactory(redis),
+		uowf:  NewUOWPaymentsFactory(db),
+		close: func() {
+			redis.Close()
+			db.Close()
+		},
+	}
+}
+
+func (st *testStorage) Close() {
+	st.close()
+}
+
+// do runs f in a unit of work, changes are saved if f succeeds
+func (st *testStorage) do(f func(uow UOWPayments) error) error {
+	uow, err := st.uowf.Make()
+	if err != nil {
+		return err
+	}
+
+	if err := f(uow); err != nil {
+		uow.Revert()
+		return err
+	}
+
+	return uow.Save()
+}
+
+// save writes account fixtures as is. Accounts without ids get them from the storage.
+func (st *testStorage) save(as ...*Account) error {
+	return st.do(func(uow UOWPayments) error {
+		for _, a := range as {
+			var err error
+			if a.ID == 0 {
+				_, err = uow.Accounts().Create(nil, a)
+			} else {
+				_, err = uow.Accounts().Update(nil, a)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// setAmount changes the balance of the account bypassing the ledger
+func (st *testStorage) setAmount(id int64, amount decimal.Decimal) error {
+	return st.do(func(uow UOWPayments) error {
+		a, err := uow.Accounts().Get(nil, id)
+		if err != nil {
+			return err
+		}
+
+		a.Amount = amount
+		_, err = uow.Accounts().Update(nil, a)
+		return err
+	})
+}
+
+// testCurrencies extends the bundled table with crypto assets used in the tests
+var testCurrencies = NewCurrencyRegistry(
+	&Currency{Code: "BTC", Name: "Bitcoin", Precision: 8},
+)
+
 func getRedis() *redis.Pool {
 	pool := &redis.Pool{
 		MaxIdle:     1,
@@ -64,9 +222,10 @@ func Test_basicPaymentsService_CreateAccount(t *testing.T) {
 		currency string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name         string
+		args         args
+		wantCurrency string
+		wantErr      bool
 	}{
 		{
 			name: "simple create",
@@ -74,20 +233,35 @@ func Test_basicPaymentsService_CreateAccount(t *testing.T) {
 				name:     "test",
 				currency: "USD",
 			},
+			wantCurrency: "USD",
+		},
+		{
+			name: "normalized currency",
+			args: args{
+				name:     "test",
+				currency: " btc ",
+			},
+			wantCurrency: "BTC",
+		},
+		{
+			name: "unknown currency",
+			args: args{
+				name:     "test",
+				currency: "XYZ",
+			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 
 		t.Run(tt.name, func(t *testing.T) {
-			db := getDB()
-			defer db.Close()
-
-			redis := getRedis()
-			defer redis.Close()
+			st := getStorage()
+			defer st.Close()
 
 			s := &basicPaymentsService{
-				lockf: NewLockFactory(redis),
-				uowf:  NewUOWPaymentsFactory(db),
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
 			}
 
 			got, err := s.CreateAccount(tt.args.ctx, tt.args.name, tt.args.currency)
@@ -95,9 +269,12 @@ func Test_basicPaymentsService_CreateAccount(t *testing.T) {
 				t.Errorf("basicPaymentsService.CreateAccount() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 
 			assert.NotEqual(t, 0, got.ID, "basicPaymentsService.CreateAccount() error: ID is 0")
-			assert.Equal(t, tt.args.currency, got.Currency, "basicPaymentsService.CreateAccount() error: currencies aren't equal")
+			assert.Equal(t, tt.wantCurrency, got.Currency, "basicPaymentsService.CreateAccount() error: currencies aren't equal")
 			assert.Equal(t, tt.args.name, got.Name, "basicPaymentsService.CreateAccount() error: names aren't equal")
 
 		})
@@ -142,33 +319,31 @@ func Test_basicPaymentsService_GetAccount(t *testing.T) {
 
 			// Init fixtures
 
-			db := getDB()
-			defer db.Close()
-
-			redis := getRedis()
-			defer redis.Close()
+			st := getStorage()
+			defer st.Close()
 
-			err := db.Save(&Account{
+			err := st.save(&Account{
 				ID:       1,
 				Name:     "test",
 				Currency: "USD",
-			}).Error
+			})
 
 			assert.NoError(t, err)
 
-			err = db.Save(&Account{
+			err = st.save(&Account{
 				ID:       2,
 				Name:     "test",
 				Currency: "USD",
-			}).Error
+			})
 
 			assert.NoError(t, err)
 
 			// End of initing fixtures
 
 			s := &basicPaymentsService{
-				lockf: NewLockFactory(redis),
-				uowf:  NewUOWPaymentsFactory(db),
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
 			}
 
 			got, err := s.GetAccount(tt.args.ctx, tt.args.id)
@@ -201,8 +376,8 @@ func Test_basicPaymentsService_GetAccounts(t *testing.T) {
 			name: "simple getting",
 			args: args{},
 			want: []*Account{
-				{ID: 1, Name: "test1", Currency: "USD", Amount: decimal.Zero},
-				{ID: 2, Name: "test2", Currency: "BTC", Amount: decimal.Zero},
+				{ID: 1, Name: "test1", Currency: "USD", Amount: decimal.Zero, Status: AccountStatusActive},
+				{ID: 2, Name: "test2", Currency: "BTC", Amount: decimal.Zero, Status: AccountStatusActive},
 			},
 		},
 	}
@@ -210,29 +385,27 @@ func Test_basicPaymentsService_GetAccounts(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 
-			db := getDB()
-			defer db.Close()
-
-			redis := getRedis()
-			defer redis.Close()
+			st := getStorage()
+			defer st.Close()
 
 			// Init fixtures
 			for _, a := range tt.want {
-				err := db.Save(&Account{
+				err := st.save(&Account{
 					ID:       a.ID,
 					Name:     a.Name,
 					Currency: a.Currency,
-				}).Error
+				})
 
 				assert.NoError(t, err)
 			}
 
 			s := &basicPaymentsService{
-				lockf: NewLockFactory(redis),
-				uowf:  NewUOWPaymentsFactory(db),
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
 			}
 
-			got, err := s.GetAccounts(tt.args.ctx)
+			p, err := s.GetAccounts(tt.args.ctx, AccountsFilter{})
 			if err != nil {
 				if (err != nil) != tt.wantErr {
 					t.Errorf("basicPaymentsService.GetAccounts() error = %v, wantErr %v", err, tt.wantErr)
@@ -240,11 +413,14 @@ func Test_basicPaymentsService_GetAccounts(t *testing.T) {
 				return
 			}
 
+			got := p.Accounts
 			for _, a := range got {
 				a.CreatedAt = time.Time{}
 				a.UpdatedAt = time.Time{}
 				assert.True(t, a.Amount.Equal(decimal.Zero))
 				a.Amount = decimal.Zero
+				assert.True(t, a.Held.Equal(decimal.Zero))
+				a.Held = decimal.Decimal{}
 			}
 
 			assert.ElementsMatch(t, got, tt.want)
@@ -294,27 +470,25 @@ func Test_basicPaymentsService_MakeTransfer(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 
-			db := getDB()
-			defer db.Close()
-
-			redis := getRedis()
-			defer redis.Close()
+			st := getStorage()
+			defer st.Close()
 
 			// Init fixtures
 			for _, a := range tt.want {
-				err := db.Save(&Account{
+				err := st.save(&Account{
 					ID:       a.ID,
 					Name:     a.Name,
 					Currency: a.Currency,
 					Amount:   decimal.RequireFromString("15"),
-				}).Error
+				})
 
 				assert.NoError(t, err)
 			}
 
 			s := &basicPaymentsService{
-				lockf: NewLockFactory(redis),
-				uowf:  NewUOWPaymentsFactory(db),
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
 			}
 
 			_, err := tt.action(s)
@@ -342,3 +516,2335 @@ func Test_basicPaymentsService_MakeTransfer(t *testing.T) {
 		})
 	}
 }
+
+// Transfers to the donor account itself would only charge fees
+func Test_basicPaymentsService_sameAccount(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	assert.NoError(t, st.save(&Account{ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")}))
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	amount := decimal.RequireFromString("5")
+
+	_, err := s.MakeTransfer(nil, 1, 1, "USD", amount)
+	assert.Equal(t, ErrSameAccount, errors.Cause(err))
+
+	_, err = s.MakeBatchTransfer(nil, []TransferLeg{{From: 1, To: 1, Currency: "USD", Amount: amount}})
+	assert.Equal(t, ErrSameAccount, errors.Cause(err))
+
+	_, err = s.CreateSchedule(nil, ScheduleSpec{From: 1, To: 1, Currency: "USD", Amount: amount, Period: SchedulePeriodOnce})
+	assert.Equal(t, ErrSameAccount, errors.Cause(err))
+
+	a, err := s.GetAccount(nil, 1)
+	assert.NoError(t, err)
+	assert.True(t, a.Amount.Equal(decimal.RequireFromString("15")), "Got: %s", a.Amount)
+}
+
+func Test_basicPaymentsService_MakeBatchTransfer(t *testing.T) {
+	leg := func(from, to int64, currency, amount string) TransferLeg {
+		return TransferLeg{From: from, To: to, Currency: currency, Amount: decimal.RequireFromString(amount)}
+	}
+
+	tests := []struct {
+		name    string
+		legs    []TransferLeg
+		want    map[int64]string
+		wantErr error
+	}{
+		{
+			name: "payroll",
+			legs: []TransferLeg{leg(1, 2, "USD", "5"), leg(1, 3, "usd", "7"), leg(2, 3, "USD", "1")},
+			want: map[int64]string{1: "3", 2: "19", 3: "23"},
+		},
+		{
+			name:    "all or nothing",
+			legs:    []TransferLeg{leg(1, 2, "USD", "10"), leg(1, 3, "USD", "10")},
+			want:    map[int64]string{1: "15", 2: "15", 3: "15"},
+			wantErr: ErrBalanceTooLow,
+		},
+		{
+			name:    "leg of another currency",
+			legs:    []TransferLeg{leg(1, 2, "USD", "1"), leg(1, 4, "USD", "1")},
+			want:    map[int64]string{1: "15", 2: "15", 4: "15"},
+			wantErr: ErrDifferentCurrencies,
+		},
+		{
+			name:    "empty batch",
+			want:    map[int64]string{1: "15"},
+			wantErr: ErrEmptyBatch,
+		},
+		{
+			name:    "negative leg",
+			legs:    []TransferLeg{leg(1, 2, "USD", "5"), leg(1, 3, "USD", "-5")},
+			want:    map[int64]string{1: "15", 2: "15", 3: "15"},
+			wantErr: ErrAmountNotPositive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for id, currency := range map[int64]string{1: "USD", 2: "USD", 3: "USD", 4: "EUR"} {
+				err := st.save(&Account{
+					ID:       id,
+					Name:     "test",
+					Currency: currency,
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			o, err := s.MakeBatchTransfer(nil, tt.legs)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			if err == nil {
+				assert.Equal(t, OperationTypeBatch, o.Type)
+				assert.Len(t, o.Transactions, len(tt.legs))
+			}
+
+			for id, want := range tt.want {
+				a, err := s.GetAccount(nil, id)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(decimal.RequireFromString(want)), "Got: %s; Want: %s", a.Amount, want)
+			}
+		})
+	}
+}
+
+func Test_legsTransactions(t *testing.T) {
+	leg := func(accID int64, currency, amount string) OperationLeg {
+		return OperationLeg{AccountID: accID, Currency: currency, Amount: decimal.RequireFromString(amount)}
+	}
+	tx := func(from, to int64, currency, amount string) string {
+		return fmt.Sprintf("%d->%d %s %s", from, to, currency, decimal.RequireFromString(amount))
+	}
+
+	tests := []struct {
+		name    string
+		legs    []OperationLeg
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "transfer",
+			legs: []OperationLeg{leg(1, "USD", "-10"), leg(2, "USD", "10")},
+			want: []string{tx(1, 2, "USD", "10")},
+		},
+		{
+			name: "marketplace payout",
+			legs: []OperationLeg{leg(1, "USD", "-100"), leg(2, "USD", "90"), leg(3, "USD", "7"), leg(4, "USD", "3")},
+			want: []string{tx(1, 2, "USD", "90"), tx(1, 3, "USD", "7"), tx(1, 4, "USD", "3")},
+		},
+		{
+			name: "split between payers",
+			legs: []OperationLeg{leg(1, "USD", "-6"), leg(2, "USD", "-4"), leg(3, "USD", "5"), leg(4, "USD", "5")},
+			want: []string{tx(1, 3, "USD", "5"), tx(1, 4, "USD", "1"), tx(2, 4, "USD", "4")},
+		},
+		{
+			name: "legs of the same account are summed",
+			legs: []OperationLeg{leg(1, "USD", "-10"), leg(1, "USD", "4"), leg(2, "USD", "6")},
+			want: []string{tx(1, 2, "USD", "6")},
+		},
+		{
+			name: "many currencies",
+			legs: []OperationLeg{leg(1, "USD", "-10"), leg(3, "EUR", "-5"), leg(2, "USD", "10"), leg(4, "EUR", "5")},
+			want: []string{tx(1, 2, "USD", "10"), tx(3, 4, "EUR", "5")},
+		},
+		{
+			name:    "unbalanced",
+			legs:    []OperationLeg{leg(1, "USD", "-10"), leg(2, "USD", "9")},
+			wantErr: ErrUnbalancedOperation,
+		},
+		{
+			name:    "balanced across currencies only",
+			legs:    []OperationLeg{leg(1, "USD", "-10"), leg(2, "EUR", "10")},
+			wantErr: ErrUnbalancedOperation,
+		},
+		{
+			name:    "nothing moves",
+			legs:    []OperationLeg{leg(1, "USD", "-10"), leg(1, "USD", "10")},
+			wantErr: ErrEmptyOperation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs, err := legsTransactions(tt.legs)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			if err != nil {
+				return
+			}
+
+			got := []string{}
+			for _, x := range txs {
+				got = append(got, tx(x.From, x.To, x.Currency, x.Amount.String()))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_basicPaymentsService_MakeOperation(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	// Init fixtures
+	for _, id := range []int64{1, 2, 3} {
+		err := st.save(&Account{
+			ID:       id,
+			Name:     "test",
+			Currency: "USD",
+			Amount:   decimal.RequireFromString("15"),
+		})
+
+		assert.NoError(t, err)
+	}
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	legs := []OperationLeg{
+		{AccountID: 1, Currency: "usd", Amount: decimal.RequireFromString("-10")},
+		{AccountID: 2, Currency: "USD", Amount: decimal.RequireFromString("9")},
+		{AccountID: 3, Currency: "USD", Amount: decimal.RequireFromString("1")},
+	}
+	o, err := s.MakeOperation(nil, legs)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, OperationTypeMultiLeg, o.Type)
+	assert.Len(t, o.Transactions, 2)
+
+	// The second debit exceeds the balance, nothing is applied
+	_, err = s.MakeOperation(nil, legs)
+	assert.Equal(t, ErrBalanceTooLow, errors.Cause(err))
+
+	for id, want := range map[int64]string{1: "5", 2: "24", 3: "16"} {
+		a, err := s.GetAccount(nil, id)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		assert.True(t, a.Amount.Equal(decimal.RequireFromString(want)), "Got: %s; Want: %s", a.Amount, want)
+	}
+}
+
+func Test_basicPaymentsService_MakeWithdrawal(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    map[int64]*Account
+		action  func(PaymentsService) (*Operation, error)
+		wantErr bool
+	}{
+		{
+			name: "simple withdrawal",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("10")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				return s.MakeWithdrawal(nil, 1, "USD", decimal.RequireFromString("5"))
+			},
+		},
+		{
+			name: "full withdrawal",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("0")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				return s.MakeWithdrawal(nil, 1, "USD", decimal.RequireFromString("15"))
+			},
+		},
+		{
+			name: "balance too low",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				return s.MakeWithdrawal(nil, 1, "USD", decimal.RequireFromString("16"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "different currencies",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				return s.MakeWithdrawal(nil, 1, "BTC", decimal.RequireFromString("5"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative amount",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				return s.MakeWithdrawal(nil, 1, "USD", decimal.RequireFromString("-5"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero amount",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				return s.MakeWithdrawal(nil, 1, "USD", decimal.Zero)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, a := range tt.want {
+				err := st.save(&Account{
+					ID:       a.ID,
+					Name:     a.Name,
+					Currency: a.Currency,
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			o, err := tt.action(s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService.MakeWithdrawal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr {
+				assert.Equal(t, OperationTypeWithdrawal, o.Type)
+			}
+
+			for _, wantAcc := range tt.want {
+				a, err := s.GetAccount(tt.args.ctx, wantAcc.ID)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(wantAcc.Amount), "Got: %s; Want: %s", a.Amount, wantAcc.Amount)
+			}
+		})
+	}
+}
+
+func Test_basicPaymentsService_ReverseOperation(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    map[int64]*Account
+		action  func(PaymentsService) (*Operation, error)
+		wantErr bool
+	}{
+		{
+			name: "simple reversal",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				o, err := s.MakeTransfer(nil, 2, 1, "USD", decimal.RequireFromString("5"))
+				if err != nil {
+					return nil, err
+				}
+				return s.ReverseOperation(nil, int64(o.ID), "mistake")
+			},
+		},
+		{
+			name: "double reversal",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				o, err := s.MakeTransfer(nil, 2, 1, "USD", decimal.RequireFromString("5"))
+				if err != nil {
+					return nil, err
+				}
+				if _, err := s.ReverseOperation(nil, int64(o.ID), "mistake"); err != nil {
+					return nil, err
+				}
+				return s.ReverseOperation(nil, int64(o.ID), "mistake")
+			},
+			wantErr: true,
+		},
+		{
+			name: "balance too low",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("0")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("30")},
+			},
+			action: func(s PaymentsService) (*Operation, error) {
+				o, err := s.MakeTransfer(nil, 2, 1, "USD", decimal.RequireFromString("15"))
+				if err != nil {
+					return nil, err
+				}
+				if _, err := s.MakeTransfer(nil, 1, 2, "USD", decimal.RequireFromString("30")); err != nil {
+					return nil, err
+				}
+				return s.ReverseOperation(nil, int64(o.ID), "mistake")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, a := range tt.want {
+				err := st.save(&Account{
+					ID:       a.ID,
+					Name:     a.Name,
+					Currency: a.Currency,
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			o, err := tt.action(s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService.ReverseOperation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr {
+				assert.Equal(t, OperationTypeReversal, o.Type)
+				assert.NotNil(t, o.ReversalOf)
+			}
+
+			for _, wantAcc := range tt.want {
+				a, err := s.GetAccount(tt.args.ctx, wantAcc.ID)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(wantAcc.Amount), "Got: %s; Want: %s", a.Amount, wantAcc.Amount)
+			}
+		})
+	}
+}
+
+func Test_basicPaymentsService_Idempotency(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    map[int64]*Account
+		action  func(PaymentsService) error
+		wantErr bool
+	}{
+		{
+			name: "deposit replay",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("20")},
+			},
+			action: func(s PaymentsService) error {
+				ctx := WithIdempotencyKey(context.Background(), "deposit-1")
+				o1, err := s.MakeDeposit(ctx, 1, "USD", decimal.RequireFromString("5"))
+				if err != nil {
+					return err
+				}
+				o2, err := s.MakeDeposit(ctx, 1, "USD", decimal.RequireFromString("5"))
+				if err != nil {
+					return err
+				}
+				if o1.ID != o2.ID {
+					return errors.Errorf("replay created new operation: %d != %d", o1.ID, o2.ID)
+				}
+				return nil
+			},
+		},
+		{
+			name: "transfer replay with another payload",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("10")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("20")},
+			},
+			action: func(s PaymentsService) error {
+				ctx := WithIdempotencyKey(context.Background(), "transfer-1")
+				if _, err := s.MakeTransfer(ctx, 1, 2, "USD", decimal.RequireFromString("5")); err != nil {
+					return err
+				}
+				_, err := s.MakeTransfer(ctx, 1, 2, "USD", decimal.RequireFromString("6"))
+				return err
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, a := range tt.want {
+				err := st.save(&Account{
+					ID:       a.ID,
+					Name:     a.Name,
+					Currency: a.Currency,
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			err := tt.action(s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService idempotency error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, wantAcc := range tt.want {
+				a, err := s.GetAccount(tt.args.ctx, wantAcc.ID)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(wantAcc.Amount), "Got: %s; Want: %s", a.Amount, wantAcc.Amount)
+			}
+		})
+	}
+}
+
+// ─── HOLDS ──────────────────────────────────────────────────────────────────────
+
+// Requests with the same key lock different accounts, so they aren't serialized by locks
+func Test_basicPaymentsService_Idempotency_concurrent(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	const workers = 4
+	for id := int64(1); id <= 2*workers; id++ {
+		assert.NoError(t, st.save(&Account{ID: id, Name: "test", Currency: "USD", Amount: decimal.RequireFromString("15")}))
+	}
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	ctx := WithIdempotencyKey(context.Background(), "shared")
+	start := make(chan struct{})
+	errs := make(chan error, workers)
+	for i := int64(0); i < workers; i++ {
+		go func(from, to int64) {
+			<-start
+			_, err := s.MakeTransfer(ctx, from, to, "USD", decimal.RequireFromString("5"))
+			errs <- err
+		}(2*i+1, 2*i+2)
+	}
+	close(start)
+
+	// One request wins, the others conflict with it
+	succeeded := 0
+	for i := 0; i < workers; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, ErrIdempotencyKeyConflict, errors.Cause(err))
+	}
+	assert.Equal(t, 1, succeeded)
+
+	changed := 0
+	for id := int64(1); id <= 2*workers; id++ {
+		a, err := s.GetAccount(nil, id)
+		if assert.NoError(t, err) && !a.Amount.Equal(decimal.RequireFromString("15")) {
+			changed++
+		}
+	}
+	assert.Equal(t, 2, changed)
+}
+
+// pgError has the accessor of fields of postgres errors
+type pgError map[byte]string
+
+func (e pgError) Get(field byte) string { return e[field] }
+
+func (e pgError) Error() string { return "pq: " + e['M'] }
+
+func Test_isUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "postgres",
+			err:  pgError{'C': "23505", 'n': "uix_operations_idempotency_key", 'M': "duplicate key value violates unique constraint"},
+			want: true,
+		},
+		{
+			name: "postgres other constraint",
+			err:  pgError{'C': "23505", 'n': "uix_operations_reversal_of", 'M': "duplicate key value violates unique constraint"},
+		},
+		{
+			name: "postgres other error",
+			err:  pgError{'C': "23502", 'n': "uix_operations_idempotency_key", 'M': "null value violates not-null constraint"},
+		},
+		{
+			name: "sqlite",
+			err:  errors.New("UNIQUE constraint failed: operations.idempotency_key"),
+			want: true,
+		},
+		{
+			name: "sqlite other column",
+			err:  errors.New("UNIQUE constraint failed: operations.reversal_of"),
+		},
+		{
+			name: "other message",
+			err:  errors.New("unique idempotency_key"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isUniqueViolation(tt.err, "operations", "idempotency_key"))
+		})
+	}
+
+	// Errors of the real driver, sqlite is used unless the tests run against postgresql
+	t.Run("driver", func(t *testing.T) {
+		db, remove := getSQLite()
+		if usePostgres() {
+			remove()
+			db, remove = getDB(), func() {}
+		}
+		defer remove()
+
+		key := fmt.Sprintf("unique-%d", time.Now().UnixNano())
+		assert.NoError(t, db.Create(&Operation{Type: OperationTypeDeposit, IdempotencyKey: &key}).Error)
+
+		err := db.Create(&Operation{Type: OperationTypeDeposit, IdempotencyKey: &key}).Error
+		if assert.Error(t, err) {
+			assert.True(t, isUniqueViolation(errors.Cause(err), "operations", "idempotency_key"), "%v", err)
+		}
+	})
+}
+
+func Test_basicPaymentsService_Holds(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		want     map[int64]*Account
+		wantHeld map[int64]decimal.Decimal
+		action   func(PaymentsService) error
+		wantErr  bool
+	}{
+		{
+			name: "held money can't be transferred",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			wantHeld: map[int64]decimal.Decimal{1: decimal.RequireFromString("10")},
+			action: func(s PaymentsService) error {
+				if _, err := s.AuthorizeHold(nil, 1, "USD", decimal.RequireFromString("10"), time.Hour); err != nil {
+					return err
+				}
+				_, err := s.MakeTransfer(nil, 1, 2, "USD", decimal.RequireFromString("10"))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "partial capture",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("11")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("19")},
+			},
+			action: func(s PaymentsService) error {
+				h, err := s.AuthorizeHold(nil, 1, "USD", decimal.RequireFromString("10"), time.Hour)
+				if err != nil {
+					return err
+				}
+				_, err = s.CaptureHold(nil, int64(h.ID), 2, decimal.RequireFromString("4"))
+				return err
+			},
+		},
+		{
+			name: "negative capture",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			wantHeld: map[int64]decimal.Decimal{1: decimal.RequireFromString("10")},
+			action: func(s PaymentsService) error {
+				h, err := s.AuthorizeHold(nil, 1, "USD", decimal.RequireFromString("10"), time.Hour)
+				if err != nil {
+					return err
+				}
+				_, err = s.CaptureHold(nil, int64(h.ID), 2, decimal.RequireFromString("-4"))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "capture with fractional minor units",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "JPY", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "JPY", Amount: decimal.RequireFromString("15")},
+			},
+			wantHeld: map[int64]decimal.Decimal{1: decimal.RequireFromString("10")},
+			action: func(s PaymentsService) error {
+				h, err := s.AuthorizeHold(nil, 1, "JPY", decimal.RequireFromString("10"), time.Hour)
+				if err != nil {
+					return err
+				}
+				_, err = s.CaptureHold(nil, int64(h.ID), 2, decimal.RequireFromString("1.5"))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "void",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) error {
+				h, err := s.AuthorizeHold(nil, 1, "USD", decimal.RequireFromString("10"), time.Hour)
+				if err != nil {
+					return err
+				}
+				if _, err := s.VoidHold(nil, int64(h.ID)); err != nil {
+					return err
+				}
+				_, err = s.CaptureHold(nil, int64(h.ID), 2, decimal.Zero)
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "expiry",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) error {
+				if _, err := s.AuthorizeHold(nil, 1, "USD", decimal.RequireFromString("10"), time.Nanosecond); err != nil {
+					return err
+				}
+				time.Sleep(time.Millisecond)
+				_, err := s.ExpireHolds(nil)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, a := range tt.want {
+				err := st.save(&Account{
+					ID:       a.ID,
+					Name:     a.Name,
+					Currency: a.Currency,
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			err := tt.action(s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService holds error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, wantAcc := range tt.want {
+				a, err := s.GetAccount(tt.args.ctx, wantAcc.ID)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(wantAcc.Amount), "Got: %s; Want: %s", a.Amount, wantAcc.Amount)
+				assert.True(t, a.Held.Equal(tt.wantHeld[a.ID]), "Got held: %s; Want held: %s", a.Held, tt.wantHeld[a.ID])
+			}
+		})
+	}
+}
+
+// ─── ACCOUNT STATUS ─────────────────────────────────────────────────────────────
+
+func Test_basicPaymentsService_AccountStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		want        map[int64]decimal.Decimal
+		wantStatus  map[int64]AccountStatus
+		wantHistory []AccountStatus // statuses of the account 1 after every change
+		action      func(PaymentsService) error
+		wantErr     error
+	}{
+		{
+			name:        "frozen account can't receive money",
+			want:        map[int64]decimal.Decimal{1: decimal.RequireFromString("15")},
+			wantStatus:  map[int64]AccountStatus{1: AccountStatusFrozen},
+			wantHistory: []AccountStatus{AccountStatusFrozen},
+			action: func(s PaymentsService) error {
+				if _, err := s.FreezeAccount(nil, 1, "compromised"); err != nil {
+					return err
+				}
+				_, err := s.MakeDeposit(nil, 1, "USD", decimal.RequireFromString("10"))
+				return err
+			},
+			wantErr: ErrAccountFrozen,
+		},
+		{
+			name:        "frozen account can't send money",
+			want:        map[int64]decimal.Decimal{1: decimal.RequireFromString("15"), 2: decimal.RequireFromString("15")},
+			wantStatus:  map[int64]AccountStatus{1: AccountStatusFrozen, 2: AccountStatusActive},
+			wantHistory: []AccountStatus{AccountStatusFrozen},
+			action: func(s PaymentsService) error {
+				if _, err := s.FreezeAccount(nil, 1, "compromised"); err != nil {
+					return err
+				}
+				_, err := s.MakeTransfer(nil, 1, 2, "USD", decimal.RequireFromString("10"))
+				return err
+			},
+			wantErr: ErrAccountFrozen,
+		},
+		{
+			name:        "unfrozen account works again",
+			want:        map[int64]decimal.Decimal{1: decimal.RequireFromString("5"), 2: decimal.RequireFromString("25")},
+			wantStatus:  map[int64]AccountStatus{1: AccountStatusActive, 2: AccountStatusActive},
+			wantHistory: []AccountStatus{AccountStatusFrozen, AccountStatusActive},
+			action: func(s PaymentsService) error {
+				if _, err := s.FreezeAccount(nil, 1, "compromised"); err != nil {
+					return err
+				}
+				if _, err := s.UnfreezeAccount(nil, 1, "checked"); err != nil {
+					return err
+				}
+				_, err := s.MakeTransfer(nil, 1, 2, "USD", decimal.RequireFromString("10"))
+				return err
+			},
+		},
+		{
+			name:       "active account can't be unfrozen",
+			want:       map[int64]decimal.Decimal{1: decimal.RequireFromString("15")},
+			wantStatus: map[int64]AccountStatus{1: AccountStatusActive},
+			action: func(s PaymentsService) error {
+				_, err := s.UnfreezeAccount(nil, 1, "")
+				return err
+			},
+			wantErr: ErrAccountNotFrozen,
+		},
+		{
+			name:       "account with money can't be closed without sweep",
+			want:       map[int64]decimal.Decimal{1: decimal.RequireFromString("15")},
+			wantStatus: map[int64]AccountStatus{1: AccountStatusActive},
+			action: func(s PaymentsService) error {
+				_, err := s.CloseAccount(nil, 1, 0, "")
+				return err
+			},
+			wantErr: ErrAccountNotEmpty,
+		},
+		{
+			name:       "account with holds can't be closed",
+			want:       map[int64]decimal.Decimal{1: decimal.RequireFromString("15"), 2: decimal.RequireFromString("15")},
+			wantStatus: map[int64]AccountStatus{1: AccountStatusActive, 2: AccountStatusActive},
+			action: func(s PaymentsService) error {
+				if _, err := s.AuthorizeHold(nil, 1, "USD", decimal.RequireFromString("10"), time.Hour); err != nil {
+					return err
+				}
+				_, err := s.CloseAccount(nil, 1, 2, "")
+				return err
+			},
+			wantErr: ErrAccountHasHolds,
+		},
+		{
+			name:        "frozen account is swept on closing",
+			want:        map[int64]decimal.Decimal{1: decimal.Zero, 2: decimal.RequireFromString("30")},
+			wantStatus:  map[int64]AccountStatus{1: AccountStatusClosed, 2: AccountStatusActive},
+			wantHistory: []AccountStatus{AccountStatusFrozen, AccountStatusClosed},
+			action: func(s PaymentsService) error {
+				if _, err := s.FreezeAccount(nil, 1, "compromised"); err != nil {
+					return err
+				}
+				a, err := s.CloseAccount(nil, 1, 2, "closed by the owner")
+				if err != nil {
+					return err
+				}
+				if a.DeletedAt == nil {
+					return errors.New("closed account isn't deleted")
+				}
+				_, err = s.MakeTransfer(nil, 2, 1, "USD", decimal.RequireFromString("10"))
+				return err
+			},
+			wantErr: ErrAccountClosed,
+		},
+		{
+			name:       "closed account can't be unfrozen",
+			want:       map[int64]decimal.Decimal{1: decimal.Zero, 2: decimal.RequireFromString("30")},
+			wantStatus: map[int64]AccountStatus{1: AccountStatusClosed},
+			action: func(s PaymentsService) error {
+				if _, err := s.CloseAccount(nil, 1, 2, ""); err != nil {
+					return err
+				}
+				_, err := s.UnfreezeAccount(nil, 1, "")
+				return err
+			},
+			wantHistory: []AccountStatus{AccountStatusClosed},
+			wantErr:     ErrAccountClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, id := range []int64{1, 2} {
+				err := st.save(&Account{
+					ID:       id,
+					Name:     "test",
+					Currency: "USD",
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			err := tt.action(s)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+
+			for id, want := range tt.want {
+				a, err := s.GetAccount(nil, id)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(want), "Got: %s; Want: %s", a.Amount, want)
+				if status, ok := tt.wantStatus[id]; ok {
+					assert.Equal(t, status, a.Status)
+				}
+			}
+
+			cs, err := s.GetAccountStatusHistory(nil, 1)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+
+			got := []AccountStatus{}
+			for _, c := range cs {
+				got = append(got, c.To)
+			}
+			if tt.wantHistory == nil {
+				tt.wantHistory = []AccountStatus{}
+			}
+			assert.Equal(t, tt.wantHistory, got)
+		})
+	}
+}
+
+// ─── EXCHANGE ───────────────────────────────────────────────────────────────────
+
+func Test_basicPaymentsService_MakeExchangeTransfer(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	rates := NewMemoryFXRateProvider()
+	rates.Set("USD", "EUR", decimal.RequireFromString("0.9"))
+	rates.Set("USD", "JPY", decimal.RequireFromString("150.555"))
+
+	tests := []struct {
+		name    string
+		args    args
+		want    map[int64]*Account
+		action  func(PaymentsService) error
+		wantErr bool
+	}{
+		{
+			name: "simple exchange",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("5")},
+				2: {ID: 2, Name: "test2", Currency: "EUR", Amount: decimal.RequireFromString("24")},
+			},
+			action: func(s PaymentsService) error {
+				q, err := s.QuoteExchange(nil, "usd", "eur", time.Minute)
+				if err != nil {
+					return err
+				}
+				_, err = s.MakeExchangeTransfer(nil, 1, 2, decimal.RequireFromString("10"), int64(q.ID))
+				return err
+			},
+		},
+		{
+			name: "target amount is rounded down",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("14.99")},
+				2: {ID: 2, Name: "test2", Currency: "JPY", Amount: decimal.RequireFromString("16")},
+			},
+			action: func(s PaymentsService) error {
+				q, err := s.QuoteExchange(nil, "USD", "JPY", time.Minute)
+				if err != nil {
+					return err
+				}
+				_, err = s.MakeExchangeTransfer(nil, 1, 2, decimal.RequireFromString("0.01"), int64(q.ID))
+				return err
+			},
+		},
+		{
+			name: "quote can't be used twice",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("5")},
+				2: {ID: 2, Name: "test2", Currency: "EUR", Amount: decimal.RequireFromString("24")},
+			},
+			action: func(s PaymentsService) error {
+				q, err := s.QuoteExchange(nil, "USD", "EUR", time.Minute)
+				if err != nil {
+					return err
+				}
+				if _, err := s.MakeExchangeTransfer(nil, 1, 2, decimal.RequireFromString("10"), int64(q.ID)); err != nil {
+					return err
+				}
+				_, err = s.MakeExchangeTransfer(nil, 1, 2, decimal.RequireFromString("1"), int64(q.ID))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired quote",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "EUR", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) error {
+				q, err := s.QuoteExchange(nil, "USD", "EUR", time.Nanosecond)
+				if err != nil {
+					return err
+				}
+				time.Sleep(time.Millisecond)
+				_, err = s.MakeExchangeTransfer(nil, 1, 2, decimal.RequireFromString("10"), int64(q.ID))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown rate",
+			args: args{},
+			want: map[int64]*Account{
+				1: {ID: 1, Name: "test1", Currency: "EUR", Amount: decimal.RequireFromString("15")},
+				2: {ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+			},
+			action: func(s PaymentsService) error {
+				_, err := s.QuoteExchange(nil, "EUR", "USD", time.Minute)
+				return err
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, a := range tt.want {
+				err := st.save(&Account{
+					ID:       a.ID,
+					Name:     a.Name,
+					Currency: a.Currency,
+					Amount:   decimal.RequireFromString("15"),
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+				fx:         rates,
+			}
+
+			err := tt.action(s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService exchange error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, wantAcc := range tt.want {
+				a, err := s.GetAccount(tt.args.ctx, wantAcc.ID)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+
+				assert.True(t, a.Amount.Equal(wantAcc.Amount), "Got: %s; Want: %s", a.Amount, wantAcc.Amount)
+			}
+		})
+	}
+}
+
+// ─── FEES ───────────────────────────────────────────────────────────────────────
+
+func Test_FeeSchedule_Fee(t *testing.T) {
+	fees := NewFeeSchedule(
+		&FeeRule{Type: OperationTypeTransfer, Flat: decimal.RequireFromString("0.3"), Percent: decimal.RequireFromString("2.9")},
+		&FeeRule{Type: OperationTypeTransfer, Currency: "eur", Percent: decimal.RequireFromString("1"), Min: decimal.RequireFromString("0.5"), Max: decimal.RequireFromString("5")},
+		&FeeRule{Type: OperationTypeDeposit, Currency: "USD", Tiers: []FeeTier{
+			{From: decimal.RequireFromString("1000"), Percent: decimal.RequireFromString("0.5")},
+			{From: decimal.RequireFromString("100"), Percent: decimal.RequireFromString("1")},
+		}},
+	)
+
+	tests := []struct {
+		name     string
+		opType   OperationType
+		currency string
+		amount   string
+		want     string
+	}{
+		{name: "flat and percent", opType: OperationTypeTransfer, currency: "USD", amount: "10", want: "0.59"},
+		{name: "rounded to currency precision", opType: OperationTypeTransfer, currency: "JPY", amount: "100", want: "3"},
+		{name: "min", opType: OperationTypeTransfer, currency: "EUR", amount: "10", want: "0.5"},
+		{name: "max", opType: OperationTypeTransfer, currency: "EUR", amount: "1000", want: "5"},
+		{name: "below tiers", opType: OperationTypeDeposit, currency: "USD", amount: "50", want: "0"},
+		{name: "first tier", opType: OperationTypeDeposit, currency: "USD", amount: "100", want: "1"},
+		{name: "second tier", opType: OperationTypeDeposit, currency: "USD", amount: "2000", want: "10"},
+		{name: "no rule", opType: OperationTypeWithdrawal, currency: "USD", amount: "10", want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := testCurrencies.Get(tt.currency)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+
+			got := fees.Fee(tt.opType, c, decimal.RequireFromString(tt.amount))
+			assert.True(t, got.Equal(decimal.RequireFromString(tt.want)), "Got: %s; Want: %s", got, tt.want)
+		})
+	}
+}
+
+func Test_basicPaymentsService_Fees(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+		fees: NewFeeSchedule(
+			&FeeRule{Type: OperationTypeDeposit, Flat: decimal.RequireFromString("1")},
+			&FeeRule{Type: OperationTypeTransfer, Percent: decimal.RequireFromString("10")},
+		),
+	}
+
+	a1, err := s.CreateAccount(nil, "test1", "USD")
+	assert.NoError(t, err)
+	a2, err := s.CreateAccount(nil, "test2", "USD")
+	assert.NoError(t, err)
+
+	_, err = s.MakeDeposit(nil, a1.ID, "USD", decimal.RequireFromString("20"))
+	assert.NoError(t, err)
+
+	o, err := s.MakeTransfer(nil, a1.ID, a2.ID, "USD", decimal.RequireFromString("10"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, o.Transactions, 2)
+
+	_, err = s.MakeTransfer(nil, a1.ID, a2.ID, "USD", decimal.RequireFromString("8"))
+	assert.Equal(t, ErrBalanceTooLow, errors.Cause(err), "fee must be covered by the balance")
+
+	want := map[int64]string{a1.ID: "8", a2.ID: "10"}
+	for id, amount := range want {
+		a, err := s.GetAccount(nil, id)
+		assert.NoError(t, err)
+		assert.True(t, a.Amount.Equal(decimal.RequireFromString(amount)), "Got: %s; Want: %s", a.Amount, amount)
+	}
+
+	fees, err := s.systemAccount(nil, AccountRoleFees, "USD")
+	assert.NoError(t, err)
+	assert.True(t, fees.Amount.Equal(decimal.RequireFromString("2")), "Got fees: %s", fees.Amount)
+
+	assert.NoError(t, s.CheckLedger(nil))
+}
+
+// ─── SYSTEM ACCOUNTS ────────────────────────────────────────────────────────────
+
+func Test_InitModels(t *testing.T) {
+	// Migrations need a database, sqlite is used unless the tests run against postgresql
+	db, remove := getSQLite()
+	if usePostgres() {
+		remove()
+		db, remove = getDB(), func() {}
+	}
+	defer remove()
+
+	// Accounts of earlier releases may have no role
+	assert.NoError(t, db.Save(&Account{ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("10")}).Error)
+	assert.NoError(t, db.Exec("UPDATE accounts SET role = NULL WHERE id = 1").Error)
+
+	if !assert.NoError(t, InitModels(db, "eur")) {
+		t.FailNow()
+	}
+
+	system := []*Account{}
+	assert.NoError(t, db.Where("role <> ?", AccountRoleUser).Find(&system).Error)
+	assert.Len(t, system, 2*len(SystemAccountRoles))
+
+	world := Account{}
+	assert.NoError(t, db.Where("role = ? AND currency = ?", AccountRoleWorld, "USD").First(&world).Error)
+
+	// Repeated initialization doesn't duplicate system accounts
+	assert.NoError(t, InitModels(db))
+	count := 0
+	assert.NoError(t, db.Model(&Account{}).Where("role <> ?", AccountRoleUser).Count(&count).Error)
+	assert.Equal(t, 2*len(SystemAccountRoles), count)
+}
+
+func Test_InitModels_notMigrated(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+
+	assert.Equal(t, migrations.ErrNotMigrated, errors.Cause(InitModels(db)))
+	assert.False(t, db.HasTable(&Account{}))
+}
+
+// ─── OPERATIONS HISTORY ─────────────────────────────────────────────────────────
+
+func Test_DecodeCursor(t *testing.T) {
+	id, err := DecodeCursor(EncodeCursor(42))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	id, err = DecodeCursor("")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), id)
+
+	_, err = DecodeCursor("not a cursor")
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func Test_basicPaymentsService_GetAccountOperations(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	a1, err := s.CreateAccount(nil, "test1", "USD")
+	assert.NoError(t, err)
+	a2, err := s.CreateAccount(nil, "test2", "USD")
+	assert.NoError(t, err)
+	a3, err := s.CreateAccount(nil, "test3", "USD")
+	assert.NoError(t, err)
+
+	// a1: deposit 105, transfers 10, 20, 30 to a2, transfer 40 to a3, withdrawal 5
+	_, err = s.MakeDeposit(nil, a1.ID, "USD", decimal.RequireFromString("105"))
+	assert.NoError(t, err)
+	for _, amount := range []string{"10", "20", "30"} {
+		_, err = s.MakeTransfer(nil, a1.ID, a2.ID, "USD", decimal.RequireFromString(amount))
+		assert.NoError(t, err)
+	}
+	_, err = s.MakeTransfer(nil, a1.ID, a3.ID, "USD", decimal.RequireFromString("40"))
+	assert.NoError(t, err)
+	_, err = s.MakeWithdrawal(nil, a1.ID, "USD", decimal.RequireFromString("5"))
+	assert.NoError(t, err)
+
+	min, max := decimal.RequireFromString("15"), decimal.RequireFromString("35")
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  OperationsFilter
+		want    []string // amounts of the first transaction of every operation
+		wantErr bool
+	}{
+		{name: "all", want: []string{"105", "10", "20", "30", "40", "5"}},
+		{name: "desc", filter: OperationsFilter{Page: Page{Order: SortOrderDesc}}, want: []string{"5", "40", "30", "20", "10", "105"}},
+		{name: "types", filter: OperationsFilter{Types: []OperationType{OperationTypeDeposit, OperationTypeWithdrawal}}, want: []string{"105", "5"}},
+		{name: "counterparty", filter: OperationsFilter{Counterparty: a3.ID}, want: []string{"40"}},
+		{name: "amount range", filter: OperationsFilter{MinAmount: &min, MaxAmount: &max}, want: []string{"20", "30"}},
+		{name: "date range", filter: OperationsFilter{CreatedFrom: &past, CreatedTo: &future, Types: []OperationType{OperationTypeDeposit}}, want: []string{"105"}},
+		{name: "empty date range", filter: OperationsFilter{CreatedFrom: &future}, want: []string{}},
+		{name: "invalid cursor", filter: OperationsFilter{Page: Page{Cursor: "?"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := s.GetAccountOperations(nil, a1.ID, tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService.GetAccountOperations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := []string{}
+			for _, o := range p.Operations {
+				got = append(got, o.Transactions[0].Amount.String())
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Empty(t, p.NextCursor)
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		got := []string{}
+		f := OperationsFilter{Page: Page{Limit: 4}}
+		for i := 0; ; i++ {
+			if i > 2 {
+				t.Fatal("too many pages")
+			}
+
+			p, err := s.GetAccountOperations(nil, a1.ID, f)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			for _, o := range p.Operations {
+				got = append(got, o.Transactions[0].Amount.String())
+			}
+			if p.NextCursor == "" {
+				break
+			}
+			f.Cursor = p.NextCursor
+		}
+		assert.Equal(t, []string{"105", "10", "20", "30", "40", "5"}, got)
+	})
+}
+
+func Test_basicPaymentsService_GetAccountsFilter(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	accounts := []*Account{
+		{Name: "alice", Currency: "USD", Amount: decimal.RequireFromString("10")},
+		{Name: "alex", Currency: "EUR", Amount: decimal.RequireFromString("20")},
+		{Name: "a_b", Currency: "USD", Amount: decimal.RequireFromString("30")},
+		{Name: "bob", Currency: "USD", Amount: decimal.RequireFromString("40")},
+	}
+	assert.NoError(t, st.save(accounts...))
+	assert.NoError(t, st.do(func(uow UOWPayments) error {
+		return uow.Accounts().Delete(nil, accounts[3].ID)
+	}))
+
+	min, max := decimal.RequireFromString("15"), decimal.RequireFromString("35")
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	total := int64(2)
+
+	tests := []struct {
+		name      string
+		filter    AccountsFilter
+		want      []string
+		wantTotal *int64
+		wantErr   bool
+	}{
+		{name: "all", want: []string{"alice", "alex", "a_b"}},
+		{name: "desc", filter: AccountsFilter{Page: Page{Order: SortOrderDesc}}, want: []string{"a_b", "alex", "alice"}},
+		{name: "currency", filter: AccountsFilter{Currency: "usd"}, want: []string{"alice", "a_b"}},
+		{name: "name prefix", filter: AccountsFilter{NamePrefix: "al"}, want: []string{"alice", "alex"}},
+		{name: "escaped name prefix", filter: AccountsFilter{NamePrefix: "a_"}, want: []string{"a_b"}},
+		{name: "amount range", filter: AccountsFilter{MinAmount: &min, MaxAmount: &max}, want: []string{"alex", "a_b"}},
+		{name: "date range", filter: AccountsFilter{CreatedFrom: &past, CreatedTo: &future}, want: []string{"alice", "alex", "a_b"}},
+		{name: "empty date range", filter: AccountsFilter{CreatedFrom: &future}, want: []string{}},
+		{name: "deleted", filter: AccountsFilter{Deleted: DeletedStatusDeleted}, want: []string{"bob"}},
+		{name: "with deleted", filter: AccountsFilter{Deleted: DeletedStatusAll}, want: []string{"alice", "alex", "a_b", "bob"}},
+		{name: "total", filter: AccountsFilter{Currency: "USD", WithTotal: true, Page: Page{Limit: 1}}, want: []string{"alice"}, wantTotal: &total},
+		{name: "invalid cursor", filter: AccountsFilter{Page: Page{Cursor: "?"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := s.GetAccounts(nil, tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("basicPaymentsService.GetAccounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := []string{}
+			for _, a := range p.Accounts {
+				got = append(got, a.Name)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTotal, p.Total)
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		got := []string{}
+		f := AccountsFilter{Page: Page{Limit: 2}}
+		for i := 0; ; i++ {
+			if i > 2 {
+				t.Fatal("too many pages")
+			}
+
+			p, err := s.GetAccounts(nil, f)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			for _, a := range p.Accounts {
+				got = append(got, a.Name)
+			}
+			if p.NextCursor == "" {
+				break
+			}
+			f.Cursor = p.NextCursor
+		}
+		assert.Equal(t, []string{"alice", "alex", "a_b"}, got)
+	})
+}
+
+// ─── LEDGER ─────────────────────────────────────────────────────────────────────
+
+func Test_basicPaymentsService_CheckLedger(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		action  func(*testStorage, PaymentsService) error
+		wantErr bool
+	}{
+		{
+			name: "balanced ledger",
+			args: args{},
+			action: func(st *testStorage, s PaymentsService) error {
+				if _, err := s.MakeDeposit(nil, 1, "USD", decimal.RequireFromString("15")); err != nil {
+					return err
+				}
+				if _, err := s.MakeTransfer(nil, 1, 2, "USD", decimal.RequireFromString("10")); err != nil {
+					return err
+				}
+				_, err := s.MakeWithdrawal(nil, 2, "USD", decimal.RequireFromString("3"))
+				return err
+			},
+		},
+		{
+			name: "balance changed outside of the ledger",
+			args: args{},
+			action: func(st *testStorage, s PaymentsService) error {
+				if _, err := s.MakeDeposit(nil, 1, "USD", decimal.RequireFromString("15")); err != nil {
+					return err
+				}
+				return st.setAmount(1, decimal.RequireFromString("20"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures
+			for _, id := range []int64{1, 2} {
+				err := st.save(&Account{
+					ID:       id,
+					Name:     "test",
+					Currency: "USD",
+				})
+
+				assert.NoError(t, err)
+			}
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			if !assert.NoError(t, tt.action(st, s)) {
+				t.FailNow()
+			}
+
+			err := s.CheckLedger(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("basicPaymentsService.CheckLedger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_reconciler_Reconcile(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		action     func(PaymentsService) error
+		wantDrifts map[int64]decimal.Decimal
+	}{
+		{
+			name: "deposit history",
+			args: args{},
+			action: func(s PaymentsService) error {
+				_, err := s.MakeDeposit(nil, 1, "USD", decimal.RequireFromString("15"))
+				return err
+			},
+			wantDrifts: map[int64]decimal.Decimal{2: decimal.RequireFromString("15")},
+		},
+		{
+			name: "transfer history",
+			args: args{},
+			action: func(s PaymentsService) error {
+				_, err := s.MakeTransfer(nil, 2, 1, "USD", decimal.RequireFromString("5"))
+				return err
+			},
+			wantDrifts: map[int64]decimal.Decimal{2: decimal.RequireFromString("15")},
+		},
+	}
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			st := getStorage()
+			defer st.Close()
+
+			// Init fixtures: account 2 has money without any operations
+			err := st.save(&Account{ID: 1, Name: "test1", Currency: "USD"})
+			assert.NoError(t, err)
+			err = st.save(&Account{ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")})
+			assert.NoError(t, err)
+
+			s := &basicPaymentsService{
+				lockf:      st.lockf,
+				uowf:       st.uowf,
+				currencies: testCurrencies,
+			}
+
+			if !assert.NoError(t, tt.action(s)) {
+				t.FailNow()
+			}
+
+			r := NewReconciler(s.lockf, s.uowf)
+			drifts, err := r.Reconcile(tt.args.ctx)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+
+			got := map[int64]decimal.Decimal{}
+			for _, d := range drifts {
+				got[d.AccountID] = d.Difference
+			}
+
+			assert.Len(t, got, len(tt.wantDrifts))
+			for id, want := range tt.wantDrifts {
+				assert.True(t, got[id].Equal(want), "Account: %d; Got: %s; Want: %s", id, got[id], want)
+			}
+
+			for _, d := range drifts {
+				_, err := r.Fix(tt.args.ctx, d)
+				assert.NoError(t, err)
+				assert.NotNil(t, d.AdjustmentID)
+			}
+
+			drifts, err = r.Reconcile(tt.args.ctx)
+			assert.NoError(t, err)
+			assert.Empty(t, drifts)
+		})
+	}
+}
+
+// Opening postings of the ledger backfill explain balances of accounts from before the ledger
+func Test_reconciler_Reconcile_openings(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	// Account 2 has money without operations, account 3 has a deposit without postings
+	assert.NoError(t, st.save(&Account{ID: 1, Name: "test1", Currency: "USD"}))
+	assert.NoError(t, st.save(&Account{ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")}))
+	assert.NoError(t, st.save(&Account{ID: 3, Name: "test3", Currency: "USD", Amount: decimal.RequireFromString("10")}))
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	world, err := s.systemAccount(nil, AccountRoleWorld, "USD")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	uow, err := st.uowf.Make()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = uow.Operations().Create(nil, &Operation{
+		Type:         OperationTypeDeposit,
+		Participants: []int64{world.ID, 3},
+		Transactions: []Transaction{{From: world.ID, To: 3, Currency: "USD", Amount: decimal.RequireFromString("10")}},
+	})
+	assert.NoError(t, err)
+
+	// Openings like the ones of the backfill migration
+	openings := map[int64]decimal.Decimal{2: decimal.RequireFromString("15"), 3: decimal.RequireFromString("10"), world.ID: decimal.RequireFromString("-25")}
+	for id, amount := range openings {
+		_, err := uow.Postings().Create(nil, &Posting{AccountID: id, Currency: "USD", Amount: amount, Balance: amount})
+		assert.NoError(t, err)
+	}
+	world.Amount = decimal.RequireFromString("-25")
+	_, err = uow.Accounts().Update(nil, world)
+	assert.NoError(t, err)
+	assert.NoError(t, uow.Save())
+
+	_, err = s.MakeTransfer(nil, 2, 1, "USD", decimal.RequireFromString("5"))
+	assert.NoError(t, err)
+
+	r := NewReconciler(s.lockf, s.uowf)
+	drifts, err := r.Reconcile(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+	assert.NoError(t, s.CheckLedger(nil))
+}
+
+// ─── SCHEDULES ──────────────────────────────────────────────────────────────────
+
+func Test_Schedule_plan(t *testing.T) {
+	start := time.Date(2019, time.January, 31, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2019, time.April, 30, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		schedule  Schedule
+		runs      int
+		wantRuns  []time.Time
+		wantState ScheduleStatus
+	}{
+		{
+			name:      "once",
+			schedule:  Schedule{Period: SchedulePeriodOnce, Every: 1, StartAt: start},
+			runs:      2,
+			wantRuns:  []time.Time{start},
+			wantState: ScheduleStatusCompleted,
+		},
+		{
+			name:     "every 2 weeks",
+			schedule: Schedule{Period: SchedulePeriodWeek, Every: 2, StartAt: start},
+			runs:     3,
+			wantRuns: []time.Time{start, start.AddDate(0, 0, 14), start.AddDate(0, 0, 28)},
+		},
+		{
+			name:     "month clamped to the last day",
+			schedule: Schedule{Period: SchedulePeriodMonth, Every: 1, StartAt: start},
+			runs:     3,
+			wantRuns: []time.Time{
+				start,
+				time.Date(2019, time.February, 28, 10, 0, 0, 0, time.UTC),
+				time.Date(2019, time.March, 31, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "end date",
+			schedule: Schedule{Period: SchedulePeriodMonth, Every: 1, StartAt: start, EndAt: &end},
+			runs:     5,
+			wantRuns: []time.Time{
+				start,
+				time.Date(2019, time.February, 28, 10, 0, 0, 0, time.UTC),
+				time.Date(2019, time.March, 31, 10, 0, 0, 0, time.UTC),
+				end,
+			},
+			wantState: ScheduleStatusCompleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch := tt.schedule
+			sch.Status = ScheduleStatusActive
+			sch.reschedule(start)
+
+			runs := []time.Time{}
+			for i := 0; i < tt.runs && sch.Status == ScheduleStatusActive; i++ {
+				runs = append(runs, sch.NextAttemptAt)
+				sch.advance()
+			}
+
+			assert.Equal(t, tt.wantRuns, runs)
+			if tt.wantState != "" {
+				assert.Equal(t, tt.wantState, sch.Status)
+			}
+		})
+	}
+}
+
+func Test_Schedule_retry(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sch := Schedule{Period: SchedulePeriodDay, Every: 1, StartAt: start, MaxRetries: 1, RetryDelay: time.Hour, Status: ScheduleStatusActive}
+	sch.reschedule(start)
+
+	sch.retry(start)
+	assert.Equal(t, 0, sch.Occurrence)
+	assert.Equal(t, start.Add(time.Hour), sch.NextAttemptAt)
+
+	// The run is skipped after the last retry
+	sch.retry(start.Add(time.Hour))
+	assert.Equal(t, 1, sch.Occurrence)
+	assert.Equal(t, 0, sch.Attempts)
+	assert.Equal(t, start.AddDate(0, 0, 1), sch.NextAttemptAt)
+}
+
+func Test_basicPaymentsService_RunDueSchedules(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	for _, a := range []*Account{
+		{ID: 1, Name: "test1", Currency: "USD", Amount: decimal.RequireFromString("15")},
+		{ID: 2, Name: "test2", Currency: "USD", Amount: decimal.RequireFromString("15")},
+	} {
+		assert.NoError(t, st.save(a))
+	}
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	// The first run is planned right now
+	sch, err := s.CreateSchedule(nil, ScheduleSpec{
+		From:     1,
+		To:       2,
+		Currency: "USD",
+		Amount:   decimal.RequireFromString("10"),
+		Period:   SchedulePeriodDay,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, ScheduleStatusActive, sch.Status)
+	assert.Equal(t, 1, sch.Every)
+
+	as, err := s.RunDueSchedules(nil)
+	if assert.NoError(t, err) && assert.Len(t, as, 1) {
+		assert.NotNil(t, as[0].OperationID)
+	}
+
+	// The next run is tomorrow
+	as, err = s.RunDueSchedules(nil)
+	assert.NoError(t, err)
+	assert.Len(t, as, 0)
+
+	sch, err = s.GetSchedule(nil, int64(sch.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sch.Occurrence)
+
+	a, err := s.GetAccount(nil, 1)
+	assert.NoError(t, err)
+	assert.True(t, a.Amount.Equal(decimal.RequireFromString("5")), "Got: %s", a.Amount)
+
+	// Insufficient funds are retried
+	sch, err = s.UpdateSchedule(nil, int64(sch.ID), ScheduleSpec{
+		From:       1,
+		To:         2,
+		Currency:   "USD",
+		Amount:     decimal.RequireFromString("10"),
+		Period:     SchedulePeriodDay,
+		MaxRetries: 3,
+		RetryDelay: time.Minute,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	as, err = s.RunDueSchedules(nil)
+	if assert.NoError(t, err) && assert.Len(t, as, 1) {
+		assert.Nil(t, as[0].OperationID)
+		assert.NotEmpty(t, as[0].Error)
+	}
+
+	sch, err = s.GetSchedule(nil, int64(sch.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleStatusActive, sch.Status)
+	assert.Equal(t, 1, sch.Attempts)
+
+	_, err = s.CancelSchedule(nil, int64(sch.ID))
+	assert.NoError(t, err)
+	_, err = s.CancelSchedule(nil, int64(sch.ID))
+	assert.Equal(t, ErrScheduleNotActive, errors.Cause(err))
+
+	as, err = s.GetScheduleAttempts(nil, int64(sch.ID))
+	assert.NoError(t, err)
+	assert.Len(t, as, 2)
+}
+
+// ─── OUTBOX ─────────────────────────────────────────────────────────────────────
+
+func Test_relay_Relay(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	a1, err := s.CreateAccount(nil, "test1", "USD")
+	assert.NoError(t, err)
+	a2, err := s.CreateAccount(nil, "test2", "USD")
+	assert.NoError(t, err)
+	assert.NoError(t, st.setAmount(a1.ID, decimal.RequireFromString("15")))
+
+	for i := 0; i < 2; i++ {
+		_, err := s.MakeTransfer(nil, a1.ID, a2.ID, "USD", decimal.RequireFromString("5"))
+		assert.NoError(t, err)
+	}
+
+	// Events of the first account are held back while its oldest event fails
+	pub := NewMemoryPublisher()
+	pub.Fail = func(e *Event) error {
+		if e.AccountID == a1.ID {
+			return errors.New("unavailable")
+		}
+		return nil
+	}
+	r := NewRelay(s.lockf, s.uowf, pub)
+
+	n, err := r.Relay(nil)
+	assert.Error(t, err)
+	assert.Equal(t, 3, n)
+
+	pub.Fail = nil
+	n, err = r.Relay(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = r.Relay(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	types := map[int64][]EventType{}
+	for _, e := range pub.Events() {
+		types[e.AccountID] = append(types[e.AccountID], e.Type)
+	}
+	assert.Equal(t, []EventType{EventAccountCreated, EventOperationCompleted, EventOperationCompleted}, types[a1.ID])
+	assert.Equal(t, []EventType{EventAccountCreated, EventOperationCompleted, EventOperationCompleted}, types[a2.ID])
+}
+
+// ─── WEBHOOKS ───────────────────────────────────────────────────────────────────
+
+func Test_Webhook_Matches(t *testing.T) {
+	e := &Event{Type: EventOperationCompleted, AccountID: 1}
+
+	tests := []struct {
+		name    string
+		webhook Webhook
+		want    bool
+	}{
+		{name: "all", webhook: Webhook{}, want: true},
+		{name: "type", webhook: Webhook{Events: EventTypes{EventAccountCreated, EventOperationCompleted}}, want: true},
+		{name: "other type", webhook: Webhook{Events: EventTypes{EventAccountCreated}}, want: false},
+		{name: "account", webhook: Webhook{Accounts: AccountIDs{1}}, want: true},
+		{name: "other account", webhook: Webhook{Events: EventTypes{EventOperationCompleted}, Accounts: AccountIDs{2}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.webhook.Matches(e))
+		})
+	}
+}
+
+func Test_WebhookDelivery_fail(t *testing.T) {
+	now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	d := &WebhookDelivery{Status: WebhookDeliveryPending}
+
+	wantDelays := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for _, delay := range wantDelays {
+		d.fail(now, errors.New("unavailable"))
+		assert.Equal(t, now.Add(delay), d.NextAttemptAt)
+	}
+
+	for d.Status == WebhookDeliveryPending {
+		d.fail(now, errors.New("unavailable"))
+	}
+	assert.Equal(t, WebhookDeliveryDead, d.Status)
+	assert.Equal(t, WebhookMaxAttempts, d.Attempts)
+}
+
+func Test_EventTypes_Scan(t *testing.T) {
+	ts := EventTypes{EventAccountCreated, EventOperationCompleted}
+	v, err := ts.Value()
+	assert.NoError(t, err)
+
+	got := EventTypes{}
+	assert.NoError(t, got.Scan(v))
+	assert.Equal(t, ts, got)
+
+	ids := AccountIDs{}
+	assert.NoError(t, ids.Scan([]byte("1,20")))
+	assert.Equal(t, AccountIDs{1, 20}, ids)
+
+	assert.NoError(t, ids.Scan(""))
+	assert.Len(t, ids, 0)
+}
+
+func Test_webhookDispatcher_Dispatch(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	s := &basicPaymentsService{
+		lockf:      st.lockf,
+		uowf:       st.uowf,
+		currencies: testCurrencies,
+	}
+
+	var (
+		fail   = true
+		bodies [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var ts int64
+		var sig string
+		fmt.Sscanf(r.Header.Get(WebhookSignatureHeader), "t=%d,v1=%s", &ts, &sig)
+
+		wh, _ := s.GetWebhooks(nil)
+		if len(wh) != 1 || sig != SignWebhook(wh[0].Secret, ts, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	_, err := s.CreateWebhook(nil, WebhookSpec{URL: "ftp://example.com"})
+	assert.Equal(t, ErrWebhookURL, errors.Cause(err))
+
+	w, err := s.CreateWebhook(nil, WebhookSpec{URL: srv.URL, Events: EventTypes{EventAccountCreated}})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotEmpty(t, w.Secret)
+
+	// The secret isn't shown by reads of the webhook
+	got, err := s.GetWebhook(nil, int64(w.ID))
+	if assert.NoError(t, err) {
+		data, _ := json.Marshal(got)
+		assert.NotContains(t, string(data), w.Secret)
+	}
+
+	a, err := s.CreateAccount(nil, "test1", "USD")
+	assert.NoError(t, err)
+
+	r := NewRelay(s.lockf, s.uowf, NewWebhooksPublisher(s.uowf))
+	_, err = r.Relay(nil)
+	assert.NoError(t, err)
+
+	d := NewWebhookDispatcher(s.lockf, s.uowf, srv.Client())
+	ds, err := d.Dispatch(nil)
+	if assert.NoError(t, err) && assert.Len(t, ds, 1) {
+		assert.Equal(t, WebhookDeliveryPending, ds[0].Status)
+		assert.Equal(t, http.StatusServiceUnavailable, ds[0].ResponseStatus)
+		assert.Equal(t, 1, ds[0].Attempts)
+	}
+
+	// The failed delivery waits for the backoff
+	ds, err = d.Dispatch(nil)
+	assert.NoError(t, err)
+	assert.Len(t, ds, 0)
+
+	_, err = s.RedeliverWebhook(nil, 0)
+	assert.Error(t, err)
+
+	deliveries, err := s.GetWebhookDeliveries(nil, int64(w.ID))
+	if !assert.NoError(t, err) || !assert.Len(t, deliveries, 1) {
+		t.FailNow()
+	}
+
+	_, err = s.RedeliverWebhook(nil, int64(deliveries[0].ID))
+	assert.Equal(t, ErrWebhookDeliveryPending, errors.Cause(err))
+
+	// A dead delivery is sent again after the manual redelivery
+	deliveries[0].Status = WebhookDeliveryDead
+	assert.NoError(t, st.do(func(uow UOWPayments) error {
+		_, err := uow.WebhookDeliveries().Update(nil, deliveries[0])
+		return err
+	}))
+
+	fail = false
+	_, err = s.RedeliverWebhook(nil, int64(deliveries[0].ID))
+	assert.NoError(t, err)
+
+	ds, err = d.Dispatch(nil)
+	if assert.NoError(t, err) && assert.Len(t, ds, 1) {
+		assert.Equal(t, WebhookDeliveryDelivered, ds[0].Status)
+	}
+
+	if assert.Len(t, bodies, 1) {
+		e := Event{}
+		assert.NoError(t, json.Unmarshal(bodies[0], &e))
+		assert.Equal(t, EventAccountCreated, e.Type)
+		assert.Equal(t, a.ID, e.AccountID)
+	}
+}
+
+// ─── ERRORS ─────────────────────────────────────────────────────────────────────
+
+func Test_KindOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind ErrorKind
+		wantCode string
+	}{
+		{name: "not found", err: ErrAccountNotFound, wantKind: ErrorKindNotFound, wantCode: "account_not_found"},
+		{name: "wrapped", err: errors.Wrapf(ErrBalanceTooLow, "account (%d)", 1), wantKind: ErrorKindInsufficientFunds, wantCode: "balance_too_low"},
+		{name: "double wrapped", err: errors.Wrap(errors.Wrap(ErrOperationAlreadyReversed, "a"), "b"), wantKind: ErrorKindConflict, wantCode: "operation_already_reversed"},
+		{name: "internal", err: errors.New("connection refused"), wantKind: ErrorKindInternal, wantCode: "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantKind, KindOf(tt.err))
+			assert.Equal(t, tt.wantCode, CodeOf(tt.err))
+		})
+	}
+}
+
+// ─── CURRENCIES ─────────────────────────────────────────────────────────────────
+
+func Test_currencyRegistry_CheckAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		amount   string
+		wantErr  error
+	}{
+		{name: "cents", currency: "USD", amount: "10.25"},
+		{name: "normalized code", currency: " usd", amount: "10"},
+		{name: "too precise", currency: "USD", amount: "10.255", wantErr: ErrAmountPrecision},
+		{name: "zero decimals", currency: "JPY", amount: "100"},
+		{name: "zero decimals fraction", currency: "JPY", amount: "100.5", wantErr: ErrAmountPrecision},
+		{name: "three decimals", currency: "KWD", amount: "1.125"},
+		{name: "satoshi", currency: "BTC", amount: "0.00000001"},
+		{name: "unknown", currency: "XYZ", amount: "1", wantErr: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := testCurrencies.Get(tt.currency)
+			if err == nil {
+				err = c.CheckAmount(decimal.RequireFromString(tt.amount))
+			}
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+		})
+	}
+}
+
+// ─── LOCKS ──────────────────────────────────────────────────────────────────────
+
+func Test_Lock_LockContext(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	key := "test:lock"
+
+	l1 := st.lockf.Make(key)
+	assert.NoError(t, l1.LockContext(context.Background()))
+
+	// Waiting stops with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	l2 := st.lockf.Make(key, WithLockRetryDelay(10*time.Millisecond))
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(l2.LockContext(ctx)))
+
+	// Attempts are limited by the retry policy
+	l3 := st.lockf.Make(key, WithLockTries(2), WithLockRetryDelay(10*time.Millisecond))
+	assert.Equal(t, ErrLockNotAcquired, errors.Cause(l3.LockContext(context.Background())))
+
+	assert.NoError(t, l1.Unlock())
+
+	// Tokens grow with every acquisition
+	assert.NoError(t, l2.LockContext(context.Background()))
+	assert.True(t, l2.Token() > l1.Token(), "Got tokens: %d, %d", l1.Token(), l2.Token())
+	assert.NoError(t, l2.Unlock())
+}
+
+// brokenLock fails to release the lock like a lost connection to redis
+type brokenLock struct {
+	l Lock
+}
+
+func (b brokenLock) Lock() error                           { return b.l.Lock() }
+func (b brokenLock) LockContext(ctx context.Context) error { return b.l.LockContext(ctx) }
+func (b brokenLock) Unlock() error                         { return errors.New("connection lost") }
+func (b brokenLock) Token() int64                          { return b.l.Token() }
+
+func Test_lockPool_Unlock(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	pool := NewLockPool([]Lock{
+		st.lockf.Make("test:pool:1"),
+		brokenLock{st.lockf.Make("test:pool:2")},
+		st.lockf.Make("test:pool:3"),
+	})
+	assert.NoError(t, pool.Lock())
+
+	// The error is returned, but the rest of locks are released anyway
+	assert.EqualError(t, pool.Unlock(), "connection lost")
+
+	for _, key := range []string{"test:pool:1", "test:pool:3"} {
+		l := st.lockf.Make(key, WithLockTries(1))
+		if assert.NoError(t, l.Lock(), key) {
+			assert.NoError(t, l.Unlock())
+		}
+	}
+}
+
+func Test_lock_extend(t *testing.T) {
+	if !usePostgres() {
+		t.Skip("set PAYMENTS_TEST_STORAGE=postgres to test leases of redis locks")
+	}
+
+	pool := getRedis()
+	defer pool.Close()
+
+	lockf := NewLockFactory(pool, WithLockTTL(300*time.Millisecond))
+
+	l1 := lockf.Make("test:lease")
+	assert.NoError(t, l1.Lock())
+
+	// The lease is extended while the lock is held
+	time.Sleep(time.Second)
+	l2 := lockf.Make("test:lease", WithLockTries(1))
+	assert.Equal(t, ErrLockNotAcquired, errors.Cause(l2.Lock()))
+
+	assert.NoError(t, l1.Unlock())
+	assert.NoError(t, l2.Lock())
+	assert.NoError(t, l2.Unlock())
+}
+
+func Test_accountsLock_Fence(t *testing.T) {
+	st := getStorage()
+	defer st.Close()
+
+	assert.NoError(t, st.save(&Account{ID: 1, Name: "test1", Currency: "USD"}))
+
+	s := &basicPaymentsService{lockf: st.lockf, uowf: st.uowf}
+
+	setAmount := func(ctx context.Context, amount string) error {
+		return st.do(func(uow UOWPayments) error {
+			a, err := uow.Accounts().Get(ctx, 1)
+			if err != nil {
+				return err
+			}
+
+			a.Amount = decimal.RequireFromString(amount)
+			_, err = uow.Accounts().Update(ctx, a)
+			return err
+		})
+	}
+
+	// The lease of the first holder expires, so the next one acquires the lock
+	stale := s.getLock(1)
+	assert.NoError(t, stale.LockContext(context.Background()))
+	staleCtx := stale.Fence(context.Background())
+	assert.NoError(t, stale.Unlock())
+
+	fresh := s.getLock(1)
+	assert.NoError(t, fresh.LockContext(context.Background()))
+	assert.NoError(t, setAmount(fresh.Fence(context.Background()), "10"))
+	assert.NoError(t, fresh.Unlock())
+
+	// Writes of the first holder are rejected
+	assert.Equal(t, ErrLockLost, errors.Cause(setAmount(staleCtx, "20")))
+
+	assert.NoError(t, st.do(func(uow UOWPayments) error {
+		a, err := uow.Accounts().Get(nil, 1)
+		if assert.NoError(t, err) {
+			assert.True(t, a.Amount.Equal(decimal.RequireFromString("10")), "Got amount: %s", a.Amount)
+		}
+		return err
+	}))
+
+	// Writes without locks aren't fenced
+	assert.NoError(t, setAmount(context.Background(), "30"))
+}
+
+// ─── MEMORY STORAGE ─────────────────────────────────────────────────────────────
+
+func Test_memoryUOWPayments_Revert(t *testing.T) {
+	uowf := NewMemoryUOWPaymentsFactory()
+
+	uow1, _ := uowf.Make()
+	a, err := uow1.Accounts().Create(nil, &Account{Name: "test1", Currency: "USD"})
+	assert.NoError(t, err)
+
+	// Changes are invisible to other units of work until they are saved
+	uow2, _ := uowf.Make()
+	_, err = uow2.Accounts().Get(nil, a.ID)
+	assert.Equal(t, ErrAccountNotFound, err)
+
+	assert.NoError(t, uow1.Save())
+	_, err = uow2.Accounts().Get(nil, a.ID)
+	assert.NoError(t, err)
+
+	// Reverted changes are discarded, ids aren't reused
+	a.Amount = decimal.RequireFromString("10")
+	_, err = uow2.Accounts().Update(nil, a)
+	assert.NoError(t, err)
+	o, err := uow2.Operations().Create(nil, &Operation{
+		Participants: []int64{a.ID},
+		Type:         OperationTypeDeposit,
+		Transactions: []Transaction{{To: a.ID, Currency: "USD", Amount: a.Amount}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, uow2.Revert())
+	assert.Equal(t, ErrMemoryTxDone, uow2.Save())
+
+	uow3, _ := uowf.Make()
+	defer uow3.Save()
+
+	got, err := uow3.Accounts().Get(nil, a.ID)
+	if assert.NoError(t, err) {
+		assert.True(t, got.Amount.IsZero())
+	}
+	_, err = uow3.Operations().Get(nil, int64(o.ID))
+	assert.Equal(t, ErrOperationNotFound, err)
+
+	o2, err := uow3.Operations().Create(nil, &Operation{Participants: []int64{a.ID}, Type: OperationTypeDeposit})
+	assert.NoError(t, err)
+	assert.True(t, o2.ID > o.ID)
+}
+
+func Test_memoryLock_Unlock(t *testing.T) {
+	lockf := NewMemoryLockFactory(WithLockTries(1), WithLockRetryDelay(10*time.Millisecond))
+
+	l1 := lockf.Make("test:lock")
+	assert.NoError(t, l1.Lock())
+	assert.NoError(t, l1.Unlock())
+
+	l2 := lockf.Make("test:lock")
+	assert.NoError(t, l2.Lock())
+
+	// Locks which don't hold the key don't release the holder
+	assert.Error(t, l1.Unlock())
+	l3 := lockf.Make("test:lock")
+	assert.Equal(t, ErrLockNotAcquired, errors.Cause(l3.Lock()))
+	assert.Error(t, l3.Unlock())
+
+	assert.Equal(t, ErrLockNotAcquired, errors.Cause(lockf.Make("test:lock").Lock()))
+	assert.NoError(t, l2.Unlock())
+}
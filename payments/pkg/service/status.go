@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AccountStatusChange is a record of the account status history
+type AccountStatusChange struct {
+	gorm.Model
+	AccountID int64 `gorm:"index"`
+	From      AccountStatus
+	To        AccountStatus
+	Reason    string
+
+	// OperationID is the id of the closure operation which swept the balance of the closed account
+	OperationID *uint
+}
+
+// StatusChangesRepository describes interaction with a repository that can saves and stores AccountStatusChanges.
+type StatusChangesRepository interface {
+	Create(ctx context.Context, c *AccountStatusChange) (*AccountStatusChange, error)
+
+	GetByAccID(ctx context.Context, accID int64) ([]*AccountStatusChange, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type statusChangesRepository struct {
+	db *gorm.DB
+}
+
+func NewStatusChangesRepository(db *gorm.DB) StatusChangesRepository {
+	return &statusChangesRepository{db}
+}
+
+func (r *statusChangesRepository) Create(ctx context.Context, c *AccountStatusChange) (*AccountStatusChange, error) {
+	if err := r.db.Create(c).Error; err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *statusChangesRepository) GetByAccID(ctx context.Context, accID int64) ([]*AccountStatusChange, error) {
+	cs := []*AccountStatusChange{}
+
+	if err := r.db.Where("account_id = ?", accID).Order("id").Find(&cs).Error; err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
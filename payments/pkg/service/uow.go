@@ -10,6 +10,15 @@ type UOWPayments interface {
 
 	Accounts() AccountsRepository
 	Operations() OperationsRepository
+	Holds() HoldsRepository
+	Postings() PostingsRepository
+	FXQuotes() FXQuotesRepository
+	StatusChanges() StatusChangesRepository
+	Schedules() SchedulesRepository
+	ScheduleAttempts() ScheduleAttemptsRepository
+	Events() EventsRepository
+	Webhooks() WebhooksRepository
+	WebhookDeliveries() WebhookDeliveriesRepository
 }
 
 type UOWPaymentsFactory interface {
@@ -17,17 +26,12 @@ type UOWPaymentsFactory interface {
 }
 
 type uowPayments struct {
-	db     *gorm.DB
-	accRep AccountsRepository
-	opRep  OperationsRepository
+	db *gorm.DB
 }
 
-func NewUOWPayments(db *gorm.DB, accRep AccountsRepository, opRep OperationsRepository) UOWPayments {
-	return &uowPayments{
-		db:     db,
-		accRep: accRep,
-		opRep:  opRep,
-	}
+// NewUOWPayments returns a unit of work over the transaction. Its repositories are built over the transaction too.
+func NewUOWPayments(tx *gorm.DB) UOWPayments {
+	return &uowPayments{db: tx}
 }
 
 func (u *uowPayments) Save() error {
@@ -39,11 +43,47 @@ func (u *uowPayments) Revert() error {
 }
 
 func (u *uowPayments) Accounts() AccountsRepository {
-	return u.accRep
+	return NewAccountsRepository(u.db)
 }
 
 func (u *uowPayments) Operations() OperationsRepository {
-	return u.opRep
+	return NewOperationsRepository(u.db)
+}
+
+func (u *uowPayments) Holds() HoldsRepository {
+	return NewHoldsRepository(u.db)
+}
+
+func (u *uowPayments) Postings() PostingsRepository {
+	return NewPostingsRepository(u.db)
+}
+
+func (u *uowPayments) FXQuotes() FXQuotesRepository {
+	return NewFXQuotesRepository(u.db)
+}
+
+func (u *uowPayments) StatusChanges() StatusChangesRepository {
+	return NewStatusChangesRepository(u.db)
+}
+
+func (u *uowPayments) Schedules() SchedulesRepository {
+	return NewSchedulesRepository(u.db)
+}
+
+func (u *uowPayments) ScheduleAttempts() ScheduleAttemptsRepository {
+	return NewScheduleAttemptsRepository(u.db)
+}
+
+func (u *uowPayments) Events() EventsRepository {
+	return NewEventsRepository(u.db)
+}
+
+func (u *uowPayments) Webhooks() WebhooksRepository {
+	return NewWebhooksRepository(u.db)
+}
+
+func (u *uowPayments) WebhookDeliveries() WebhookDeliveriesRepository {
+	return NewWebhookDeliveriesRepository(u.db)
 }
 
 type uowPaymentsFactory struct {
@@ -60,9 +100,5 @@ func (f *uowPaymentsFactory) Make() (UOWPayments, error) {
 		return nil, err
 	}
 
-	return NewUOWPayments(
-		tx,
-		NewAccountsRepository(tx),
-		NewOperationsRepository(tx),
-	), nil
+	return NewUOWPayments(tx), nil
 }
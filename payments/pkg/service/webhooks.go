@@ -0,0 +1,572 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// WebhookMaxAttempts is the number of delivery attempts after which the delivery is dead
+	WebhookMaxAttempts = 8
+	// WebhookSignatureHeader carries the signature of the delivery, see SignWebhook
+	WebhookSignatureHeader = "X-Payments-Signature"
+
+	webhookBaseDelay = 30 * time.Second
+	webhookMaxDelay  = 6 * time.Hour
+	// webhooksLockKey guards the deliveries, so they are sent by one replica at a time
+	webhooksLockKey = "webhooks"
+	// dueDeliveriesLimit is the biggest number of deliveries sent by one run of the dispatcher
+	dueDeliveriesLimit = 100
+)
+
+var (
+	ErrWebhookNotFound         = NewError(ErrorKindNotFound, "webhook_not_found", "webhook not found")
+	ErrWebhookDeliveryNotFound = NewError(ErrorKindNotFound, "webhook_delivery_not_found", "webhook delivery not found")
+	ErrWebhookURL              = NewError(ErrorKindValidation, "invalid_webhook_url", "webhook url must be an absolute http or https url")
+	ErrUnknownEventType        = NewError(ErrorKindValidation, "unknown_event_type", "event type is unknown")
+	ErrWebhookDeliveryPending  = NewError(ErrorKindConflict, "webhook_delivery_pending", "webhook delivery is pending")
+)
+
+// EventTypes is a list of event types stored as a comma-separated text
+type EventTypes []EventType
+
+// Value implements driver.Valuer.
+func (ts EventTypes) Value() (driver.Value, error) {
+	ss := make([]string, len(ts))
+	for i, t := range ts {
+		ss[i] = string(t)
+	}
+	return strings.Join(ss, ","), nil
+}
+
+// Scan implements sql.Scanner.
+func (ts *EventTypes) Scan(src interface{}) error {
+	*ts = EventTypes{}
+	for _, s := range splitColumn(src) {
+		*ts = append(*ts, EventType(s))
+	}
+	return nil
+}
+
+// AccountIDs is a list of account ids stored as a comma-separated text
+type AccountIDs []int64
+
+// Value implements driver.Valuer.
+func (ids AccountIDs) Value() (driver.Value, error) {
+	ss := make([]string, len(ids))
+	for i, id := range ids {
+		ss[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(ss, ","), nil
+}
+
+// Scan implements sql.Scanner.
+func (ids *AccountIDs) Scan(src interface{}) error {
+	*ids = AccountIDs{}
+	for _, s := range splitColumn(src) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "account id (%s) parsing failed", s)
+		}
+		*ids = append(*ids, id)
+	}
+	return nil
+}
+
+func splitColumn(src interface{}) []string {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	}
+
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// WebhookSpec describes a webhook subscription
+type WebhookSpec struct {
+	URL string
+	// Events and Accounts filter delivered events. Empty lists match all events and all accounts.
+	Events   EventTypes
+	Accounts AccountIDs
+}
+
+// Webhook is a subscription of a partner to events
+type Webhook struct {
+	gorm.Model
+	URL      string
+	Events   EventTypes `gorm:"type:text"`
+	Accounts AccountIDs `gorm:"type:text"`
+	// Secret signs the deliveries of the webhook. It's shown only once, when the webhook is created.
+	Secret string `json:"-"`
+}
+
+// Matches reports whether the event has to be delivered to the webhook
+func (w *Webhook) Matches(e *Event) bool {
+	return (len(w.Events) == 0 || w.Events.contain(e.Type)) && (len(w.Accounts) == 0 || w.Accounts.contain(e.AccountID))
+}
+
+func (ts EventTypes) contain(t EventType) bool {
+	for _, v := range ts {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (ids AccountIDs) contain(id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "dead" // all attempts failed
+)
+
+// WebhookDelivery is a delivery of an event to a webhook
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID uint `gorm:"unique_index:idx_webhook_delivery_event"`
+	EventID   uint `gorm:"unique_index:idx_webhook_delivery_event"`
+	EventType EventType
+	// Body is the posted event
+	Body json.RawMessage
+
+	Status        WebhookDeliveryStatus `gorm:"index"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	// ResponseStatus and Error describe the last attempt
+	ResponseStatus int
+	Error          string
+	DeliveredAt    *time.Time
+}
+
+// fail records the failed attempt and plans the next one with exponential backoff.
+// The delivery is dead after WebhookMaxAttempts attempts.
+func (d *WebhookDelivery) fail(now time.Time, err error) {
+	d.Attempts++
+	d.Error = err.Error()
+	if d.Attempts >= WebhookMaxAttempts {
+		d.Status = WebhookDeliveryDead
+		return
+	}
+
+	delay := webhookBaseDelay << uint(d.Attempts-1)
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	d.NextAttemptAt = now.Add(delay)
+}
+
+// SignWebhook returns the signature of the delivery body sent at the unix time ts.
+// The signature is sent in the WebhookSignatureHeader as "t=<ts>,v1=<signature>",
+// where the signature is the hex encoded HMAC-SHA256 of "<ts>.<body>" keyed by the webhook secret.
+func SignWebhook(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkWebhookSpec checks the url and the event types of the spec
+func checkWebhookSpec(spec WebhookSpec) error {
+	u, err := url.Parse(spec.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrWebhookURL
+	}
+
+	for _, t := range spec.Events {
+		switch t {
+		case EventAccountCreated, EventAccountStatusChanged, EventOperationCompleted:
+		default:
+			return ErrUnknownEventType
+		}
+	}
+
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "webhook secret generating failed")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WebhooksRepository describes interaction with a repository that can saves and stores Webhooks.
+type WebhooksRepository interface {
+	Create(ctx context.Context, w *Webhook) (*Webhook, error)
+	Update(ctx context.Context, w *Webhook) (*Webhook, error)
+	Delete(ctx context.Context, id int64) error
+
+	Get(ctx context.Context, id int64) (*Webhook, error)
+	GetAll(ctx context.Context) ([]*Webhook, error)
+}
+
+// WebhookDeliveriesRepository describes interaction with a repository that can saves and stores WebhookDeliveries.
+type WebhookDeliveriesRepository interface {
+	Create(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error)
+	Update(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error)
+
+	Get(ctx context.Context, id int64) (*WebhookDelivery, error)
+	// GetByEvent returns the delivery of the event to the webhook or ErrWebhookDeliveryNotFound
+	GetByEvent(ctx context.Context, webhookID, eventID uint) (*WebhookDelivery, error)
+	// GetByWebhookID returns deliveries of the webhook, the newest first
+	GetByWebhookID(ctx context.Context, webhookID int64) ([]*WebhookDelivery, error)
+	// GetDue returns pending deliveries whose next attempt time has come, the oldest first
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error)
+}
+
+// WebhookDispatcher sends pending webhook deliveries
+type WebhookDispatcher interface {
+	// Dispatch sends all due deliveries and returns them
+	Dispatch(ctx context.Context) ([]*WebhookDelivery, error)
+}
+
+// ─── IMPLEMENTATION ─────────────────────────────────────────────────────────────
+
+type webhooksRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhooksRepository(db *gorm.DB) WebhooksRepository {
+	return &webhooksRepository{db}
+}
+
+func (r *webhooksRepository) Create(ctx context.Context, w *Webhook) (*Webhook, error) {
+	if err := r.db.Create(w).Error; err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (r *webhooksRepository) Update(ctx context.Context, w *Webhook) (*Webhook, error) {
+	if err := r.db.Save(w).Error; err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (r *webhooksRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.Delete(&Webhook{}, id).Error
+}
+
+func (r *webhooksRepository) Get(ctx context.Context, id int64) (*Webhook, error) {
+	w := Webhook{}
+	if err := r.db.Find(&w, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrWebhookNotFound
+		}
+
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (r *webhooksRepository) GetAll(ctx context.Context) ([]*Webhook, error) {
+	ws := []*Webhook{}
+
+	if err := r.db.Order("id").Find(&ws).Error; err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+type webhookDeliveriesRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveriesRepository(db *gorm.DB) WebhookDeliveriesRepository {
+	return &webhookDeliveriesRepository{db}
+}
+
+func (r *webhookDeliveriesRepository) Create(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error) {
+	if err := r.db.Create(d).Error; err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *webhookDeliveriesRepository) Update(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error) {
+	if err := r.db.Save(d).Error; err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *webhookDeliveriesRepository) Get(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	d := WebhookDelivery{}
+	if err := r.db.Find(&d, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (r *webhookDeliveriesRepository) GetByEvent(ctx context.Context, webhookID, eventID uint) (*WebhookDelivery, error) {
+	d := WebhookDelivery{}
+	if err := r.db.Where("webhook_id = ? AND event_id = ?", webhookID, eventID).First(&d).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (r *webhookDeliveriesRepository) GetByWebhookID(ctx context.Context, webhookID int64) ([]*WebhookDelivery, error) {
+	ds := []*WebhookDelivery{}
+
+	if err := r.db.Where("webhook_id = ?", webhookID).Order("id DESC").Find(&ds).Error; err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+func (r *webhookDeliveriesRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	ds := []*WebhookDelivery{}
+
+	req := r.db.Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).Order("next_attempt_at").Limit(limit)
+	if err := req.Find(&ds).Error; err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// ─── WEBHOOKS PUBLISHER ─────────────────────────────────────────────────────────
+
+type webhooksPublisher struct {
+	uowf UOWPaymentsFactory
+}
+
+// NewWebhooksPublisher returns a Publisher which plans deliveries of events to the matching webhooks.
+// The deliveries are sent by the WebhookDispatcher.
+func NewWebhooksPublisher(uowf UOWPaymentsFactory) Publisher {
+	return &webhooksPublisher{uowf}
+}
+
+func (p *webhooksPublisher) Publish(ctx context.Context, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "event encoding failed")
+	}
+
+	uow, err := p.uowf.Make()
+	if err != nil {
+		return errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	ws, err := uow.Webhooks().GetAll(ctx)
+	if err != nil {
+		uow.Revert()
+		return errors.Wrap(err, "webhooks getting failed")
+	}
+
+	for _, w := range ws {
+		if !w.Matches(e) {
+			continue
+		}
+
+		// The event is published again if the relay failed to record its publishing
+		_, err := uow.WebhookDeliveries().GetByEvent(ctx, w.ID, e.ID)
+		if err == nil {
+			continue
+		}
+		if errors.Cause(err) != ErrWebhookDeliveryNotFound {
+			uow.Revert()
+			return errors.Wrapf(err, "webhook (%d) delivery getting failed", w.ID)
+		}
+
+		d := &WebhookDelivery{
+			WebhookID:     w.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Body:          body,
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if _, err := uow.WebhookDeliveries().Create(ctx, d); err != nil {
+			uow.Revert()
+			return errors.Wrapf(err, "webhook (%d) delivery createing failed", w.ID)
+		}
+	}
+
+	return nil
+}
+
+// ─── WEBHOOK DISPATCHER ─────────────────────────────────────────────────────────
+
+type webhookDispatcher struct {
+	lockf  LockFactory
+	uowf   UOWPaymentsFactory
+	client *http.Client
+}
+
+// NewWebhookDispatcher returns a WebhookDispatcher which sends deliveries with the client
+func NewWebhookDispatcher(lockf LockFactory, uowf UOWPaymentsFactory, client *http.Client) WebhookDispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &webhookDispatcher{
+		lockf:  lockf,
+		uowf:   uowf,
+		client: client,
+	}
+}
+
+func (d *webhookDispatcher) Dispatch(ctx context.Context) ([]*WebhookDelivery, error) {
+	lock := d.lockf.Make(webhooksLockKey)
+	if err := lock.LockContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "mutex (webhooks) locking failed")
+	}
+	defer lock.Unlock()
+
+	uow, err := d.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+
+	due, err := uow.WebhookDeliveries().GetDue(ctx, time.Now(), dueDeliveriesLimit)
+	uow.Save()
+	if err != nil {
+		return nil, errors.Wrap(err, "due webhook deliveries getting failed")
+	}
+
+	for _, dl := range due {
+		if err := d.send(ctx, dl); err != nil {
+			return due, err
+		}
+	}
+
+	return due, nil
+}
+
+// send makes an attempt of the delivery and records its result.
+// The webhook is requested outside of units of work, so slow receivers don't hold the storage.
+func (d *webhookDispatcher) send(ctx context.Context, dl *WebhookDelivery) error {
+	w, err := d.webhook(ctx, dl.WebhookID)
+	switch {
+	case errors.Cause(err) == ErrWebhookNotFound:
+		// The webhook was deleted, nobody waits for the delivery
+		dl.Error = err.Error()
+		dl.Status = WebhookDeliveryDead
+	case err != nil:
+		return err
+	default:
+		now := time.Now()
+		status, err := d.post(ctx, w, dl.Body, now)
+		dl.ResponseStatus = status
+		if err != nil {
+			dl.fail(now, err)
+		} else {
+			dl.Attempts++
+			dl.Error = ""
+			dl.Status = WebhookDeliveryDelivered
+			dl.DeliveredAt = &now
+		}
+	}
+
+	uow, err := d.uowf.Make()
+	if err != nil {
+		return errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	if _, err := uow.WebhookDeliveries().Update(ctx, dl); err != nil {
+		uow.Revert()
+		return errors.Wrapf(err, "webhook delivery (%d) update failed", dl.ID)
+	}
+
+	return nil
+}
+
+func (d *webhookDispatcher) webhook(ctx context.Context, id uint) (*Webhook, error) {
+	uow, err := d.uowf.Make()
+	if err != nil {
+		return nil, errors.Wrap(err, "uow context createing failed")
+	}
+	defer uow.Save()
+
+	w, err := uow.Webhooks().Get(ctx, int64(id))
+	if err != nil && errors.Cause(err) != ErrWebhookNotFound {
+		return nil, errors.Wrapf(err, "webhook (%d) getting failed", id)
+	}
+
+	return w, err
+}
+
+// post sends the signed body to the webhook and returns the response status
+func (d *webhookDispatcher) post(ctx context.Context, w *Webhook, body []byte, now time.Time) (int, error) {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Wrap(err, "webhook request createing failed")
+	}
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(WebhookSignatureHeader, fmt.Sprintf("t=%d,v1=%s", ts, SignWebhook(w.Secret, ts, body)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "webhook request failed")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,163 @@
+// Package validation checks incoming requests before they reach the service.
+// It doesn't depend on any transport, so every transport can reuse it.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+const (
+	// MaxAmountScale is the number of decimal places stored by the database (decimal(20,8))
+	MaxAmountScale = 8
+	// MaxAmountDigits is the number of integer digits stored by the database (decimal(20,8))
+	MaxAmountDigits = 12
+	// MaxStringLength is the length limit of text fields
+	MaxStringLength = 255
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z0-9]{3,10}$`)
+
+var maxAmount = decimal.New(1, MaxAmountDigits)
+
+// Validator is implemented by requests which can check themselves
+type Validator interface {
+	Validate() error
+}
+
+// Errors collects all field errors of a request
+type Errors struct {
+	fields []service.FieldError
+}
+
+// Add appends an error of the field
+func (e *Errors) Add(field, format string, args ...interface{}) {
+	e.fields = append(e.fields, service.FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Err returns a validation error with all collected field errors or nil if there are no errors
+func (e *Errors) Err() error {
+	if len(e.fields) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+
+	err := service.NewError(service.ErrorKindValidation, "invalid_request", "invalid request: "+strings.Join(msgs, "; "))
+	err.Fields = e.fields
+	return err
+}
+
+// ─── RULES ──────────────────────────────────────────────────────────────────────
+
+// Required checks that the text field isn't blank
+func (e *Errors) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		e.Add(field, "must not be empty")
+	} else if len(value) > MaxStringLength {
+		e.Add(field, "must be at most %d characters", MaxStringLength)
+	}
+}
+
+// MaxLength checks the length of an optional text field
+func (e *Errors) MaxLength(field, value string) {
+	if len(value) > MaxStringLength {
+		e.Add(field, "must be at most %d characters", MaxStringLength)
+	}
+}
+
+// ID checks that the field is a valid entity id
+func (e *Errors) ID(field string, id int64) {
+	if id <= 0 {
+		e.Add(field, "must be a positive id")
+	}
+}
+
+// Currency checks the format of a currency code. The code is checked in the normalized form,
+// whether the currency is known is decided by the service registry.
+func (e *Errors) Currency(field, code string) {
+	if !currencyRe.MatchString(service.NormalizeCurrency(code)) {
+		e.Add(field, "must be a currency code")
+	}
+}
+
+// Amount checks that the amount is positive and fits into the database column
+func (e *Errors) Amount(field string, amount decimal.Decimal) {
+	if !amount.IsPositive() {
+		e.Add(field, "must be positive")
+		return
+	}
+	e.storable(field, amount)
+}
+
+// OptionalAmount checks the amount which can be zero
+func (e *Errors) OptionalAmount(field string, amount decimal.Decimal) {
+	if amount.IsNegative() {
+		e.Add(field, "must not be negative")
+		return
+	}
+	e.storable(field, amount)
+}
+
+// SignedAmount checks the amount which can be negative but not zero
+func (e *Errors) SignedAmount(field string, amount decimal.Decimal) {
+	if amount.IsZero() {
+		e.Add(field, "must not be zero")
+		return
+	}
+	e.storable(field, amount.Abs())
+}
+
+func (e *Errors) storable(field string, amount decimal.Decimal) {
+	if !amount.Equal(amount.Truncate(MaxAmountScale)) {
+		e.Add(field, "must have at most %d decimal places", MaxAmountScale)
+	}
+	if amount.GreaterThanOrEqual(maxAmount) {
+		e.Add(field, "must be less than %s", maxAmount)
+	}
+}
+
+// Page checks the pagination parameters of a list request
+func (e *Errors) Page(cursor string, limit int, order service.SortOrder) {
+	if _, err := service.DecodeCursor(cursor); err != nil {
+		e.Add("cursor", "must be a cursor returned by the previous page")
+	}
+	if limit < 0 || limit > service.MaxPageLimit {
+		e.Add("limit", "must be between 0 and %d", service.MaxPageLimit)
+	}
+	if order != "" && order != service.SortOrderAsc && order != service.SortOrderDesc {
+		e.Add("order", "must be %q or %q", service.SortOrderAsc, service.SortOrderDesc)
+	}
+}
+
+// TimeRange checks that the beginning of the optional range is before its end
+func (e *Errors) TimeRange(fromField, toField string, from, to *time.Time) {
+	if from != nil && to != nil && !from.Before(*to) {
+		e.Add(toField, "must be after %s", fromField)
+	}
+}
+
+// AmountRange checks bounds of the optional amount range
+func (e *Errors) AmountRange(minField, maxField string, min, max *decimal.Decimal) {
+	if min != nil {
+		e.OptionalAmount(minField, *min)
+	}
+	if max != nil {
+		e.OptionalAmount(maxField, *max)
+	}
+	if min != nil && max != nil && min.GreaterThan(*max) {
+		e.Add(maxField, "must not be less than %s", minField)
+	}
+}
@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deterok/go_test_task/payments/pkg/service"
+)
+
+func TestErrors_Amount(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		wantErr bool
+	}{
+		{name: "integer", amount: "10"},
+		{name: "max scale", amount: "0.00000001"},
+		{name: "trailing zeros", amount: "1.0000000000"},
+		{name: "zero", amount: "0", wantErr: true},
+		{name: "negative", amount: "-1", wantErr: true},
+		{name: "too precise", amount: "0.000000001", wantErr: true},
+		{name: "too big", amount: "1000000000000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Errors{}
+			e.Amount("amount", decimal.RequireFromString(tt.amount))
+			if err := e.Err(); (err != nil) != tt.wantErr {
+				t.Errorf("Errors.Amount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErrors_Err(t *testing.T) {
+	e := Errors{}
+	e.Required("name", " ")
+	e.Currency("currency", "u$d")
+	e.ID("to", 0)
+
+	err := e.Err()
+	assert.Equal(t, service.ErrorKindValidation, service.KindOf(err))
+	assert.Len(t, service.FieldsOf(err), 3)
+
+	assert.NoError(t, (&Errors{}).Err())
+}